@@ -8,6 +8,7 @@ package parallelcore_client_sdk_go
 import (
 	"encoding/json"
 	"fmt"
+	"unicode/utf8"
 
 	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
 )
@@ -15,11 +16,32 @@ import (
 func CallSmartContractJSON(x *Client, name string, action string, v interface{}, result interface{}) (raw []byte, err error) {
 	raw, err = CallSmartContract(x, name, action, v)
 	if err == nil {
-		err = json.Unmarshal(raw, result)
+		if codec := x.codec(name); codec != nil {
+			err = codec.Unmarshal(raw, result)
+		} else {
+			err = json.Unmarshal(raw, result)
+		}
 	}
 	return
 }
 
+// CallSmartContractCodec calls action of the latest version of the smart contract
+// name with v encoded by codec, and decodes the result into result with codec (unless
+// result is nil).
+func CallSmartContractCodec(x Invoker, codec Codec, name string, action string, v interface{}, result interface{}) ([]byte, error) {
+	task, err := encodeTask(codec, action, v)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := x.Invoke(name+"-v*", task)
+	if err == nil && result != nil {
+		if err = codec.Unmarshal(raw, result); err != nil {
+			err = fmt.Errorf("CLIENT: %s codec: %w", codec.Name(), err)
+		}
+	}
+	return raw, err
+}
+
 func CallSmartContractText(x *Client, name string, action string, v interface{}) (text string, err error) {
 	var raw []byte
 	raw, err = CallSmartContract(x, name, action, v)
@@ -29,7 +51,26 @@ func CallSmartContractText(x *Client, name string, action string, v interface{})
 	return
 }
 
+// CallSmartContract calls action of the latest version of the smart contract name with
+// v, encoded by the codec set with WithCodec for name if any. Without one, strings and
+// byte slices are passed as is and other values JSON-encoded; binary data and values
+// JSON cannot encode are an error (they used to be passed formatted with fmt.Sprintf)
+// and must be sent with a Codec, e.g. Base64Codec or MsgpackCodec.
 func CallSmartContract(x *Client, name string, action string, v interface{}) ([]byte, error) {
+	if codec := x.codec(name); codec != nil {
+		return CallSmartContractCodec(x, codec, name, action, v, nil)
+	}
+	task, err := smartContractTask(action, v)
+	if err != nil {
+		return nil, err
+	}
+	return x.Invoke(name+"-v*", task)
+}
+
+// smartContractTask encodes the ScTask calling action with v: strings and byte slices
+// are passed as is, other values JSON-encoded. Values that cannot be encoded without
+// loss (binary data, values JSON cannot encode) fail: they need a Codec.
+func smartContractTask(action string, v interface{}) ([]byte, error) {
 	var data string
 	if v == nil {
 		data = ""
@@ -40,13 +81,12 @@ func CallSmartContract(x *Client, name string, action string, v interface{}) ([]
 	} else if raw, e := json.Marshal(v); e == nil {
 		data = string(raw)
 	} else {
-		data = fmt.Sprintf("%v", v)
+		return nil, fmt.Errorf("CLIENT: cannot encode %T as JSON, use a Codec: %w", v, e)
 	}
-	task, err := json.Marshal(pb.ScTask{Action: action, Data: data})
-	if err != nil {
-		return nil, err
+	if !utf8.ValidString(data) {
+		return nil, fmt.Errorf("CLIENT: binary data must be encoded with a Codec, e.g. Base64Codec")
 	}
-	return x.Invoke(name+"-v*", task)
+	return json.Marshal(pb.ScTask{Action: action, Data: data})
 }
 
 func ReturnBytesToString(input []byte, err error) (string, error) {
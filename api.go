@@ -4,7 +4,8 @@
 //
 // Basic workflow:
 //  1. Use OpenAny() to establish a connection from the application to ParallelCore gRPC endpoint(s).
-//  2. Invoke a smart-contract using Invoke(), passing in arguments as a space-delimited string.
+//  2. Invoke a smart-contract using Invoke(), passing in arguments as a space-delimited string
+//     (InvokeArgs() and InvokeValues() encode arguments containing spaces or binary data).
 //  3. After the application finishes using the connection, close it using Close()
 //
 // Copyright 2021 Digital Transaction Limited.
@@ -35,6 +36,11 @@ type Client struct {
 	certPath        string
 	token           string
 	expireTimestamp int64
+	cfg             *openConfig
+
+	// shared is the connection a session Client makes its calls over (see
+	// SharedConnection). It is not owned by the Client, so conn is nil.
+	shared *grpc.ClientConn
 }
 
 /*
@@ -46,12 +52,22 @@ func (x *Client) String() string {
 var endpointsCalled = 0
 var oldEndpoints string
 
+// reportedError is an error reported in a response by ParallelCore, such as the error
+// of a smart contract, rather than by gRPC.
+type reportedError struct {
+	message string
+}
+
+func (e *reportedError) Error() string {
+	return e.message
+}
+
 func handleResponse(response *pb.Response, err error, function string) ([]byte, error) {
 	if err != nil {
 		return nil, fmt.Errorf(E_FUNC_X_ERROR_X, function, err)
 	}
 	if len(response.Error) != 0 {
-		return nil, fmt.Errorf(string(response.Error))
+		return nil, &reportedError{message: string(response.Error)}
 	}
 	return response.Payload, nil
 }
@@ -61,7 +77,7 @@ func handleIdentifiedResponse(response *pb.IdentifiedResponse, err error, functi
 		return nil, "", fmt.Errorf(E_FUNC_X_ERROR_X, function, err)
 	}
 	if len(response.Error) != 0 {
-		return nil, "", fmt.Errorf(string(response.Error))
+		return nil, "", &reportedError{message: string(response.Error)}
 	}
 	return response.Payload, string(response.CommittedId), nil
 }
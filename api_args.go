@@ -0,0 +1,153 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var ErrMalformedArgs = errors.New("CLIENT: Malformed arguments")
+
+// EncodeArgs encodes args into the space-delimited argument string passed to smart
+// contracts by Invoke, such that DecodeArgs returns args.
+//
+// Arguments made of printable, non-space characters other than '"' and '\' are
+// written as is, so that smart contracts splitting their arguments on spaces read
+// them unchanged. Other arguments, including empty ones, are written as double-quoted
+// Go string literals (see strconv.Quote), so that spaces, quotes, newlines and binary
+// data survive: "two words", "line\n", "\xff\x00".
+func EncodeArgs(args ...string) []byte {
+	var out strings.Builder
+	for i, arg := range args {
+		if i > 0 {
+			out.WriteByte(' ')
+		}
+		if plainArg(arg) {
+			out.WriteString(arg)
+		} else {
+			out.WriteString(strconv.Quote(arg))
+		}
+	}
+	return []byte(out.String())
+}
+
+func plainArg(arg string) bool {
+	if arg == "" || !utf8.ValidString(arg) {
+		return false
+	}
+	for _, r := range arg {
+		if r == '"' || r == '\\' || unicode.IsSpace(r) || !unicode.IsPrint(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// DecodeArgs decodes an argument string encoded by EncodeArgs: arguments are separated
+// by spaces, and arguments starting with '"' are double-quoted Go string literals.
+// Smart contracts written in Go can use it to read the arguments of Invoke, and must
+// use it (or an equivalent) to read those of InvokeArgs and InvokeValues correctly.
+func DecodeArgs(encoded []byte) ([]string, error) {
+	var args []string
+	text := string(encoded)
+	for {
+		text = strings.TrimLeftFunc(text, unicode.IsSpace)
+		if text == "" {
+			return args, nil
+		}
+		if text[0] != '"' {
+			end := strings.IndexFunc(text, unicode.IsSpace)
+			if end < 0 {
+				end = len(text)
+			}
+			if strings.ContainsAny(text[:end], `"\`) {
+				return nil, fmt.Errorf("%w: unquoted %q", ErrMalformedArgs, text[:end])
+			}
+			args = append(args, text[:end])
+			text = text[end:]
+			continue
+		}
+
+		end := closingQuote(text)
+		if end < 0 {
+			return nil, fmt.Errorf("%w: unterminated %q", ErrMalformedArgs, text)
+		}
+		arg, err := strconv.Unquote(text[:end+1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q: %v", ErrMalformedArgs, text[:end+1], err)
+		}
+		args = append(args, arg)
+		text = text[end+1:]
+		if text != "" && !unicode.IsSpace(rune(text[0])) {
+			return nil, fmt.Errorf("%w: missing space after %q", ErrMalformedArgs, arg)
+		}
+	}
+}
+
+// closingQuote returns the index of the quote closing the string text starts with, or -1.
+func closingQuote(text string) int {
+	for i := 1; i < len(text); i++ {
+		switch text[i] {
+		case '\\':
+			i++
+		case '"':
+			return i
+		}
+	}
+	return -1
+}
+
+// EncodeValues encodes values with EncodeArgs, after formatting each value as a
+// string: strings and byte slices as is, numbers and booleans as fmt.Sprint formats
+// them, fmt.Stringers with their String method, and other values as JSON.
+func EncodeValues(values ...interface{}) ([]byte, error) {
+	args := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case string:
+			args[i] = v
+		case []byte:
+			args[i] = string(v)
+		case bool, int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			args[i] = fmt.Sprint(v)
+		case fmt.Stringer:
+			args[i] = v.String()
+		default:
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return nil, fmt.Errorf("CLIENT: EncodeValues: argument %d: %w", i, err)
+			}
+			args[i] = string(raw)
+		}
+	}
+	return EncodeArgs(args...), nil
+}
+
+// InvokeArgs invokes the smart contract identified by smartContractSpec with args,
+// encoded with EncodeArgs.
+//
+// The smart contract receives a single argument string, which it must decode with
+// DecodeArgs: splitting it on spaces only works for arguments EncodeArgs writes as is.
+func (client *Client) InvokeArgs(smartContractSpec string, args ...string) ([]byte, error) {
+	return client.Invoke(smartContractSpec, EncodeArgs(args...))
+}
+
+// InvokeValues invokes the smart contract identified by smartContractSpec with values,
+// encoded with EncodeValues. As with InvokeArgs, the smart contract must decode its
+// argument string with DecodeArgs.
+func (client *Client) InvokeValues(smartContractSpec string, values ...interface{}) ([]byte, error) {
+	args, err := EncodeValues(values...)
+	if err != nil {
+		return nil, err
+	}
+	return client.Invoke(smartContractSpec, args)
+}
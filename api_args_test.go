@@ -0,0 +1,45 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEncodeArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{"transfer", "alice", "bob", "10"},
+		{"two words", "", "line\nbreak", `"quoted"`, `back\slash`, "tab\there"},
+		{"\xff\x00binary", "ünïcode", "日本"},
+	} {
+		encoded := EncodeArgs(args...)
+		decoded, err := DecodeArgs(encoded)
+		if err != nil || !reflect.DeepEqual(decoded, args) {
+			t.Errorf("DecodeArgs(%s) = %q, %v; want %q", encoded, decoded, err, args)
+		}
+	}
+	if encoded := string(EncodeArgs("transfer", "alice", "two words")); encoded != `transfer alice "two words"` {
+		t.Errorf("EncodeArgs = %s", encoded)
+	}
+	for _, malformed := range []string{`"unterminated`, `un"quoted`, `"a"b`, `"\q"`} {
+		if _, err := DecodeArgs([]byte(malformed)); !errors.Is(err, ErrMalformedArgs) {
+			t.Errorf("DecodeArgs(%s) error = %v", malformed, err)
+		}
+	}
+}
+
+func TestEncodeValues(t *testing.T) {
+	encoded, err := EncodeValues("alice", []byte("bob"), 10, 2.5, true, time.Second, map[string]int{"a": 1})
+	if err != nil || string(encoded) != `alice bob 10 2.5 true 1s "{\"a\":1}"` {
+		t.Errorf("EncodeValues = %s, %v", encoded, err)
+	}
+	if _, err = EncodeValues(func() {}); err == nil {
+		t.Error("EncodeValues(func) did not fail")
+	}
+}
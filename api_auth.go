@@ -8,12 +8,16 @@ package parallelcore_client_sdk_go
 
 import (
 	"context"
+	"crypto"
+	"errors"
 	"fmt"
 	"math/rand"
 	"strings"
 	"time"
 
 	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 func (client *Client) auth(clientID []byte, credential []byte) ([]byte, error) {
@@ -46,34 +50,110 @@ func (client *Client) GetTokenExpTime() int64 {
 	return client.expireTimestamp
 }
 
+// TokenClaims decodes the claims of the client's token without contacting ParallelCore,
+// so that services can cheaply authorize tokens forwarded to them.
+//
+// The token's signature is verified if the Client was opened WithTokenVerificationKey.
+// Its validity period is checked against the local clock, tolerating the skew set by
+// WithClockSkew. If the token has expired or is not yet valid, TokenClaims returns both
+// the claims and the error of TokenClaims.Valid.
+func (client *Client) TokenClaims() (*TokenClaims, error) {
+	var key crypto.PublicKey
+	skew := DEFAULT_CLOCK_SKEW
+	if client.cfg != nil {
+		key = client.cfg.tokenVerificationKey
+		if client.cfg.clockSkew > 0 {
+			skew = client.cfg.clockSkew
+		}
+	}
+
+	claims, err := decodeTokenClaims(client.token, key)
+	if err != nil {
+		return nil, err
+	}
+	return claims, claims.Valid(time.Now(), skew)
+}
+
 // Renew asks a ParallelCore endpoint randomly selected from client.endpointSpecs
 // to renew the calling client's authentication token.
+//
+// If ParallelCore refuses to renew the token because it has expired or is otherwise
+// rejected, and the client was opened with a CredentialProvider, Renew authenticates
+// again instead. Other errors, such as an unreachable endpoint, are returned as is.
 func (client *Client) Renew() error {
 	token, expireTimestamp, err := client.renewToken()
+	if err != nil && client.cfg != nil && client.cfg.credentialProvider != nil && client.tokenRejected(err) {
+		token, expireTimestamp, err = client.reauthToken()
+	}
 	if err != nil {
 		return err
 	}
+	return client.reconnect(token, expireTimestamp)
+}
+
+// tokenRejected reports whether err, returned by renewToken, means that the client's
+// token can no longer be used: either it has expired, or ParallelCore refused it.
+func (client *Client) tokenRejected(err error) bool {
+	skew := DEFAULT_CLOCK_SKEW
+	if client.cfg != nil && client.cfg.clockSkew > 0 {
+		skew = client.cfg.clockSkew
+	}
+	if client.expireTimestamp != 0 && !time.Now().Add(skew).Before(time.Unix(client.expireTimestamp, 0)) {
+		return true
+	}
+	return grpcCode(err) == codes.Unauthenticated
+}
+
+// grpcCode returns the gRPC status code of err, or of the first error it wraps that
+// has one. It returns codes.Unknown if there is none.
+func grpcCode(err error) codes.Code {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	if errors.As(err, &grpcErr) {
+		return grpcErr.GRPCStatus().Code()
+	}
+	return codes.Unknown
+}
+
+// reconnect replaces the client's connection with one to an endpoint randomly selected
+// from client.endpointSpecs, authenticated with token.
+func (client *Client) reconnect(token string, expireTimestamp int64) error {
+	if client.shared != nil {
+		// Session clients keep using their shared connection, only with the new token.
+		client.grpcClient = newSessionHandlerClient(client.shared, token)
+		client.token, client.expireTimestamp = token, expireTimestamp
+		return nil
+	}
 
 	client.Close()
 
 	randGen := rand.New(rand.NewSource(time.Now().UnixNano()))
 
-	endpoints := strings.Split(client.endpointSpecs, " ")
+	endpointSpecs := client.endpointSpecs
+	if client.cfg.resolver != nil {
+		// Prefer the endpoints currently in service, but keep the old ones if the resolver fails.
+		if resolved, err := client.cfg.resolver.Resolve(context.Background()); err == nil && len(resolved) != 0 {
+			endpointSpecs = strings.Join(resolved, " ")
+		}
+	}
+
+	endpoints := strings.Split(endpointSpecs, " ")
 	var lastError error
 	for len(endpoints) != 0 {
 		i := randGen.Intn(len(endpoints))
 		endpoint := endpoints[i]
 
-		newClient, err := openOne(endpoint, client.certPath, token)
+		newClient, err := openOne(endpoint, client.certPath, token, client.cfg)
 		if err != nil {
 			lastError = err
 			endpoints = append(endpoints[:i], endpoints[i+1:]...)
 			continue
 		}
 		newClient.expireTimestamp = expireTimestamp
-		newClient.endpointSpecs = client.endpointSpecs
+		newClient.endpointSpecs = endpointSpecs
 
 		*client = *newClient
+		// Best effort: the renewal succeeded whether or not its token can be saved.
+		client.saveToken()
 		return nil
 	}
 	return fmt.Errorf("CLIENT: Failed to renew a connection. %v", lastError)
@@ -0,0 +1,204 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// DefaultBatchConcurrency is the number of invocations InvokeBatch runs at once when
+// BatchOptions.Concurrency is not set.
+const DefaultBatchConcurrency = 8
+
+// ErrBatchStopped is the error of the requests InvokeBatch did not start, because an
+// earlier request failed (with BatchOptions.StopOnError) or the context was done.
+var ErrBatchStopped = errors.New("CLIENT: Batch stopped")
+
+// InvokeRequest is one smart contract invocation of a batch.
+type InvokeRequest struct {
+	SmartContractSpec string
+	Args              []byte
+}
+
+// InvokeResult is the outcome of one InvokeRequest: the payload and commit ID returned
+// by IdentifiedInvoke, or an *InvokeError.
+type InvokeResult struct {
+	Payload  []byte
+	CommitID string
+	Err      error
+	Elapsed  time.Duration
+}
+
+// InvokeError is the error of a failed InvokeRequest. Err is the error of
+// IdentifiedInvoke, context.DeadlineExceeded if the request timed out, or
+// ErrBatchStopped if it was not started.
+type InvokeError struct {
+	Index             int
+	SmartContractSpec string
+	Err               error
+}
+
+func (e *InvokeError) Error() string {
+	return fmt.Sprintf("CLIENT: InvokeBatch: request %d (%s): %v", e.Index, e.SmartContractSpec, e.Err)
+}
+
+func (e *InvokeError) Unwrap() error {
+	return e.Err
+}
+
+// BatchOptions configures InvokeBatch.
+type BatchOptions struct {
+	// Concurrency bounds the number of invocations in flight. DefaultBatchConcurrency
+	// if zero.
+	Concurrency int
+	// StopOnError stops starting requests once one fails. Requests in flight complete.
+	StopOnError bool
+	// Timeout, if set, bounds the time InvokeBatch waits for each request. A request
+	// that times out may still be carried out by ParallelCore. Requests made through
+	// Invokers other than a *Client or a *Pool cannot be cancelled: their worker waits
+	// for them to return before starting another request, so that Concurrency holds.
+	Timeout time.Duration
+}
+
+// BatchSummary counts the outcomes of the requests of a batch.
+type BatchSummary struct {
+	Succeeded int
+	Failed    int
+	// Skipped requests were not started (see ErrBatchStopped).
+	Skipped int
+	Elapsed time.Duration
+}
+
+// InvokeBatch invokes requests with IdentifiedInvoke through invoker (e.g. a Client, or
+// a Pool to spread them over its connections), running up to opts.Concurrency of them
+// at once. It returns their results in the order of requests, and a summary.
+//
+// The error is nil if every request succeeded, and wraps the error of the first
+// failed request (in the order of requests) otherwise.
+func InvokeBatch(ctx context.Context, invoker Invoker, requests []InvokeRequest, opts BatchOptions) ([]InvokeResult, BatchSummary, error) {
+	start := time.Now()
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultBatchConcurrency
+	}
+
+	results := make([]InvokeResult, len(requests))
+	started := make([]bool, len(requests))
+	stop := make(chan struct{})
+	var stopOnce sync.Once
+
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency && w < len(requests); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				results[i] = invokeOne(ctx, invoker, requests[i], opts.Timeout)
+				if results[i].Err != nil {
+					results[i].Err = &InvokeError{Index: i, SmartContractSpec: requests[i].SmartContractSpec, Err: results[i].Err}
+					if opts.StopOnError {
+						stopOnce.Do(func() { close(stop) })
+					}
+				}
+			}
+		}()
+	}
+
+feed:
+	for i := range requests {
+		select {
+		case <-stop:
+			break feed
+		case <-ctx.Done():
+			break feed
+		default:
+		}
+		select {
+		case indexes <- i:
+			started[i] = true
+		case <-stop:
+			break feed
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	summary := BatchSummary{}
+	var first error
+	for i := range results {
+		if !started[i] {
+			results[i].Err = &InvokeError{Index: i, SmartContractSpec: requests[i].SmartContractSpec, Err: ErrBatchStopped}
+			summary.Skipped++
+		} else if results[i].Err != nil {
+			summary.Failed++
+		} else {
+			summary.Succeeded++
+		}
+		if first == nil && results[i].Err != nil {
+			first = results[i].Err
+		}
+	}
+	summary.Elapsed = time.Since(start)
+
+	if first != nil {
+		return results, summary, fmt.Errorf("CLIENT: InvokeBatch: %d failed, %d skipped of %d requests: %w", summary.Failed, summary.Skipped, len(requests), first)
+	}
+	return results, summary, nil
+}
+
+// invokeOne invokes request, giving up when ctx is done or timeout elapses. The
+// invocations of a *Client or a *Pool are then cancelled; invokeOne waits for those of
+// other Invokers, which take no context, to return, so that the worker running it does
+// not start another one meanwhile.
+func invokeOne(ctx context.Context, invoker Invoker, request InvokeRequest, timeout time.Duration) InvokeResult {
+	start := time.Now()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	var result InvokeResult
+	switch invoker.(type) {
+	case *Client, *Pool:
+		result.Payload, result.CommitID, result.Err = identifiedInvokeContext(ctx, invoker, request.SmartContractSpec, request.Args)
+		result.Elapsed = time.Since(start)
+		return result
+	}
+
+	done := make(chan InvokeResult, 1)
+	go func() {
+		payload, commitID, err := invoker.IdentifiedInvoke(request.SmartContractSpec, request.Args)
+		done <- InvokeResult{Payload: payload, CommitID: commitID, Err: err}
+	}()
+	select {
+	case result = <-done:
+		result.Elapsed = time.Since(start)
+	case <-ctx.Done():
+		result.Err = ctx.Err()
+		result.Elapsed = time.Since(start)
+		<-done
+	}
+	return result
+}
+
+// InvokeBatch invokes requests over client (see the InvokeBatch function).
+func (client *Client) InvokeBatch(ctx context.Context, requests []InvokeRequest, opts BatchOptions) ([]InvokeResult, BatchSummary, error) {
+	return InvokeBatch(ctx, client, requests, opts)
+}
+
+// InvokeBatch invokes requests over the connections of pool, in turn (see the
+// InvokeBatch function).
+func (pool *Pool) InvokeBatch(ctx context.Context, requests []InvokeRequest, opts BatchOptions) ([]InvokeResult, BatchSummary, error) {
+	return InvokeBatch(ctx, pool, requests, opts)
+}
@@ -0,0 +1,101 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// scripted is an Invoker whose IdentifiedInvoke echoes its args, failing on "fail"
+// and sleeping on "slow".
+type scripted struct {
+	Invoker
+	inFlight, maxInFlight, calls int32
+}
+
+func (s *scripted) IdentifiedInvoke(spec string, args []byte) ([]byte, string, error) {
+	atomic.AddInt32(&s.calls, 1)
+	n := atomic.AddInt32(&s.inFlight, 1)
+	defer atomic.AddInt32(&s.inFlight, -1)
+	for {
+		max := atomic.LoadInt32(&s.maxInFlight)
+		if n <= max || atomic.CompareAndSwapInt32(&s.maxInFlight, max, n) {
+			break
+		}
+	}
+
+	time.Sleep(time.Millisecond)
+	switch string(args) {
+	case "fail":
+		return nil, "", errors.New("failed")
+	case "slow":
+		time.Sleep(200 * time.Millisecond)
+	}
+	return args, "commit-" + string(args), nil
+}
+
+func requests(args ...string) []InvokeRequest {
+	requests := make([]InvokeRequest, len(args))
+	for i, arg := range args {
+		requests[i] = InvokeRequest{SmartContractSpec: "echo-v1", Args: []byte(arg)}
+	}
+	return requests
+}
+
+func TestInvokeBatch(t *testing.T) {
+	var args []string
+	for i := 0; i < 20; i++ {
+		args = append(args, fmt.Sprint(i))
+	}
+	invoker := &scripted{}
+	results, summary, err := InvokeBatch(context.Background(), invoker, requests(args...), BatchOptions{Concurrency: 3})
+	if err != nil || summary.Succeeded != 20 {
+		t.Fatalf("InvokeBatch = %+v, %v", summary, err)
+	}
+	for i, result := range results {
+		if string(result.Payload) != args[i] || result.CommitID != "commit-"+args[i] {
+			t.Errorf("result %d = %+v", i, result)
+		}
+	}
+	if invoker.maxInFlight > 3 {
+		t.Errorf("%d invocations in flight, want at most 3", invoker.maxInFlight)
+	}
+}
+
+func TestInvokeBatchErrors(t *testing.T) {
+	results, summary, err := InvokeBatch(context.Background(), &scripted{}, requests("a", "fail", "slow", "b"), BatchOptions{Timeout: 50 * time.Millisecond})
+	var invokeErr *InvokeError
+	if !errors.As(err, &invokeErr) || invokeErr.Index != 1 || summary.Succeeded != 2 || summary.Failed != 2 {
+		t.Errorf("InvokeBatch = %+v, %v", summary, err)
+	}
+	if !errors.Is(results[2].Err, context.DeadlineExceeded) {
+		t.Errorf("slow request error = %v", results[2].Err)
+	}
+
+	// A timed-out request keeps its worker until it returns.
+	invoker := &scripted{}
+	results, summary, _ = InvokeBatch(context.Background(), invoker, requests("slow", "a", "b"), BatchOptions{Concurrency: 1, Timeout: 50 * time.Millisecond})
+	if !errors.Is(results[0].Err, context.DeadlineExceeded) || summary.Succeeded != 2 || invoker.maxInFlight != 1 {
+		t.Errorf("InvokeBatch with a timeout = %+v, %d invocations in flight, want 1", summary, invoker.maxInFlight)
+	}
+
+	invoker = &scripted{}
+	results, summary, _ = InvokeBatch(context.Background(), invoker, requests("fail", "a", "b", "c"), BatchOptions{Concurrency: 1, StopOnError: true})
+	if summary.Failed != 1 || summary.Skipped < 2 || !errors.Is(results[3].Err, ErrBatchStopped) {
+		t.Errorf("InvokeBatch with StopOnError = %+v, %v", summary, results)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, summary, err = InvokeBatch(ctx, &scripted{}, requests("a", "b"), BatchOptions{}); !errors.Is(err, ErrBatchStopped) || summary.Skipped != 2 {
+		t.Errorf("InvokeBatch with a cancelled context = %+v, %v", summary, err)
+	}
+}
@@ -5,6 +5,11 @@
 
 package parallelcore_client_sdk_go
 
+import (
+	"encoding/json"
+	"fmt"
+)
+
 // GetBlockchainSummaryJson returns a JSON-encoded object with information about
 // all blockchain(s) in the ParallelChain network.
 //
@@ -30,6 +35,20 @@ func (client *Client) GetBlockchainSummaryJson() ([]byte, error) {
 	return callUserMan(client, API_GET_BLOCK_CHAIN_SUMMARY_JSON, make([]byte, 0))
 }
 
+// GetBlockchainSummary is similar to GetBlockchainSummaryJson, but returns the summary
+// decoded into a BlockchainSummary (see type definition).
+func (client *Client) GetBlockchainSummary() (BlockchainSummary, error) {
+	var summary BlockchainSummary
+	raw, err := client.GetBlockchainSummaryJson()
+	if err != nil {
+		return summary, err
+	}
+	if err = json.Unmarshal(raw, &summary); err != nil {
+		return summary, fmt.Errorf(E_FUNC_X_OUTPUT_DECODE_ERROR_X, API_GET_BLOCK_CHAIN_SUMMARY_JSON, err)
+	}
+	return summary, nil
+}
+
 // GetBlockDetailsJson returns a JSON-encoded object with information about the block
 // identified by chainID and blockID.
 //
@@ -44,7 +63,7 @@ func (client *Client) GetBlockchainSummaryJson() ([]byte, error) {
 //  - network_address string
 //  - pcore_id string
 //  - prev_hash string
-//  - status int
+//  - status int: BLOCK_STATUS_OPEN or BLOCK_STATUS_SEALED
 func (client *Client) GetBlockDetailsJson(chainID string, blockID string) ([]byte, error) {
 	return callUserManV(client, API_GET_BLOCK_DETAILS_JSON, BlockData{ChainId: chainID, BlockId: blockID})
 }
@@ -0,0 +1,238 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// REDACTED replaces secrets (credentials and tokens) in cassettes.
+const REDACTED = "REDACTED"
+
+// CassetteEntry is one recorded call, stored as one line of JSON in a cassette.
+type CassetteEntry struct {
+	// Method is the name of the RequestHandler method called, e.g. "Invoke".
+	Method string `json:"method"`
+	// ClientID and Request are the request of the call. ClientID is only set for Auth,
+	// whose credential is never recorded.
+	ClientID string `json:"clientId,omitempty"`
+	Request  []byte `json:"request,omitempty"`
+	// Response, Error and CommitID are the fields of the response.
+	Response []byte `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+	CommitID string `json:"commitId,omitempty"`
+	// Status is set when the call failed at the transport level.
+	Status *CassetteStatus `json:"status,omitempty"`
+	// Stream holds the messages of streaming calls (RegisterEventListener), in order.
+	Stream []CassetteMessage `json:"stream,omitempty"`
+}
+
+// CassetteStatus is a recorded gRPC error.
+type CassetteStatus struct {
+	Code    codes.Code `json:"code"`
+	Message string     `json:"message"`
+}
+
+// CassetteMessage is one message of a recorded stream: either Sent by the client, or
+// Received (with a response Error, if any) from the server.
+type CassetteMessage struct {
+	Sent     []byte `json:"sent,omitempty"`
+	Received []byte `json:"received,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Redactor removes secrets from a CassetteEntry before it is recorded.
+type Redactor func(entry *CassetteEntry)
+
+// Recorder writes every call made by the Clients opened with WithRecorder to a
+// cassette: a stream of JSON-encoded CassetteEntry values, one per line. Cassettes can
+// be replayed with WithReplay.
+//
+// Credentials, tokens and the "clientCredential", "credential" and "password" fields of
+// JSON payloads are always redacted. Redact, if set, is called on every entry after
+// that, to remove application-specific secrets.
+type Recorder struct {
+	Redact Redactor
+
+	mu  sync.Mutex
+	enc *json.Encoder
+	err error
+}
+
+// NewRecorder returns a Recorder writing its cassette to w.
+func NewRecorder(w io.Writer) *Recorder {
+	return &Recorder{enc: json.NewEncoder(w)}
+}
+
+// Err returns the first error encountered while writing the cassette, or nil.
+func (recorder *Recorder) Err() error {
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	return recorder.err
+}
+
+// WithRecorder makes connections record every call to recorder.
+func WithRecorder(recorder *Recorder) Option {
+	return func(cfg *openConfig) {
+		cfg.unaryInterceptors = append(cfg.unaryInterceptors, recorder.interceptUnary)
+		cfg.streamInterceptors = append(cfg.streamInterceptors, recorder.interceptStream)
+	}
+}
+
+func (recorder *Recorder) write(entry *CassetteEntry) {
+	redact(entry)
+	if recorder.Redact != nil {
+		recorder.Redact(entry)
+	}
+
+	recorder.mu.Lock()
+	defer recorder.mu.Unlock()
+	if err := recorder.enc.Encode(entry); err != nil && recorder.err == nil {
+		recorder.err = fmt.Errorf("CLIENT: Recorder: %w", err)
+	}
+}
+
+func (recorder *Recorder) interceptUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	err := invoker(ctx, method, req, reply, cc, opts...)
+
+	entry := &CassetteEntry{Method: path.Base(method)}
+	switch request := req.(type) {
+	case *pb.AuthRequest:
+		entry.ClientID = string(request.ClientId)
+	case *pb.Request:
+		entry.Request = request.Payload
+	}
+	if err != nil {
+		entry.Status = cassetteStatus(err)
+	} else {
+		switch response := reply.(type) {
+		case *pb.Response:
+			entry.Response, entry.Error = response.Payload, string(response.Error)
+		case *pb.IdentifiedResponse:
+			entry.Response, entry.Error, entry.CommitID = response.Payload, string(response.Error), string(response.CommittedId)
+		}
+	}
+	recorder.write(entry)
+	return err
+}
+
+func (recorder *Recorder) interceptStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		recorder.write(&CassetteEntry{Method: path.Base(method), Status: cassetteStatus(err)})
+		return nil, err
+	}
+	return &recordedStream{ClientStream: stream, recorder: recorder, entry: &CassetteEntry{Method: path.Base(method)}}, nil
+}
+
+// recordedStream records the messages of a stream, writing its entry once the stream ends.
+type recordedStream struct {
+	grpc.ClientStream
+	recorder *Recorder
+
+	mu    sync.Mutex
+	entry *CassetteEntry
+	done  bool
+}
+
+func (stream *recordedStream) SendMsg(m interface{}) error {
+	if request, ok := m.(*pb.Request); ok {
+		stream.mu.Lock()
+		stream.entry.Stream = append(stream.entry.Stream, CassetteMessage{Sent: request.Payload})
+		stream.mu.Unlock()
+	}
+	return stream.ClientStream.SendMsg(m)
+}
+
+func (stream *recordedStream) RecvMsg(m interface{}) error {
+	err := stream.ClientStream.RecvMsg(m)
+
+	stream.mu.Lock()
+	defer stream.mu.Unlock()
+	if stream.done {
+		return err
+	}
+	if err != nil {
+		if err != io.EOF {
+			stream.entry.Status = cassetteStatus(err)
+		}
+		stream.done = true
+		stream.recorder.write(stream.entry)
+		return err
+	}
+	if response, ok := m.(*pb.Response); ok {
+		stream.entry.Stream = append(stream.entry.Stream, CassetteMessage{Received: response.Payload, Error: string(response.Error)})
+	}
+	return nil
+}
+
+func cassetteStatus(err error) *CassetteStatus {
+	s, _ := status.FromError(err)
+	return &CassetteStatus{Code: s.Code(), Message: s.Message()}
+}
+
+// redact removes the secrets the SDK knows about from entry.
+func redact(entry *CassetteEntry) {
+	if (entry.Method == "Auth" || entry.Method == "Renew") && len(entry.Response) != 0 {
+		// Keep the expiry: clients parse it from the response.
+		if fields := strings.Fields(string(entry.Response)); len(fields) != 0 {
+			fields[0] = REDACTED
+			entry.Response = []byte(strings.Join(fields, " "))
+		}
+	}
+	entry.Request = redactJSON(entry.Request)
+	for i := range entry.Stream {
+		entry.Stream[i].Sent = redactJSON(entry.Stream[i].Sent)
+	}
+}
+
+var secretFields = []string{"clientCredential", "credential", "password"}
+
+// redactJSON redacts the secret fields of a JSON object, including those of the JSON
+// object encoded in its "data" field (as sent to UserMan and SysMan). Payloads that
+// are not JSON objects are returned unchanged.
+func redactJSON(payload []byte) []byte {
+	var object map[string]interface{}
+	if json.Unmarshal(payload, &object) != nil || object == nil {
+		return payload
+	}
+	changed := false
+	for _, field := range secretFields {
+		if _, ok := object[field]; ok {
+			object[field] = REDACTED
+			changed = true
+		}
+	}
+	if data, ok := object["data"].(string); ok {
+		if raw, err := base64.StdEncoding.DecodeString(data); err == nil {
+			if redacted := redactJSON(raw); string(redacted) != string(raw) {
+				object["data"] = redacted
+				changed = true
+			}
+		}
+	}
+	if !changed {
+		return payload
+	}
+	redacted, err := json.Marshal(object)
+	if err != nil {
+		return payload
+	}
+	return redacted
+}
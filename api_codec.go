@@ -0,0 +1,219 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/golang/protobuf/proto"
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Codec encodes the data of the tasks sent to smart contracts by CallSmartContract (and
+// ContractCaller), and decodes their results.
+//
+// Task data travels in the Data string of a JSON-encoded pb.ScTask whose Codec field
+// names the codec: the encoding of Binary codecs is base64-encoded (with
+// base64.StdEncoding) to survive the trip. Results are decoded from the raw bytes
+// returned by the smart contract.
+type Codec interface {
+	// Name identifies the codec in tasks, e.g. "json".
+	Name() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+	// Binary reports whether encodings may not be valid UTF-8 text.
+	Binary() bool
+}
+
+// The codecs provided by the SDK.
+var (
+	// JSONCodec encodes values with encoding/json.
+	JSONCodec Codec = jsonCodec{}
+	// ProtoCodec encodes protobuf messages (values implementing proto.Message).
+	ProtoCodec Codec = protoCodec{}
+	// CBORCodec encodes values in CBOR (RFC 7049).
+	CBORCodec Codec = cborCodec{}
+	// MsgpackCodec encodes values in MessagePack.
+	MsgpackCodec Codec = msgpackCodec{}
+	// Base64Codec encodes byte slices and strings in base64 (base64.StdEncoding), and
+	// decodes into *[]byte and *string values.
+	Base64Codec Codec = base64Codec{}
+)
+
+var codecs = struct {
+	sync.RWMutex
+	byName map[string]Codec
+}{byName: map[string]Codec{"json": JSONCodec, "proto": ProtoCodec, "cbor": CBORCodec, "msgpack": MsgpackCodec, "base64": Base64Codec}}
+
+// RegisterCodec makes codec available to CodecByName and DecodeTask.
+func RegisterCodec(codec Codec) {
+	codecs.Lock()
+	defer codecs.Unlock()
+	codecs.byName[codec.Name()] = codec
+}
+
+// CodecByName returns the registered codec named name, or nil.
+func CodecByName(name string) Codec {
+	codecs.RLock()
+	defer codecs.RUnlock()
+	return codecs.byName[name]
+}
+
+// WithCodec makes CallSmartContract and CallSmartContractJSON use codec for the smart
+// contract named scName, instead of the legacy encoding (see CallSmartContract).
+func WithCodec(scName string, codec Codec) Option {
+	return func(cfg *openConfig) {
+		if cfg.codecs == nil {
+			cfg.codecs = make(map[string]Codec)
+		}
+		cfg.codecs[scName] = codec
+	}
+}
+
+// codec returns the codec client uses for the smart contract named scName, or nil.
+func (client *Client) codec(scName string) Codec {
+	if client.cfg == nil {
+		return nil
+	}
+	return client.cfg.codecs[scName]
+}
+
+// encodeTask returns the JSON-encoded ScTask calling action with v encoded by codec.
+func encodeTask(codec Codec, action string, v interface{}) ([]byte, error) {
+	data, err := codec.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: %s codec: %w", codec.Name(), err)
+	}
+	text := string(data)
+	if codec.Binary() {
+		text = base64.StdEncoding.EncodeToString(data)
+	}
+	return json.Marshal(pb.ScTask{Action: action, Data: text, Codec: codec.Name()})
+}
+
+// DecodeTask decodes a JSON-encoded ScTask, as sent by CallSmartContract, and its data
+// into v with the codec the task names (the data is stored as is into *string and
+// *[]byte values if it names none). Smart contracts written in Go can use it to read
+// their tasks.
+func DecodeTask(raw []byte, v interface{}) (pb.ScTask, error) {
+	var task pb.ScTask
+	if err := json.Unmarshal(raw, &task); err != nil {
+		return task, fmt.Errorf("CLIENT: DecodeTask: %w", err)
+	}
+	if task.Codec == "" {
+		switch value := v.(type) {
+		case *string:
+			*value = task.Data
+		case *[]byte:
+			*value = []byte(task.Data)
+		default:
+			if err := json.Unmarshal([]byte(task.Data), v); err != nil {
+				return task, fmt.Errorf("CLIENT: DecodeTask: %w", err)
+			}
+		}
+		return task, nil
+	}
+
+	codec := CodecByName(task.Codec)
+	if codec == nil {
+		return task, fmt.Errorf("CLIENT: DecodeTask: unknown codec %q", task.Codec)
+	}
+	data := []byte(task.Data)
+	if codec.Binary() {
+		decoded, err := base64.StdEncoding.DecodeString(task.Data)
+		if err != nil {
+			return task, fmt.Errorf("CLIENT: DecodeTask: %w", err)
+		}
+		data = decoded
+	}
+	if err := codec.Unmarshal(data, v); err != nil {
+		return task, fmt.Errorf("CLIENT: DecodeTask: %s codec: %w", codec.Name(), err)
+	}
+	return task, nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                               { return "json" }
+func (jsonCodec) Binary() bool                               { return false }
+func (jsonCodec) Marshal(v interface{}) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error { return json.Unmarshal(data, v) }
+
+type protoCodec struct{}
+
+func (protoCodec) Name() string { return "proto" }
+func (protoCodec) Binary() bool { return true }
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return proto.Marshal(message)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%T is not a proto.Message", v)
+	}
+	return proto.Unmarshal(data, message)
+}
+
+type cborCodec struct{}
+
+func (cborCodec) Name() string                               { return "cbor" }
+func (cborCodec) Binary() bool                               { return true }
+func (cborCodec) Marshal(v interface{}) ([]byte, error)      { return cbor.Marshal(v) }
+func (cborCodec) Unmarshal(data []byte, v interface{}) error { return cbor.Unmarshal(data, v) }
+
+type msgpackCodec struct{}
+
+func (msgpackCodec) Name() string                               { return "msgpack" }
+func (msgpackCodec) Binary() bool                               { return true }
+func (msgpackCodec) Marshal(v interface{}) ([]byte, error)      { return msgpack.Marshal(v) }
+func (msgpackCodec) Unmarshal(data []byte, v interface{}) error { return msgpack.Unmarshal(data, v) }
+
+type base64Codec struct{}
+
+func (base64Codec) Name() string { return "base64" }
+func (base64Codec) Binary() bool { return false }
+
+func (base64Codec) Marshal(v interface{}) ([]byte, error) {
+	var raw []byte
+	switch value := v.(type) {
+	case []byte:
+		raw = value
+	case string:
+		raw = []byte(value)
+	case nil:
+	default:
+		return nil, fmt.Errorf("cannot encode %T, only []byte and string", v)
+	}
+	return []byte(base64.StdEncoding.EncodeToString(raw)), nil
+}
+
+func (base64Codec) Unmarshal(data []byte, v interface{}) error {
+	raw, err := base64.StdEncoding.DecodeString(string(data))
+	if err != nil {
+		return err
+	}
+	switch value := v.(type) {
+	case *[]byte:
+		*value = raw
+	case *string:
+		*value = string(raw)
+	default:
+		return fmt.Errorf("cannot decode into %T, only *[]byte and *string", v)
+	}
+	return nil
+}
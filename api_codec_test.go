@@ -0,0 +1,74 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+)
+
+type transfer struct {
+	From   string `json:"from" cbor:"from"`
+	Amount int64  `json:"amount" cbor:"amount"`
+}
+
+func TestCodecs(t *testing.T) {
+	binary := []byte{0xff, 0x00, 0xfe, ' ', '\n'}
+	for _, test := range []struct {
+		codec  Codec
+		in     interface{}
+		out    interface{}
+		result interface{}
+	}{
+		{JSONCodec, transfer{"alice", 10}, &transfer{}, &transfer{"alice", 10}},
+		{CBORCodec, transfer{"alice", 10}, &transfer{}, &transfer{"alice", 10}},
+		{MsgpackCodec, transfer{"alice", 10}, &transfer{}, &transfer{"alice", 10}},
+		{ProtoCodec, &pb.Request{Payload: binary}, &pb.Request{}, nil},
+		{Base64Codec, binary, &[]byte{}, &binary},
+	} {
+		task, err := encodeTask(test.codec, "transfer", test.in)
+		if err != nil {
+			t.Errorf("%s: encodeTask: %v", test.codec.Name(), err)
+			continue
+		}
+		decoded, err := DecodeTask(task, test.out)
+		if err != nil || decoded.Action != "transfer" || decoded.Codec != test.codec.Name() {
+			t.Errorf("%s: DecodeTask(%s) = %+v, %v", test.codec.Name(), task, decoded, err)
+			continue
+		}
+		if request, ok := test.out.(*pb.Request); ok {
+			if !bytes.Equal(request.Payload, binary) {
+				t.Errorf("%s: decoded %v", test.codec.Name(), request.Payload)
+			}
+		} else if !reflect.DeepEqual(test.out, test.result) {
+			t.Errorf("%s: decoded %v, want %v", test.codec.Name(), test.out, test.result)
+		}
+	}
+
+	if _, err := ProtoCodec.Marshal("not a message"); err == nil {
+		t.Error("ProtoCodec encoded a string")
+	}
+	if CodecByName("cbor") != CBORCodec || CodecByName("msgpack") != MsgpackCodec || CodecByName("xml") != nil {
+		t.Error("CodecByName does not return the built-in codecs")
+	}
+}
+
+func TestLegacyTaskRejectsBinaryData(t *testing.T) {
+	if _, err := smartContractTask("store", []byte{0xff, 0x00}); err == nil {
+		t.Error("binary data was encoded")
+	}
+	if _, err := smartContractTask("store", func() {}); err == nil {
+		t.Error("a func was encoded")
+	}
+	task, err := smartContractTask("store", "text")
+	var data string
+	if _, decodeErr := DecodeTask(task, &data); err != nil || decodeErr != nil || data != "text" {
+		t.Errorf("legacy task %s = %q, %v, %v", task, data, err, decodeErr)
+	}
+}
@@ -0,0 +1,271 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Consistency selects how a ConsistentSession makes its calls see its own commits.
+type Consistency int
+
+const (
+	// StickyReads sends the calls of a session to the endpoint of its last commit, for
+	// as long as the pool is connected to it, and waits as WaitForCommit does otherwise.
+	StickyReads Consistency = iota
+	// WaitForCommit sends the calls of a session to any endpoint of the pool that can
+	// see its last commit, waiting for one to, if none can yet.
+	WaitForCommit
+)
+
+// ReadYourWritesOptions configures a ConsistentSession.
+type ReadYourWritesOptions struct {
+	Mode Consistency
+	// WaitTimeout bounds how long a call waits for an endpoint to see the last commit.
+	// DEFAULT_COMMIT_WAIT_TIMEOUT if zero.
+	WaitTimeout time.Duration
+	// PollInterval is how often endpoints are asked whether they see the last commit.
+	// DEFAULT_COMMIT_POLL_INTERVAL if zero.
+	PollInterval time.Duration
+}
+
+// ConsistentSession makes calls over the Clients of a Pool such that each call sees
+// the commits of the session's earlier calls, even though the pool's endpoints may not
+// all have caught up with them yet. The session remembers the commit ID of its last
+// state-changing invocation, and the endpoint that made it.
+//
+// An endpoint sees a commit once GetSmartContractTransactionMetadataJson finds it
+// there. A ConsistentSession is safe for concurrent use.
+type ConsistentSession struct {
+	pool *Pool
+	opts ReadYourWritesOptions
+	// next rotates the endpoint find starts from.
+	next uint64
+
+	mu         sync.Mutex
+	lastCommit string
+	// seen holds the endpoints known to see lastCommit.
+	seen map[string]bool
+	// sticky is the endpoint that made lastCommit, if any.
+	sticky string
+}
+
+// ReadYourWrites returns a new ConsistentSession over pool.
+func (pool *Pool) ReadYourWrites(opts ReadYourWritesOptions) *ConsistentSession {
+	if opts.WaitTimeout <= 0 {
+		opts.WaitTimeout = DEFAULT_COMMIT_WAIT_TIMEOUT
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DEFAULT_COMMIT_POLL_INTERVAL
+	}
+	return &ConsistentSession{pool: pool, opts: opts, seen: make(map[string]bool)}
+}
+
+// LastCommit returns the commit ID the session's calls wait for, or "".
+func (session *ConsistentSession) LastCommit() string {
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	return session.lastCommit
+}
+
+// Observe makes the session's later calls see the transaction commitID, made outside
+// of the session (e.g. by another process).
+func (session *ConsistentSession) Observe(commitID string) {
+	session.record(commitID, "")
+}
+
+func (session *ConsistentSession) record(commitID string, endpoint string) {
+	if commitID == "" {
+		return
+	}
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	session.lastCommit, session.sticky = commitID, endpoint
+	session.seen = make(map[string]bool)
+	if endpoint != "" {
+		session.seen[endpoint] = true
+	}
+}
+
+// Client returns a Client of the pool that sees the session's last commit. Calls made
+// directly on it are not tracked by the session.
+func (session *ConsistentSession) Client() (*Client, error) {
+	_, client, err := session.route()
+	return client, err
+}
+
+// route returns a Client of the pool that sees the session's last commit, and its
+// endpoint.
+func (session *ConsistentSession) route() (string, *Client, error) {
+	session.mu.Lock()
+	commitID, sticky := session.lastCommit, session.sticky
+	session.mu.Unlock()
+
+	if commitID == "" {
+		return session.pool.pick()
+	}
+	if session.opts.Mode == StickyReads && sticky != "" {
+		if client := session.pool.client(sticky); client != nil {
+			return sticky, client, nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), session.opts.WaitTimeout)
+	defer cancel()
+	ticker := time.NewTicker(session.opts.PollInterval)
+	defer ticker.Stop()
+	var lastErr error
+	for {
+		endpoint, client, err := session.find(commitID)
+		if client != nil {
+			return endpoint, client, nil
+		}
+		if err != nil {
+			lastErr = err
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			if lastErr != nil {
+				return "", nil, fmt.Errorf("CLIENT: ReadYourWrites: No endpoint sees commit %s: %w. Last error: %v", commitID, ctx.Err(), lastErr)
+			}
+			return "", nil, fmt.Errorf("CLIENT: ReadYourWrites: No endpoint sees commit %s: %w", commitID, ctx.Err())
+		}
+	}
+}
+
+// find returns an endpoint of the pool that sees commitID, and its Client, trying the
+// endpoints in turn from a different one every time. The Client is nil if none does
+// yet; the error is then the last one met while asking them, other than the commit
+// not being found.
+func (session *ConsistentSession) find(commitID string) (string, *Client, error) {
+	endpoints := session.pool.Endpoints()
+	start := atomic.AddUint64(&session.next, 1)
+	var lastErr error
+	for i := range endpoints {
+		endpoint := endpoints[(start+uint64(i))%uint64(len(endpoints))]
+		client := session.pool.client(endpoint)
+		if client == nil {
+			continue
+		}
+
+		session.mu.Lock()
+		seen := session.lastCommit == commitID && session.seen[endpoint]
+		session.mu.Unlock()
+		if seen {
+			return endpoint, client, nil
+		}
+
+		if _, err := client.GetSmartContractTransactionMetadataJson(commitID); err != nil {
+			if !transactionNotFound(err, commitID) {
+				lastErr = err
+			}
+			continue
+		}
+		session.mu.Lock()
+		if session.lastCommit == commitID {
+			session.seen[endpoint] = true
+		}
+		session.mu.Unlock()
+		return endpoint, client, nil
+	}
+	return "", nil, lastErr
+}
+
+// The methods below make a ConsistentSession usable as an Invoker and a
+// BlockchainReader: each one calls the Client method of the same name on a Client
+// returned by route. Invocations are made with IdentifiedInvoke, so that the session
+// learns of their commits.
+
+func (session *ConsistentSession) Invoke(smartContractSpec string, args []byte) ([]byte, error) {
+	payload, _, err := session.IdentifiedInvoke(smartContractSpec, args)
+	return payload, err
+}
+
+func (session *ConsistentSession) IdentifiedInvoke(smartContractSpec string, args []byte) ([]byte, string, error) {
+	endpoint, client, err := session.route()
+	if err != nil {
+		return nil, "", err
+	}
+	payload, commitID, err := client.IdentifiedInvoke(smartContractSpec, args)
+	if err == nil {
+		session.record(commitID, endpoint)
+	}
+	return payload, commitID, err
+}
+
+func (session *ConsistentSession) ListInvokableSC() ([]byte, error) {
+	client, err := session.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.ListInvokableSC()
+}
+
+func (session *ConsistentSession) GetBlockchainSummaryJson() ([]byte, error) {
+	client, err := session.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetBlockchainSummaryJson()
+}
+
+func (session *ConsistentSession) GetBlockchainSummary() (BlockchainSummary, error) {
+	client, err := session.Client()
+	if err != nil {
+		return BlockchainSummary{}, err
+	}
+	return client.GetBlockchainSummary()
+}
+
+func (session *ConsistentSession) GetBlockDetailsJson(chainID string, blockID string) ([]byte, error) {
+	client, err := session.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetBlockDetailsJson(chainID, blockID)
+}
+
+func (session *ConsistentSession) CalculateBlockHash(chainID string, blockID string) ([]byte, error) {
+	client, err := session.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.CalculateBlockHash(chainID, blockID)
+}
+
+func (session *ConsistentSession) GetSmartContractTransactionJson(transactionId string) ([]byte, error) {
+	client, err := session.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSmartContractTransactionJson(transactionId)
+}
+
+func (session *ConsistentSession) GetSmartContractTransactionMetadataJson(transactionId string) ([]byte, error) {
+	client, err := session.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.GetSmartContractTransactionMetadataJson(transactionId)
+}
+
+func (session *ConsistentSession) ListLatestTransactions(count int) ([]byte, error) {
+	client, err := session.Client()
+	if err != nil {
+		return nil, err
+	}
+	return client.ListLatestTransactions(count)
+}
+
+var (
+	_ Invoker          = (*ConsistentSession)(nil)
+	_ BlockchainReader = (*ConsistentSession)(nil)
+)
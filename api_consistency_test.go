@@ -0,0 +1,148 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestReadYourWrites(t *testing.T) {
+	const node0, node1 = "node0.pcoretest.local:5000", "node1.pcoretest.local:5000"
+	_, server := newCounterServer(t)
+	server.SetEndpoints(node0, node1)
+	// Each node only sees its own commits.
+	server.SetLagging(node0, true)
+	server.SetLagging(node1, true)
+
+	resolver := &endpointList{}
+	resolver.set(node0, node1)
+	pool, err := sdk.OpenPool(resolver, pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), server.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	// Without a session, reads land on a node that has not seen the commit.
+	_, commitID, err := pool.IdentifiedInvoke("counter-v1", []byte(`{"action":"set","data":"a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	missed := 0
+	for i := 0; i < 2; i++ {
+		if _, err = pool.GetSmartContractTransactionMetadataJson(commitID); err != nil {
+			missed++
+		}
+	}
+	if missed != 1 {
+		t.Fatalf("pool missed commit %d times out of 2, want 1", missed)
+	}
+
+	for _, mode := range []sdk.Consistency{sdk.StickyReads, sdk.WaitForCommit} {
+		session := pool.ReadYourWrites(sdk.ReadYourWritesOptions{Mode: mode, PollInterval: 5 * time.Millisecond})
+		for i := 0; i < 4; i++ {
+			_, commitID, err := session.IdentifiedInvoke("counter-v1", []byte(fmt.Sprintf(`{"action":"set","data":"%d-%d"}`, mode, i)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.LastCommit() != commitID {
+				t.Fatalf("LastCommit() = %q, want %q", session.LastCommit(), commitID)
+			}
+			if _, err = session.GetSmartContractTransactionMetadataJson(commitID); err != nil {
+				t.Fatalf("mode %d: commit %d: %v", mode, i, err)
+			}
+		}
+	}
+
+	// Reads wait for a node to see commits it has not caught up with.
+	session := pool.ReadYourWrites(sdk.ReadYourWritesOptions{WaitTimeout: 50 * time.Millisecond, PollInterval: 5 * time.Millisecond})
+	session.Observe("tx-unknown")
+	if _, err = session.ListLatestTransactions(1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("read of unknown commit = %v, want context.DeadlineExceeded", err)
+	}
+
+	session = pool.ReadYourWrites(sdk.ReadYourWritesOptions{Mode: sdk.StickyReads, PollInterval: 5 * time.Millisecond})
+	_, commitID, err = session.IdentifiedInvoke("counter-v1", []byte(`{"action":"set","data":"b"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := node0
+	for i, client := range pool.Clients() {
+		if _, err = client.GetSmartContractTransactionMetadataJson(commitID); err == nil && pool.Endpoints()[i] == node0 {
+			other = node1
+		}
+	}
+	resolver.set(other)
+	pool.Refresh()
+	time.AfterFunc(30*time.Millisecond, func() { server.SetLagging(other, false) })
+
+	start := time.Now()
+	if _, err = session.GetSmartContractTransactionMetadataJson(commitID); err != nil {
+		t.Fatalf("read after the commit's node left: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("read returned after %v, before %s caught up", elapsed, other)
+	}
+}
+
+func TestReadYourWritesSpreadsAndSurvivesErrors(t *testing.T) {
+	const node0, node1 = "node0.pcoretest.local:5000", "node1.pcoretest.local:5000"
+	_, server := newCounterServer(t)
+	server.SetEndpoints(node0, node1)
+
+	// The first lookup of a commit fails on whichever node it is made to.
+	injector := sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"UserMan"}, Times: 1, Code: codes.Unavailable})
+	opts := append(server.Options(), sdk.WithFaultInjector(injector))
+	pool, err := sdk.OpenPool(sdk.StaticResolver(server.EndpointSpecs()), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	_, commitID, err := pool.IdentifiedInvoke("counter-v1", []byte(`{"action":"set","data":"a"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := pool.ReadYourWrites(sdk.ReadYourWritesOptions{Mode: sdk.WaitForCommit, PollInterval: 5 * time.Millisecond})
+	session.Observe(commitID)
+	used := make(map[*sdk.Client]bool)
+	for i := 0; i < 4; i++ {
+		client, err := session.Client()
+		if err != nil {
+			t.Fatalf("Client() = %v", err)
+		}
+		used[client] = true
+	}
+	if fired := injector.Fired()[0]; fired != 1 {
+		t.Errorf("fault fired %d times, want 1", fired)
+	}
+	if len(used) != 2 {
+		t.Errorf("session used %d of 2 nodes", len(used))
+	}
+
+	// Errors other than the commit not being found are reported on timeout.
+	injector = sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"UserMan"}, Code: codes.Unavailable, Message: "node down"})
+	opts = append(server.Options(), sdk.WithFaultInjector(injector))
+	failing, err := sdk.OpenPool(sdk.StaticResolver(server.EndpointSpecs()), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer failing.Close()
+	session = failing.ReadYourWrites(sdk.ReadYourWritesOptions{WaitTimeout: 30 * time.Millisecond, PollInterval: 5 * time.Millisecond})
+	session.Observe(commitID)
+	if _, err = session.Client(); !errors.Is(err, context.DeadlineExceeded) || !strings.Contains(err.Error(), "node down") {
+		t.Errorf("Client() with failing nodes = %v", err)
+	}
+}
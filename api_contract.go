@@ -0,0 +1,149 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ContractCaller calls the actions of one smart contract, encoding arguments and
+// decoding results the way CallSmartContract and CallSmartContractJSON do. It is the
+// runtime of the typed clients generated by cmd/pcore-scgen.
+type ContractCaller struct {
+	// Invoker makes the calls, e.g. a *Client or a *Pool.
+	Invoker Invoker
+	// Name is the name of the smart contract, and Version the version to call. An empty
+	// Version, or "*", calls the latest version.
+	Name    string
+	Version string
+	// Resolver, if set, resolves the version to call, which may then be a constraint
+	// (see SmartContractSpec), e.g. "^2".
+	Resolver *SmartContractResolver
+	// Codec, if set, encodes arguments and decodes results instead of the legacy
+	// encoding of CallSmartContract and CallSmartContractJSON.
+	Codec Codec
+	// Errors maps substrings of the error messages of the smart contract to the errors
+	// returned in their place, wrapped in a ContractError. The longest matching
+	// substring wins.
+	Errors map[string]error
+}
+
+// ContractError is returned by ContractCaller when the smart contract fails. Err is the
+// error mapped from Message by ContractCaller.Errors, or nil.
+type ContractError struct {
+	Contract string
+	Action   string
+	Message  string
+	Err      error
+}
+
+func (e *ContractError) Error() string {
+	return fmt.Sprintf("CLIENT: %s.%s: %s", e.Contract, e.Action, e.Message)
+}
+
+func (e *ContractError) Unwrap() error {
+	return e.Err
+}
+
+// Spec returns the exact spec of the smart contract version called by caller, resolved
+// by its Resolver if set.
+func (caller ContractCaller) Spec() (SmartContractSpec, error) {
+	version := caller.Version
+	if version == "" {
+		version = "*"
+	}
+	spec, err := ParseSmartContractSpec(caller.Name + "@" + version)
+	if err != nil {
+		return SmartContractSpec{}, err
+	}
+	if caller.Resolver != nil {
+		return caller.Resolver.ResolveSpec(spec)
+	}
+	if !spec.IsExact() && spec.Constraint != "*" {
+		return SmartContractSpec{}, fmt.Errorf("CLIENT: %s: version constraint %q needs a Resolver", caller.Name, version)
+	}
+	return spec, nil
+}
+
+// Call invokes action with args and decodes the result into result, with caller's Codec
+// if set. Otherwise, strings and byte slices receive the raw result, and other values
+// are JSON-decoded. A nil result discards the result.
+//
+// Call returns ctx.Err() if ctx is done before the invocation completes. Invocations
+// made through a *Client or a *Pool are then cancelled, although ParallelCore may still
+// carry them out; those made through other Invokers keep running in the background.
+func (caller ContractCaller) Call(ctx context.Context, action string, args interface{}, result interface{}) error {
+	var task []byte
+	var err error
+	if caller.Codec != nil {
+		task, err = encodeTask(caller.Codec, action, args)
+	} else {
+		task, err = smartContractTask(action, args)
+	}
+	if err != nil {
+		return fmt.Errorf("CLIENT: %s.%s: %w", caller.Name, action, err)
+	}
+	if err = ctx.Err(); err != nil {
+		return err
+	}
+	spec, err := caller.Spec()
+	if err != nil {
+		return err
+	}
+
+	raw, err := invokeContext(ctx, caller.Invoker, spec.String(), task)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return caller.mapError(action, err)
+	}
+
+	if caller.Codec != nil && result != nil {
+		err = caller.Codec.Unmarshal(raw, result)
+	} else {
+		err = decodeResult(raw, result)
+	}
+	if err != nil {
+		return fmt.Errorf("CLIENT: %s.%s: decoding result: %w", caller.Name, action, err)
+	}
+	return nil
+}
+
+func decodeResult(raw []byte, result interface{}) error {
+	switch value := result.(type) {
+	case nil:
+	case *string:
+		*value = string(raw)
+	case *[]byte:
+		*value = raw
+	default:
+		return json.Unmarshal(raw, result)
+	}
+	return nil
+}
+
+// mapError wraps the errors reported by ParallelCore in its response, such as those of
+// the smart contract, in a ContractError. Other errors, from gRPC or from the SDK itself
+// (e.g. ErrPoolClosed), are returned unchanged.
+func (caller ContractCaller) mapError(action string, err error) error {
+	var reportedErr *reportedError
+	if !errors.As(err, &reportedErr) {
+		return err
+	}
+	contractErr := &ContractError{Contract: caller.Name, Action: action, Message: err.Error()}
+	longest := -1
+	for substring, mapped := range caller.Errors {
+		if len(substring) > longest && strings.Contains(contractErr.Message, substring) {
+			contractErr.Err, longest = mapped, len(substring)
+		}
+	}
+	return contractErr
+}
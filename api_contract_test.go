@@ -0,0 +1,62 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestContractCallerCancels(t *testing.T) {
+	_, server := newCounterServer(t)
+	injector := sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"Invoke"}, Latency: 300 * time.Millisecond})
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	caller := sdk.ContractCaller{Invoker: client, Name: "counter", Version: "1"}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err = caller.Call(ctx, "set", "alice", nil); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Call = %v, want context.DeadlineExceeded", err)
+	}
+
+	// The invocation was cancelled along with the call, before reaching the server.
+	time.Sleep(400 * time.Millisecond)
+	if _, ok := server.Value("counter", "alice"); ok {
+		t.Error("the cancelled invocation was carried out")
+	}
+
+	var value string
+	if err = caller.Call(context.Background(), "get", "alice", &value); err != nil || value != "" {
+		t.Errorf("Call after cancellation = %q, %v", value, err)
+	}
+}
+
+func TestContractCallerPassesLocalErrorsThrough(t *testing.T) {
+	_, server := newCounterServer(t)
+	pool, err := sdk.OpenPool(sdk.StaticResolver(server.EndpointSpecs()), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), server.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caller := sdk.ContractCaller{Invoker: pool, Name: "counter", Version: "1"}
+	var contractErr *sdk.ContractError
+	if err = caller.Call(context.Background(), "bogus", "alice", nil); !errors.As(err, &contractErr) {
+		t.Errorf("Call of an unknown action = %v, want a ContractError", err)
+	}
+
+	pool.Close()
+	if err = caller.Call(context.Background(), "get", "alice", nil); !errors.Is(err, sdk.ErrPoolClosed) || errors.As(err, &contractErr) {
+		t.Errorf("Call over a closed Pool = %v, want ErrPoolClosed", err)
+	}
+}
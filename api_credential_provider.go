@@ -0,0 +1,193 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+)
+
+// CredentialProvider supplies the client ID and credential (password) used to
+// authenticate against ParallelCore. The SDK asks for them every time it needs to
+// authenticate, so credentials can be rotated without restarting the application.
+type CredentialProvider interface {
+	Credentials(ctx context.Context) (clientID string, credential string, err error)
+}
+
+// StaticCredentials is a CredentialProvider that always returns the same credentials.
+type StaticCredentials struct {
+	ClientID   string
+	Credential string
+}
+
+// Credentials implements CredentialProvider.
+func (c StaticCredentials) Credentials(ctx context.Context) (string, string, error) {
+	return c.ClientID, c.Credential, nil
+}
+
+// CredentialsFunc adapts an ordinary function into a CredentialProvider, for example
+// to fetch credentials from a secrets manager.
+type CredentialsFunc func(ctx context.Context) (clientID string, credential string, err error)
+
+// Credentials implements CredentialProvider.
+func (f CredentialsFunc) Credentials(ctx context.Context) (string, string, error) {
+	return f(ctx)
+}
+
+// EnvCredentials is a CredentialProvider reading the client ID and credential from
+// environment variables every time it is asked.
+type EnvCredentials struct {
+	// ClientIDVar defaults to PCORE_CLIENT_ID.
+	ClientIDVar string
+	// CredentialVar defaults to PCORE_CREDENTIAL.
+	CredentialVar string
+}
+
+// Credentials implements CredentialProvider.
+func (c EnvCredentials) Credentials(ctx context.Context) (string, string, error) {
+	clientIDVar, credentialVar := c.ClientIDVar, c.CredentialVar
+	if clientIDVar == "" {
+		clientIDVar = "PCORE_CLIENT_ID"
+	}
+	if credentialVar == "" {
+		credentialVar = "PCORE_CREDENTIAL"
+	}
+	clientID, ok := os.LookupEnv(clientIDVar)
+	if !ok {
+		return "", "", fmt.Errorf("CLIENT: EnvCredentials: %s is not set", clientIDVar)
+	}
+	return clientID, os.Getenv(credentialVar), nil
+}
+
+// FileCredentials is a CredentialProvider reading credentials from a JSON file with
+// the fields:
+//  - clientId string
+//  - clientCredential string
+//
+// The file is re-read whenever its modification time or size changes, so credentials
+// can be rotated by rewriting the file.
+type FileCredentials struct {
+	path string
+
+	mu      sync.Mutex
+	modTime time.Time
+	size    int64
+	data    *UserData
+}
+
+// NewFileCredentials returns a FileCredentials reading from path.
+func NewFileCredentials(path string) *FileCredentials {
+	return &FileCredentials{path: path}
+}
+
+// Credentials implements CredentialProvider.
+func (c *FileCredentials) Credentials(ctx context.Context) (string, string, error) {
+	info, err := os.Stat(c.path)
+	if err != nil {
+		return "", "", fmt.Errorf("CLIENT: FileCredentials(%q): %w", c.path, err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.data == nil || !info.ModTime().Equal(c.modTime) || info.Size() != c.size {
+		content, err := ioutil.ReadFile(c.path)
+		if err != nil {
+			return "", "", fmt.Errorf("CLIENT: FileCredentials(%q): %w", c.path, err)
+		}
+		var data UserData
+		if err = json.Unmarshal(content, &data); err != nil {
+			return "", "", fmt.Errorf("CLIENT: FileCredentials(%q): %w", c.path, err)
+		}
+		c.modTime, c.size, c.data = info.ModTime(), info.Size(), &data
+	}
+	return c.data.ID, c.data.Credential, nil
+}
+
+// OpenAnyWithProvider is similar to OpenAny, but takes the client ID and credential
+// from provider. The returned Client asks provider again whenever it needs to
+// authenticate, in particular when Renew finds that the token can no longer be renewed.
+func OpenAnyWithProvider(endpointSpecs string, provider CredentialProvider, certPath string, opts ...Option) (*Client, error) {
+	clientID, credential, err := provider.Credentials(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: OpenAnyWithProvider(%q): %w", endpointSpecs, err)
+	}
+	return OpenAny(endpointSpecs, clientID, credential, certPath, append(opts, withCredentialProvider(provider))...)
+}
+
+// OpenManyWithProvider is similar to OpenMany, but takes the client ID and credential
+// from provider (see OpenAnyWithProvider).
+func OpenManyWithProvider(endpointSpecs string, provider CredentialProvider, certPath string, opts ...Option) ([]*Client, error) {
+	clientID, credential, err := provider.Credentials(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: OpenManyWithProvider(%q): %w", endpointSpecs, err)
+	}
+	return OpenMany(endpointSpecs, clientID, credential, certPath, append(opts, withCredentialProvider(provider))...)
+}
+
+func withCredentialProvider(provider CredentialProvider) Option {
+	return func(cfg *openConfig) {
+		cfg.credentialProvider = provider
+	}
+}
+
+// Reauthenticate authenticates again with the credentials of the CredentialProvider
+// the client was opened with, and reconnects with the resulting token. Unlike Renew,
+// it also works after the client's token has expired.
+func (client *Client) Reauthenticate() error {
+	token, expireTimestamp, err := client.reauthToken()
+	if err != nil {
+		return err
+	}
+	return client.reconnect(token, expireTimestamp)
+}
+
+// reauthToken fetches a new token from one of the client's endpoints, using the
+// credentials of the client's CredentialProvider.
+func (client *Client) reauthToken() (token string, expireTimestamp int64, err error) {
+	if client.cfg == nil || client.cfg.credentialProvider == nil {
+		return "", 0, fmt.Errorf("CLIENT: reauthToken: Client was not opened with a CredentialProvider")
+	}
+	clientID, credential, err := client.cfg.credentialProvider.Credentials(context.Background())
+	if err != nil {
+		return "", 0, fmt.Errorf("CLIENT: reauthToken: %w", err)
+	}
+
+	endpoints := splitEndpointSpecs(client.endpointSpecs)
+	if len(endpoints) == 0 {
+		return "", 0, fmt.Errorf("CLIENT: reauthToken: Client has no endpoint")
+	}
+	randGen := rand.New(rand.NewSource(time.Now().UnixNano()))
+	var lastError error
+	for len(endpoints) != 0 {
+		i := randGen.Intn(len(endpoints))
+		endpoint := endpoints[i]
+		endpoints = append(endpoints[:i], endpoints[i+1:]...)
+
+		// Authenticate on a connection without the old token, which may have expired.
+		authClient, err := openOne(endpoint, client.certPath, "", client.cfg)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		returnBytes, err := authClient.auth([]byte(clientID), []byte(credential))
+		authClient.Close()
+		if err != nil {
+			return "", 0, fmt.Errorf("CLIENT: reauthToken: Failed to auth. %w", err)
+		}
+		token, expireTimestamp, err = parseTokenAndExpireTimestamp(string(returnBytes))
+		if err != nil {
+			return "", 0, fmt.Errorf("CLIENT: reauthToken: Failed to parse Token. %w", err)
+		}
+		return token, expireTimestamp, nil
+	}
+	return "", 0, fmt.Errorf("CLIENT: reauthToken: Failed to open any client. Last error: %w", lastError)
+}
@@ -0,0 +1,192 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func credentialsOf(t *testing.T, provider sdk.CredentialProvider) (string, string) {
+	t.Helper()
+	clientID, credential, err := provider.Credentials(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return clientID, credential
+}
+
+func TestStaticAndFuncCredentials(t *testing.T) {
+	if clientID, credential := credentialsOf(t, sdk.StaticCredentials{ClientID: "alice", Credential: "secret"}); clientID != "alice" || credential != "secret" {
+		t.Errorf("StaticCredentials = %q, %q", clientID, credential)
+	}
+
+	calls := 0
+	provider := sdk.CredentialsFunc(func(ctx context.Context) (string, string, error) {
+		calls++
+		if calls > 1 {
+			return "", "", errors.New("vault sealed")
+		}
+		return "bob", "hunter2", nil
+	})
+	if clientID, credential := credentialsOf(t, provider); clientID != "bob" || credential != "hunter2" {
+		t.Errorf("CredentialsFunc = %q, %q", clientID, credential)
+	}
+	if _, _, err := provider.Credentials(context.Background()); err == nil || calls != 2 {
+		t.Errorf("CredentialsFunc = %v after %d calls", err, calls)
+	}
+}
+
+// setenv sets the environment variable key to value, or unsets it if value is nil,
+// until the test ends.
+func setenv(t *testing.T, key string, value *string) {
+	previous, ok := os.LookupEnv(key)
+	if value == nil {
+		os.Unsetenv(key)
+	} else {
+		os.Setenv(key, *value)
+	}
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestEnvCredentials(t *testing.T) {
+	alice, secret, carol := "alice", "secret", "carol"
+	setenv(t, "PCORE_CLIENT_ID", &alice)
+	setenv(t, "PCORE_CREDENTIAL", &secret)
+	setenv(t, "APP_USER", &carol)
+	setenv(t, "APP_PASSWORD", nil)
+
+	if clientID, credential := credentialsOf(t, sdk.EnvCredentials{}); clientID != "alice" || credential != "secret" {
+		t.Errorf("EnvCredentials with default variables = %q, %q", clientID, credential)
+	}
+	// An unset credential variable stands for an empty credential.
+	custom := sdk.EnvCredentials{ClientIDVar: "APP_USER", CredentialVar: "APP_PASSWORD"}
+	if clientID, credential := credentialsOf(t, custom); clientID != "carol" || credential != "" {
+		t.Errorf("EnvCredentials with custom variables = %q, %q", clientID, credential)
+	}
+
+	// The environment is read again on every call.
+	bob := "bob"
+	setenv(t, "PCORE_CLIENT_ID", &bob)
+	if clientID, _ := credentialsOf(t, sdk.EnvCredentials{}); clientID != "bob" {
+		t.Errorf("EnvCredentials after change = %q", clientID)
+	}
+	setenv(t, "PCORE_CLIENT_ID", nil)
+	if _, _, err := (sdk.EnvCredentials{}).Credentials(context.Background()); err == nil {
+		t.Error("EnvCredentials succeeded without PCORE_CLIENT_ID")
+	}
+}
+
+func TestFileCredentials(t *testing.T) {
+	path := filepath.Join(tempDir(t), "credentials.json")
+	provider := sdk.NewFileCredentials(path)
+	if _, _, err := provider.Credentials(context.Background()); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("FileCredentials of a missing file = %v", err)
+	}
+
+	modTime := time.Now().Add(-time.Hour)
+	write := func(content string) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		modTime = modTime.Add(time.Minute)
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"clientId": "alice", "clientCredential": "secret-1"}`)
+	if clientID, credential := credentialsOf(t, provider); clientID != "alice" || credential != "secret-1" {
+		t.Errorf("FileCredentials = %q, %q", clientID, credential)
+	}
+
+	// A rotated credential of the same length is picked up from the modification time.
+	write(`{"clientId": "alice", "clientCredential": "secret-2"}`)
+	if _, credential := credentialsOf(t, provider); credential != "secret-2" {
+		t.Errorf("FileCredentials after rotation = %q", credential)
+	}
+
+	write(`{"clientId": "alice",`)
+	if _, _, err := provider.Credentials(context.Background()); err == nil {
+		t.Error("FileCredentials accepted malformed JSON")
+	}
+}
+
+func TestRenewReauthenticates(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+	var calls int32
+	provider := sdk.CredentialsFunc(func(ctx context.Context) (string, string, error) {
+		atomic.AddInt32(&calls, 1)
+		return pcoretest.RootID, pcoretest.RootPassword, nil
+	})
+	client, err := sdk.OpenAnyWithProvider(server.EndpointSpecs(), provider, server.CertPath(), server.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	// A token that can still be renewed is, without asking for credentials.
+	if err = client.Renew(); err != nil || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("Renew = %v, with %d calls to the provider", err, calls)
+	}
+
+	// A revoked token is replaced by authenticating again.
+	revoked := client.GetToken()
+	server.RevokeToken(revoked)
+	if err = client.Renew(); err != nil || atomic.LoadInt32(&calls) != 2 || client.GetToken() == revoked {
+		t.Fatalf("Renew of a revoked token = %v, with %d calls to the provider", err, calls)
+	}
+	if _, err = client.ListInvokableSC(); err != nil {
+		t.Fatalf("after Renew: %v", err)
+	}
+
+	// So is an expired one.
+	server.SetTokenTTL(time.Second)
+	if err = client.Reauthenticate(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(1100 * time.Millisecond)
+	if err = client.Renew(); err != nil || atomic.LoadInt32(&calls) != 4 {
+		t.Fatalf("Renew of an expired token = %v, with %d calls to the provider", err, calls)
+	}
+}
+
+func TestRenewKeepsCredentialsOnTransportErrors(t *testing.T) {
+	server, err := pcoretest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var calls int32
+	provider := sdk.CredentialsFunc(func(ctx context.Context) (string, string, error) {
+		atomic.AddInt32(&calls, 1)
+		return pcoretest.RootID, pcoretest.RootPassword, nil
+	})
+	client, err := sdk.OpenAnyWithProvider(server.EndpointSpecs(), provider, server.CertPath(), server.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	server.Close()
+	if err = client.Renew(); err == nil || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("Renew with the server down = %v, with %d calls to the provider", err, calls)
+	}
+}
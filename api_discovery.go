@@ -0,0 +1,153 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SummaryResolver discovers the endpoints of a ParallelChain network from the
+// network_address every chain reports in GetBlockchainSummaryJson, starting from a
+// single seed endpoint.
+//
+// It keeps one authenticated Client open between calls to Resolve. If that Client
+// fails, the next Resolve reconnects through the seed or any endpoint discovered so far,
+// so the seed itself may be retired once the network has been discovered.
+type SummaryResolver struct {
+	seed       string
+	clientID   string
+	credential string
+	certPath   string
+	opts       []Option
+
+	mu     sync.Mutex
+	known  []string
+	client *Client
+}
+
+// NewSummaryResolver returns a SummaryResolver that bootstraps from seedEndpoint, using
+// the given credentials and options to connect.
+func NewSummaryResolver(seedEndpoint string, clientID string, credential string, certPath string, opts ...Option) *SummaryResolver {
+	return &SummaryResolver{
+		seed:       seedEndpoint,
+		clientID:   clientID,
+		credential: credential,
+		certPath:   certPath,
+		opts:       opts,
+		known:      []string{seedEndpoint},
+	}
+}
+
+// Resolve returns the network addresses of all chains in the blockchain summary. A
+// network address without a port is given the port of the seed endpoint.
+func (r *SummaryResolver) Resolve(ctx context.Context) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.connect(); err != nil {
+		return nil, err
+	}
+	summary, err := r.client.GetBlockchainSummary()
+	if err != nil {
+		// Drop the connection; the next Resolve will try the other known endpoints.
+		r.client.Close()
+		r.client = nil
+		return nil, fmt.Errorf("CLIENT: SummaryResolver(%q): %w", r.seed, err)
+	}
+
+	endpoints := summaryEndpoints(summary, r.seed)
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("CLIENT: SummaryResolver(%q): Blockchain summary reported no network address", r.seed)
+	}
+	r.known = endpoints
+	return append([]string(nil), endpoints...), nil
+}
+
+// summaryEndpoints returns the distinct network addresses of the chains in summary,
+// giving the port of seed to those without one.
+func summaryEndpoints(summary BlockchainSummary, seed string) []string {
+	_, seedPort, _ := net.SplitHostPort(seed)
+	endpoints := make([]string, 0, len(summary.Chains))
+	seen := make(map[string]bool)
+	for _, chain := range summary.Chains {
+		endpoint := strings.TrimSpace(chain.NetworkAddress)
+		if endpoint == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(endpoint); err != nil && seedPort != "" {
+			endpoint = net.JoinHostPort(endpoint, seedPort)
+		}
+		if !seen[endpoint] {
+			seen[endpoint] = true
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// connect makes sure r.client is usable, renewing its token when it is about to expire.
+func (r *SummaryResolver) connect() error {
+	if r.client != nil {
+		if time.Until(time.Unix(r.client.GetTokenExpTime(), 0)) > time.Minute {
+			return nil
+		}
+		if err := r.client.Renew(); err == nil {
+			return nil
+		}
+		r.client.Close()
+		r.client = nil
+	}
+
+	endpointSpecs := strings.Join(r.known, " ")
+	if !containsString(r.known, r.seed) {
+		endpointSpecs = r.seed + " " + endpointSpecs
+	}
+	client, err := OpenAny(endpointSpecs, r.clientID, r.credential, r.certPath, r.opts...)
+	if err != nil {
+		return err
+	}
+	r.client = client
+	return nil
+}
+
+// Close closes the Client r uses to read the blockchain summary.
+func (r *SummaryResolver) Close() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil {
+		r.client.Close()
+		r.client = nil
+	}
+}
+
+// OpenPoolFromSeed is similar to OpenPool, but discovers the endpoints of the network
+// from seedEndpoint using a SummaryResolver, so applications only need to be configured
+// with one address. The pool keeps re-reading the blockchain summary on every refresh.
+func OpenPoolFromSeed(seedEndpoint string, clientID string, credential string, certPath string, opts ...Option) (*Pool, error) {
+	resolver := NewSummaryResolver(seedEndpoint, clientID, credential, certPath, opts...)
+	pool, err := OpenPool(resolver, clientID, credential, certPath, opts...)
+	if err != nil {
+		resolver.Close()
+		return nil, err
+	}
+	pool.onClose = resolver.Close
+	return pool, nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, each := range list {
+		if each == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,71 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestOpenPoolFromSeed(t *testing.T) {
+	const node0, node1, node2 = "node0.pcoretest.local:5000", "node1.pcoretest.local:5000", "node2.pcoretest.local:5000"
+	_, server := newCounterServer(t)
+	server.SetEndpoints(node0, node1, node2)
+
+	pool, err := sdk.OpenPoolFromSeed(node0, pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), server.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if endpoints := pool.Endpoints(); !reflect.DeepEqual(endpoints, []string{node0, node1, node2}) {
+		t.Fatalf("Endpoints() = %q", endpoints)
+	}
+	if clients := pool.Clients(); len(clients) != 3 {
+		t.Fatalf("pool has %d clients, want 3", len(clients))
+	}
+
+	// Retiring the seed leaves the pool connected to the rest of the network.
+	server.SetEndpoints(node1, node2)
+	if err = pool.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if endpoints := pool.Endpoints(); !reflect.DeepEqual(endpoints, []string{node1, node2}) {
+		t.Fatalf("Endpoints() after retiring the seed = %q", endpoints)
+	}
+	if _, err = pool.Invoke("counter-v1", []byte(`{"action":"set","data":"a"}`)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSummaryResolver(t *testing.T) {
+	const node0, node1 = "node0.pcoretest.local:5000", "node1.pcoretest.local:5000"
+	_, server := newCounterServer(t)
+	server.SetEndpoints(node0, node1)
+
+	// Retired nodes refuse connections, which OpenAny would otherwise retry forever.
+	opts := append(server.Options(), sdk.WithDialTimeout(time.Second))
+	resolver := sdk.NewSummaryResolver(node0, pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), opts...)
+	defer resolver.Close()
+	if endpoints, err := resolver.Resolve(context.Background()); err != nil || !reflect.DeepEqual(endpoints, []string{node0, node1}) {
+		t.Fatalf("Resolve() = %q, %v", endpoints, err)
+	}
+
+	// Once the network is known, the resolver reconnects without the seed.
+	resolver.Close()
+	server.SetEndpoints(node1)
+	if endpoints, err := resolver.Resolve(context.Background()); err != nil || !reflect.DeepEqual(endpoints, []string{node1}) {
+		t.Fatalf("Resolve() without the seed = %q, %v", endpoints, err)
+	}
+
+	if _, err := sdk.NewSummaryResolver(node1, pcoretest.RootID, "wrong", server.CertPath(), opts...).Resolve(context.Background()); err == nil {
+		t.Error("Resolve() with a wrong credential succeeded")
+	}
+}
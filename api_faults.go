@@ -0,0 +1,283 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"path"
+	"sync"
+	"time"
+
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// FaultRule describes a failure to inject into calls, for resilience testing (see
+// FaultInjector).
+type FaultRule struct {
+	// Methods are the names of the RequestHandler methods the rule applies to, e.g.
+	// "Invoke" or "RegisterEventListener". The rule applies to every method if empty.
+	Methods []string
+	// Probability is the chance that the rule fires for a matching call, between 0
+	// and 1. Zero is treated as 1: the rule fires for every matching call.
+	Probability float64
+	// Times limits how many times the rule fires. Zero means no limit.
+	Times int
+
+	// Latency delays the call.
+	Latency time.Duration
+	// Code, unless codes.OK, fails the call with a gRPC error with this code and
+	// Message. See DropConnection to actually drop the connection.
+	Code    codes.Code
+	Message string
+	// ServerError, if set, makes the call return a response carrying this error, the
+	// way ParallelCore reports failed requests.
+	ServerError string
+	// AfterCall lets the call reach the server before failing it with Code or
+	// ServerError, as when a connection drops before the response arrives: the
+	// request may have been carried out.
+	AfterCall bool
+	// TerminateStreamAfter, for streaming calls, lets that many messages through before
+	// the stream fails with Code (codes.Unavailable if unset) or ServerError.
+	TerminateStreamAfter int
+	// DropConnection closes the connections to the endpoint the call is made to, as
+	// when the network fails: the call fails with codes.Unavailable, and so do the
+	// other calls and streams in flight on those connections. gRPC connects again for
+	// later calls. With AfterCall or TerminateStreamAfter, the connections are closed
+	// once the call has reached the server, or once the stream has let its messages
+	// through.
+	DropConnection bool
+}
+
+// FaultInjector injects the failures described by its rules into the calls made by the
+// Clients opened with WithFaultInjector. For every call, the first matching rule that
+// fires (according to its Probability and Times) applies; calls no rule fires for are
+// left alone.
+//
+// Rules fire according to a pseudo-random sequence determined by the injector's seed,
+// so that a sequence of calls sees the same failures on every run.
+type FaultInjector struct {
+	mu    sync.Mutex
+	rules []FaultRule
+	fired []int
+	rand  *rand.Rand
+	conns map[*droppableConn]bool
+}
+
+// NewFaultInjector returns a FaultInjector applying rules, seeded with seed.
+func NewFaultInjector(seed int64, rules ...FaultRule) *FaultInjector {
+	return &FaultInjector{
+		rules: rules,
+		fired: make([]int, len(rules)),
+		rand:  rand.New(rand.NewSource(seed)),
+		conns: make(map[*droppableConn]bool),
+	}
+}
+
+// Fired returns how many times each rule has fired so far, in the order of the rules.
+func (injector *FaultInjector) Fired() []int {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+	return append([]int(nil), injector.fired...)
+}
+
+// WithFaultInjector makes connections inject the failures described by injector.
+func WithFaultInjector(injector *FaultInjector) Option {
+	return func(cfg *openConfig) {
+		cfg.unaryInterceptors = append(cfg.unaryInterceptors, injector.interceptUnary)
+		cfg.streamInterceptors = append(cfg.streamInterceptors, injector.interceptStream)
+		cfg.connHooks = append(cfg.connHooks, injector.track)
+	}
+}
+
+// droppableConn is a connection a FaultInjector can drop.
+type droppableConn struct {
+	net.Conn
+	injector *FaultInjector
+	endpoint string
+}
+
+func (conn *droppableConn) Close() error {
+	conn.injector.mu.Lock()
+	delete(conn.injector.conns, conn)
+	conn.injector.mu.Unlock()
+	return conn.Conn.Close()
+}
+
+// track registers conn, to endpoint, as a connection the injector can drop.
+func (injector *FaultInjector) track(endpoint string, conn net.Conn) net.Conn {
+	tracked := &droppableConn{Conn: conn, injector: injector, endpoint: endpoint}
+	injector.mu.Lock()
+	injector.conns[tracked] = true
+	injector.mu.Unlock()
+	return tracked
+}
+
+// drop closes the connections to endpoint.
+func (injector *FaultInjector) drop(endpoint string) {
+	injector.mu.Lock()
+	var dropped []*droppableConn
+	for conn := range injector.conns {
+		if conn.endpoint == endpoint {
+			dropped = append(dropped, conn)
+		}
+	}
+	injector.mu.Unlock()
+
+	for _, conn := range dropped {
+		conn.Close()
+	}
+}
+
+// fire returns the rule to apply to a call of method, or nil.
+func (injector *FaultInjector) fire(method string) *FaultRule {
+	injector.mu.Lock()
+	defer injector.mu.Unlock()
+
+	for i := range injector.rules {
+		rule := &injector.rules[i]
+		if !rule.matches(method) || (rule.Times > 0 && injector.fired[i] >= rule.Times) {
+			continue
+		}
+		if rule.Probability > 0 && injector.rand.Float64() >= rule.Probability {
+			continue
+		}
+		injector.fired[i]++
+		return rule
+	}
+	return nil
+}
+
+func (rule *FaultRule) matches(method string) bool {
+	if len(rule.Methods) == 0 {
+		return true
+	}
+	for _, each := range rule.Methods {
+		if each == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (rule *FaultRule) failing() bool {
+	return rule.Code != codes.OK || rule.ServerError != "" || rule.DropConnection
+}
+
+func (rule *FaultRule) delay(ctx context.Context) error {
+	if rule.Latency <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(rule.Latency)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return status.FromContextError(ctx.Err()).Err()
+	}
+}
+
+func (rule *FaultRule) statusError(defaultCode codes.Code) error {
+	code, message := rule.Code, rule.Message
+	if code == codes.OK {
+		code = defaultCode
+	}
+	if message == "" {
+		message = "CLIENT: injected fault"
+	}
+	return status.Error(code, message)
+}
+
+func (injector *FaultInjector) interceptUnary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	rule := injector.fire(path.Base(method))
+	if rule == nil {
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+	if err := rule.delay(ctx); err != nil {
+		return err
+	}
+	if !rule.failing() || rule.AfterCall {
+		if err := invoker(ctx, method, req, reply, cc, opts...); err != nil || !rule.failing() {
+			return err
+		}
+	}
+
+	if rule.DropConnection {
+		injector.drop(cc.Target())
+		return rule.statusError(codes.Unavailable)
+	}
+	if rule.Code != codes.OK {
+		return rule.statusError(rule.Code)
+	}
+	switch response := reply.(type) {
+	case *pb.Response:
+		response.Payload, response.Error = nil, []byte(rule.ServerError)
+	case *pb.IdentifiedResponse:
+		response.Payload, response.Error, response.CommittedId = nil, []byte(rule.ServerError), nil
+	}
+	return nil
+}
+
+func (injector *FaultInjector) interceptStream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	rule := injector.fire(path.Base(method))
+	if rule == nil {
+		return streamer(ctx, desc, cc, method, opts...)
+	}
+	if err := rule.delay(ctx); err != nil {
+		return nil, err
+	}
+	if rule.DropConnection && rule.TerminateStreamAfter == 0 && !rule.AfterCall {
+		injector.drop(cc.Target())
+		return nil, rule.statusError(codes.Unavailable)
+	}
+	if rule.Code != codes.OK && rule.TerminateStreamAfter == 0 && !rule.AfterCall {
+		return nil, rule.statusError(rule.Code)
+	}
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil || !rule.failing() && rule.TerminateStreamAfter == 0 {
+		return stream, err
+	}
+	return &faultyStream{ClientStream: stream, rule: rule, drop: func() { injector.drop(cc.Target()) }}, nil
+}
+
+// faultyStream lets rule.TerminateStreamAfter messages through, then fails.
+type faultyStream struct {
+	grpc.ClientStream
+	rule     *FaultRule
+	drop     func()
+	received int
+}
+
+func (stream *faultyStream) RecvMsg(m interface{}) error {
+	if stream.received < stream.rule.TerminateStreamAfter {
+		err := stream.ClientStream.RecvMsg(m)
+		if err == nil {
+			stream.received++
+		}
+		return err
+	}
+
+	if stream.rule.DropConnection {
+		// Let the stream fail the way gRPC reports the connection closing under it.
+		stream.drop()
+		if err := stream.ClientStream.RecvMsg(m); err != nil {
+			return err
+		}
+		return stream.rule.statusError(codes.Unavailable)
+	}
+
+	if response, ok := m.(*pb.Response); ok && stream.rule.ServerError != "" && stream.rule.Code == codes.OK {
+		response.Payload, response.Error = nil, []byte(stream.rule.ServerError)
+		return nil
+	}
+	return stream.rule.statusError(codes.Unavailable)
+}
@@ -0,0 +1,153 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFaultInjection(t *testing.T) {
+	_, server := newCounterServer(t)
+
+	injector := sdk.NewFaultInjector(1,
+		sdk.FaultRule{Methods: []string{"Invoke"}, Times: 1, Code: codes.Unavailable},
+		sdk.FaultRule{Methods: []string{"IdentifiedInvoke"}, Times: 1, AfterCall: true, Code: codes.Unavailable},
+		sdk.FaultRule{Methods: []string{"ListClient"}, ServerError: "injected"},
+	)
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	_, err = client.Invoke("counter-v1", []byte(`{"action":"get","data":"alice"}`))
+	if s, _ := status.FromError(errors.Unwrap(err)); s.Code() != codes.Unavailable {
+		t.Errorf("Invoke error = %v", err)
+	}
+	if _, err = client.Invoke("counter-v1", []byte(`{"action":"get","data":"alice"}`)); err != nil {
+		t.Errorf("Invoke after the rule's last firing = %v", err)
+	}
+
+	if _, _, err = client.IdentifiedInvoke("counter-v1", []byte(`{"action":"set","data":"alice"}`)); err == nil {
+		t.Error("IdentifiedInvoke did not fail")
+	}
+	if _, ok := server.Value("counter", "alice"); !ok {
+		t.Error("AfterCall fault did not let the call reach the server")
+	}
+
+	if _, err = client.GetUserInfo(""); err == nil || err.Error() != "injected" {
+		t.Errorf("GetUserInfo error = %v", err)
+	}
+	if fired := injector.Fired(); fired[0] != 1 || fired[1] != 1 || fired[2] != 1 {
+		t.Errorf("Fired = %v", fired)
+	}
+}
+
+func TestFaultInjectionIsDeterministic(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+
+	run := func() string {
+		injector := sdk.NewFaultInjector(42, sdk.FaultRule{Methods: []string{"ListClient"}, Probability: 0.5, Code: codes.Internal})
+		client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer client.Close()
+
+		var outcomes strings.Builder
+		for i := 0; i < 20; i++ {
+			if _, err := client.GetUserInfo(""); err != nil {
+				outcomes.WriteByte('x')
+			} else {
+				outcomes.WriteByte('.')
+			}
+		}
+		return outcomes.String()
+	}
+	first, second := run(), run()
+	if first != second || !strings.Contains(first, "x") || !strings.Contains(first, ".") {
+		t.Errorf("outcomes %q and %q", first, second)
+	}
+}
+
+func TestFaultInjectionTerminatesStreams(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+
+	injector := sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"RegisterEventListener"}, TerminateStreamAfter: 2})
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, events, err := client.RegisterEventListener("counter", ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controller.Close()
+
+	server.EmitEvent("counter", "set", "alice")
+	for _, wantError := range []bool{false, true} {
+		select {
+		case e := <-events:
+			if (e.Error != nil) != wantError {
+				t.Errorf("event = %+v, %v", e.ScEvent, e.Error)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+		}
+	}
+}
+
+func TestFaultInjectionDropsConnections(t *testing.T) {
+	_, server := newCounterServer(t)
+
+	injector := sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"Invoke"}, Times: 1, DropConnection: true})
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	controller, events, err := client.RegisterEventListener("counter", ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controller.Close()
+
+	_, err = client.Invoke("counter-v1", []byte(`{"action":"get","data":"alice"}`))
+	if s, _ := status.FromError(errors.Unwrap(err)); s.Code() != codes.Unavailable {
+		t.Errorf("Invoke error = %v", err)
+	}
+
+	// The event stream shared the dropped connection.
+	select {
+	case e := <-events:
+		if e.Error == nil {
+			t.Errorf("event = %+v, want the stream to fail", e.ScEvent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the event stream survived the dropped connection")
+	}
+
+	// Later calls connect again.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err = client.Invoke("counter-v1", []byte(`{"action":"get","data":"alice"}`)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("Invoke after the dropped connection = %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
@@ -0,0 +1,177 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TransactionReceipt locates a transaction in a sealed block.
+type TransactionReceipt struct {
+	TxID        string
+	ChainID     string
+	BlockNumber int64
+	BlockHash   string
+	// Timestamp is the Unix time of the transaction.
+	Timestamp int64
+}
+
+// WithPollInterval sets how often WaitForTransaction and InvokeAndWait poll for the
+// status of a transaction. The default is DEFAULT_TRANSACTION_POLL_INTERVAL.
+func WithPollInterval(interval time.Duration) Option {
+	return func(cfg *openConfig) {
+		cfg.pollInterval = interval
+	}
+}
+
+// WithSealedBlockStatus sets the block status (see GetBlockDetailsJson) that
+// WaitForTransaction and InvokeAndWait take for a sealed block. The default is
+// BLOCK_STATUS_SEALED.
+func WithSealedBlockStatus(status int) Option {
+	return func(cfg *openConfig) {
+		cfg.sealedStatus = &status
+	}
+}
+
+func sealedStatus(cfg *openConfig) int {
+	if cfg == nil || cfg.sealedStatus == nil {
+		return BLOCK_STATUS_SEALED
+	}
+	return *cfg.sealedStatus
+}
+
+func pollInterval(cfg *openConfig) time.Duration {
+	if cfg == nil || cfg.pollInterval <= 0 {
+		return DEFAULT_TRANSACTION_POLL_INTERVAL
+	}
+	return cfg.pollInterval
+}
+
+// WaitForTransaction waits until the transaction txID (e.g. a commit ID returned by
+// IdentifiedInvoke) is in a sealed block, polling reader every interval
+// (DEFAULT_TRANSACTION_POLL_INTERVAL if not positive), and returns its receipt.
+// Transactions not yet known to ParallelCore are waited for too. Blocks are sealed
+// once their status is BLOCK_STATUS_SEALED.
+//
+// It returns ctx.Err() if ctx is done first.
+func WaitForTransaction(ctx context.Context, reader BlockchainReader, txID string, interval time.Duration) (TransactionReceipt, error) {
+	return waitForTransaction(ctx, reader, txID, interval, BLOCK_STATUS_SEALED)
+}
+
+func waitForTransaction(ctx context.Context, reader BlockchainReader, txID string, interval time.Duration, sealed int) (TransactionReceipt, error) {
+	if interval <= 0 {
+		interval = DEFAULT_TRANSACTION_POLL_INTERVAL
+	}
+	var metadata struct {
+		ChainId     string `json:"chain_id"`
+		BlockNumber int64  `json:"block_number"`
+		Timestamp   int64  `json:"timestamp"`
+	}
+	located := false
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if !located {
+			raw, err := reader.GetSmartContractTransactionMetadataJson(txID)
+			switch {
+			case err == nil:
+				if err = json.Unmarshal(raw, &metadata); err != nil {
+					return TransactionReceipt{}, fmt.Errorf(E_FUNC_X_OUTPUT_DECODE_ERROR_X, API_GET_SMARTCONTRACT_TRANSACTION_META_JSON, err)
+				}
+				located = true
+			case !transactionNotFound(err, txID):
+				return TransactionReceipt{}, err
+			}
+		}
+
+		if located {
+			raw, err := reader.GetBlockDetailsJson(metadata.ChainId, strconv.FormatInt(metadata.BlockNumber, 10))
+			if err != nil {
+				return TransactionReceipt{}, err
+			}
+			var block BlockSummary
+			if err = json.Unmarshal(raw, &block); err != nil {
+				return TransactionReceipt{}, fmt.Errorf(E_FUNC_X_OUTPUT_DECODE_ERROR_X, API_GET_BLOCK_DETAILS_JSON, err)
+			}
+			if block.Status == sealed {
+				return TransactionReceipt{
+					TxID:        txID,
+					ChainID:     metadata.ChainId,
+					BlockNumber: metadata.BlockNumber,
+					BlockHash:   block.Hash,
+					Timestamp:   metadata.Timestamp,
+				}, nil
+			}
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return TransactionReceipt{}, ctx.Err()
+		}
+	}
+}
+
+// transactionNotFound reports whether err is the error of ParallelCore for the
+// transaction txID it does not know (yet), FMT_TRANSACTION_X_DOES_NOT_EXIST. The
+// message is all ParallelCore returns to tell it from other errors.
+func transactionNotFound(err error, txID string) bool {
+	return strings.Contains(err.Error(), fmt.Sprintf(FMT_TRANSACTION_X_DOES_NOT_EXIST, txID))
+}
+
+// WaitForTransaction waits until the transaction txID is in a sealed block (see the
+// WaitForTransaction function), polling as often as set by WithPollInterval, and
+// with the sealed block status set by WithSealedBlockStatus.
+func (client *Client) WaitForTransaction(ctx context.Context, txID string) (TransactionReceipt, error) {
+	return waitForTransaction(ctx, client, txID, pollInterval(client.cfg), sealedStatus(client.cfg))
+}
+
+// InvokeAndWait invokes the smart contract identified by smartContractSpec (see
+// IdentifiedInvoke), then waits until its transaction is in a sealed block. It
+// returns the smart contract's payload and the transaction's receipt, which is nil
+// for read-only transactions.
+func (client *Client) InvokeAndWait(ctx context.Context, smartContractSpec string, args []byte) ([]byte, *TransactionReceipt, error) {
+	payload, commitID, err := client.identifiedInvoke(ctx, append([]byte(smartContractSpec+" "), args...))
+	if err != nil || commitID == "" {
+		return payload, nil, err
+	}
+	receipt, err := client.WaitForTransaction(ctx, commitID)
+	if err != nil {
+		return payload, nil, fmt.Errorf("CLIENT: InvokeAndWait: transaction %s: %w", commitID, err)
+	}
+	return payload, &receipt, nil
+}
+
+// WaitForTransaction waits until the transaction txID is in a sealed block, querying
+// the pool's Clients in turn (see the WaitForTransaction function).
+func (pool *Pool) WaitForTransaction(ctx context.Context, txID string) (TransactionReceipt, error) {
+	return waitForTransaction(ctx, pool, txID, pollInterval(pool.cfg), sealedStatus(pool.cfg))
+}
+
+// InvokeAndWait invokes a smart contract over one of the pool's Clients, then waits
+// until its transaction is in a sealed block (see Client.InvokeAndWait).
+func (pool *Pool) InvokeAndWait(ctx context.Context, smartContractSpec string, args []byte) ([]byte, *TransactionReceipt, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, nil, err
+	}
+	payload, commitID, err := client.identifiedInvoke(ctx, append([]byte(smartContractSpec+" "), args...))
+	release()
+	if err != nil || commitID == "" {
+		return payload, nil, err
+	}
+	receipt, err := pool.WaitForTransaction(ctx, commitID)
+	if err != nil {
+		return payload, nil, fmt.Errorf("CLIENT: InvokeAndWait: transaction %s: %w", commitID, err)
+	}
+	return payload, &receipt, nil
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestInvokeAndWait(t *testing.T) {
+	_, server := newCounterServer(t)
+	server.SetAutoSeal(false)
+
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithPollInterval(5*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	ctx := context.Background()
+
+	type outcome struct {
+		receipt *sdk.TransactionReceipt
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		_, receipt, err := client.InvokeAndWait(ctx, "counter-v1", []byte(`{"action":"set","data":"alice"}`))
+		done <- outcome{receipt, err}
+	}()
+
+	select {
+	case out := <-done:
+		t.Fatalf("InvokeAndWait returned before the block was sealed: %+v, %v", out.receipt, out.err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	server.Seal()
+
+	out := <-done
+	if out.err != nil || out.receipt == nil {
+		t.Fatalf("InvokeAndWait = %+v, %v", out.receipt, out.err)
+	}
+	hash, err := client.CalculateBlockHash(out.receipt.ChainID, fmt.Sprint(out.receipt.BlockNumber))
+	if err != nil || string(hash) != out.receipt.BlockHash || out.receipt.TxID == "" || out.receipt.Timestamp == 0 {
+		t.Errorf("receipt %+v, block hash %s, %v", out.receipt, hash, err)
+	}
+
+	if _, receipt, err := client.InvokeAndWait(ctx, "counter-v1", []byte(`{"action":"get","data":"alice"}`)); err != nil || receipt != nil {
+		t.Errorf("read-only InvokeAndWait = %+v, %v", receipt, err)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 30*time.Millisecond)
+	defer cancel()
+	if _, err = client.WaitForTransaction(short, "no-such-tx"); err != context.DeadlineExceeded {
+		t.Errorf("WaitForTransaction of an unknown transaction = %v", err)
+	}
+
+	// A zero interval stands for the default one.
+	if receipt, err := sdk.WaitForTransaction(ctx, client, out.receipt.TxID, 0); err != nil || receipt != *out.receipt {
+		t.Errorf("WaitForTransaction with a zero interval = %+v, %v", receipt, err)
+	}
+}
+
+func TestWithSealedBlockStatus(t *testing.T) {
+	_, server := newCounterServer(t)
+	server.SetAutoSeal(false)
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithPollInterval(5*time.Millisecond), sdk.WithSealedBlockStatus(sdk.BLOCK_STATUS_OPEN))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if _, receipt, err := client.InvokeAndWait(ctx, "counter-v1", []byte(`{"action":"set","data":"alice"}`)); err != nil || receipt == nil {
+		t.Errorf("InvokeAndWait of an open block taken as sealed = %+v, %v", receipt, err)
+	}
+}
+
+// brokenReader is a BlockchainReader failing to read transaction metadata.
+type brokenReader struct {
+	sdk.BlockchainReader
+	err error
+}
+
+func (r brokenReader) GetSmartContractTransactionMetadataJson(string) ([]byte, error) {
+	return nil, r.err
+}
+
+func TestWaitForTransactionErrors(t *testing.T) {
+	// Only the error for the transaction waited for is waited out.
+	broken := errors.New("Chain chain-0 does not exist")
+	if _, err := sdk.WaitForTransaction(context.Background(), brokenReader{err: broken}, "tx-1", time.Millisecond); err != broken {
+		t.Errorf("WaitForTransaction = %v, want %v", err, broken)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	unknown := fmt.Errorf(sdk.FMT_TRANSACTION_X_DOES_NOT_EXIST, "tx-1")
+	if _, err := sdk.WaitForTransaction(ctx, brokenReader{err: unknown}, "tx-1", time.Millisecond); err != context.DeadlineExceeded {
+		t.Errorf("WaitForTransaction of an unknown transaction = %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+)
+
+// Future is the pending result of an asynchronous invocation (see InvokeAsync and
+// IdentifiedInvokeAsync).
+type Future struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	payload  []byte
+	commitID string
+	err      error
+}
+
+// CallbackPanicError reports a panic in a callback passed to OnComplete.
+type CallbackPanicError struct {
+	Value interface{}
+	Stack []byte
+}
+
+func (e *CallbackPanicError) Error() string {
+	return fmt.Sprintf("CLIENT: Future callback panicked: %v", e.Value)
+}
+
+// startFuture runs call in its own goroutine, with a context derived from ctx that
+// Future.Cancel cancels.
+func startFuture(ctx context.Context, call func(ctx context.Context) ([]byte, string, error)) *Future {
+	ctx, cancel := context.WithCancel(ctx)
+	future := &Future{done: make(chan struct{}), cancel: cancel}
+	go func() {
+		defer cancel()
+		future.payload, future.commitID, future.err = call(ctx)
+		close(future.done)
+	}()
+	return future
+}
+
+// failedFuture returns a completed Future failed with err.
+func failedFuture(err error) *Future {
+	future := &Future{done: make(chan struct{}), cancel: func() {}, err: err}
+	close(future.done)
+	return future
+}
+
+// Done returns a channel closed when the invocation completes.
+func (future *Future) Done() <-chan struct{} {
+	return future.done
+}
+
+// Cancel cancels the invocation, if it has not completed. ParallelCore may still carry
+// it out; the Future then fails with a codes.Canceled error.
+func (future *Future) Cancel() {
+	future.cancel()
+}
+
+// Wait waits for the invocation to complete and returns its result: the payload, the
+// commit ID (empty for InvokeAsync, and for read-only transactions) and the error. If
+// ctx is done first, Wait returns ctx.Err() without cancelling the invocation.
+func (future *Future) Wait(ctx context.Context) ([]byte, string, error) {
+	select {
+	case <-future.done:
+		return future.payload, future.commitID, future.err
+	case <-ctx.Done():
+		return nil, "", ctx.Err()
+	}
+}
+
+// OnComplete calls callback with the result of the invocation once it completes, in its
+// own goroutine. The returned channel receives nil once callback returns, or a
+// *CallbackPanicError if it panicked, and is then closed.
+func (future *Future) OnComplete(callback func(payload []byte, commitID string, err error)) <-chan error {
+	finished := make(chan error, 1)
+	go func() {
+		defer close(finished)
+		<-future.done
+		finished <- runCallback(func() { callback(future.payload, future.commitID, future.err) })
+	}()
+	return finished
+}
+
+func runCallback(callback func()) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = &CallbackPanicError{Value: recovered, Stack: debug.Stack()}
+		}
+	}()
+	callback()
+	return nil
+}
+
+// InvokeAsync starts invoking the smart contract identified by smartContractSpec (see
+// Invoke) and returns its Future. Cancelling ctx cancels the invocation.
+func (client *Client) InvokeAsync(ctx context.Context, smartContractSpec string, args []byte) *Future {
+	in := append([]byte(smartContractSpec+" "), args...)
+	return startFuture(ctx, func(ctx context.Context) ([]byte, string, error) {
+		payload, err := client.invoke(ctx, in)
+		return payload, "", err
+	})
+}
+
+// IdentifiedInvokeAsync is similar to InvokeAsync, but the Future's result carries the
+// commit ID of the transaction (see IdentifiedInvoke).
+func (client *Client) IdentifiedInvokeAsync(ctx context.Context, smartContractSpec string, args []byte) *Future {
+	in := append([]byte(smartContractSpec+" "), args...)
+	return startFuture(ctx, func(ctx context.Context) ([]byte, string, error) {
+		return client.identifiedInvoke(ctx, in)
+	})
+}
+
+// IdentifiedInvokeWithCallback starts an IdentifiedInvokeAsync invocation, and calls
+// callback with its result. It returns the invocation's Future, and the channel
+// returned by Future.OnComplete, which receives a *CallbackPanicError if callback
+// panics.
+func (client *Client) IdentifiedInvokeWithCallback(ctx context.Context, smartContractSpec string, args []byte, callback func(payload []byte, commitID string, err error)) (*Future, <-chan error) {
+	future := client.IdentifiedInvokeAsync(ctx, smartContractSpec, args)
+	return future, future.OnComplete(callback)
+}
+
+// InvokeAsync starts an invocation over one of the pool's Clients (see
+// Client.InvokeAsync).
+func (pool *Pool) InvokeAsync(ctx context.Context, smartContractSpec string, args []byte) *Future {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return failedFuture(err)
+	}
+	in := append([]byte(smartContractSpec+" "), args...)
+	return startFuture(ctx, func(ctx context.Context) ([]byte, string, error) {
+		defer release()
+		payload, err := client.invoke(ctx, in)
+		return payload, "", err
+	})
+}
+
+// IdentifiedInvokeAsync starts an invocation over one of the pool's Clients (see
+// Client.IdentifiedInvokeAsync).
+func (pool *Pool) IdentifiedInvokeAsync(ctx context.Context, smartContractSpec string, args []byte) *Future {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return failedFuture(err)
+	}
+	in := append([]byte(smartContractSpec+" "), args...)
+	return startFuture(ctx, func(ctx context.Context) ([]byte, string, error) {
+		defer release()
+		return client.identifiedInvoke(ctx, in)
+	})
+}
+
+// IdentifiedInvokeWithCallback starts an invocation over one of the pool's Clients,
+// and calls callback with its result (see Client.IdentifiedInvokeWithCallback).
+func (pool *Pool) IdentifiedInvokeWithCallback(ctx context.Context, smartContractSpec string, args []byte, callback func(payload []byte, commitID string, err error)) (*Future, <-chan error) {
+	future := pool.IdentifiedInvokeAsync(ctx, smartContractSpec, args)
+	return future, future.OnComplete(callback)
+}
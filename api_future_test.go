@@ -0,0 +1,85 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestFutures(t *testing.T) {
+	root, server := newCounterServer(t)
+	release := make(chan struct{})
+	server.AddSmartContract("blocking", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("blocking-v1", func(*pcoretest.Context) ([]byte, error) {
+		<-release
+		return []byte("released"), nil
+	})
+	defer close(release)
+	ctx := context.Background()
+
+	future := root.IdentifiedInvokeAsync(ctx, "counter-v1", []byte(`{"action":"set","data":"alice"}`))
+	payload, commitID, err := future.Wait(ctx)
+	if err != nil || string(payload) != "ok" || commitID == "" {
+		t.Errorf("IdentifiedInvokeAsync = %q, %q, %v", payload, commitID, err)
+	}
+
+	results := make(chan string, 1)
+	future, panicked := root.IdentifiedInvokeWithCallback(ctx, "counter-v1", []byte(`{"action":"get","data":"alice"}`), func(payload []byte, _ string, err error) {
+		results <- string(payload)
+		panic("callback bug")
+	})
+	finished := future.OnComplete(func([]byte, string, error) {})
+	if <-finished != nil {
+		t.Error("a second callback was affected by the first's panic")
+	}
+	if result := <-results; result != "1" {
+		t.Errorf("callback got %q", result)
+	}
+	var panicErr *sdk.CallbackPanicError
+	if err := <-panicked; !errors.As(err, &panicErr) || panicErr.Value != "callback bug" {
+		t.Errorf("IdentifiedInvokeWithCallback reported %v", err)
+	}
+
+	// Pools call back the same way.
+	pool, err := sdk.OpenPool(sdk.StaticResolver(server.EndpointSpecs()), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), server.Options()...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	_, done := pool.IdentifiedInvokeWithCallback(ctx, "counter-v1", []byte(`{"action":"set","data":"bob"}`), func(payload []byte, commitID string, err error) {
+		if err != nil || string(payload) != "ok" || commitID == "" {
+			t.Errorf("Pool.IdentifiedInvokeWithCallback = %q, %q, %v", payload, commitID, err)
+		}
+	})
+	if err = <-done; err != nil {
+		t.Errorf("Pool.IdentifiedInvokeWithCallback reported %v", err)
+	}
+
+	if err := <-root.InvokeAsync(ctx, "counter-v1", nil).OnComplete(func([]byte, string, error) { panic("boom") }); !errors.As(err, &panicErr) || panicErr.Value != "boom" {
+		t.Errorf("OnComplete = %v", err)
+	}
+
+	blocked := root.InvokeAsync(ctx, "blocking-v1", nil)
+	short, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, _, err = blocked.Wait(short); err != context.DeadlineExceeded {
+		t.Errorf("Wait with a short context = %v", err)
+	}
+	blocked.Cancel()
+	<-blocked.Done()
+	if _, _, err = blocked.Wait(ctx); status.Code(errors.Unwrap(err)) != codes.Canceled {
+		t.Errorf("cancelled invocation error = %v", err)
+	}
+}
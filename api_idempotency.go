@@ -0,0 +1,354 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"google.golang.org/grpc/codes"
+)
+
+var (
+	ErrIdempotencyKeyNotFound = errors.New("CLIENT: Idempotency key not found")
+	ErrIdempotencyKeyReused   = errors.New("CLIENT: Idempotency key reused for another smart contract")
+)
+
+// NewIdempotencyKey returns a new random idempotency key.
+func NewIdempotencyKey() string {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		panic(fmt.Sprintf("CLIENT: NewIdempotencyKey: %v", err))
+	}
+	return hex.EncodeToString(key)
+}
+
+// IdempotencyMutationKey returns the key under which a smart contract records the
+// idempotency key of a task (see IdempotentCaller).
+func IdempotencyMutationKey(key string) string {
+	return IDEMPOTENCY_KEY_MUTATION_PREFIX + key
+}
+
+// IdempotencyRecord is what an IdempotencyStore knows of an idempotent invocation.
+type IdempotencyRecord struct {
+	Key               string `json:"key"`
+	SmartContractSpec string `json:"smartContractSpec"`
+	// Completed is set once the invocation is known to have been carried out. Until
+	// then, it may or may not have been.
+	Completed bool   `json:"completed"`
+	CommitID  string `json:"commitId,omitempty"`
+	// Payload is what the smart contract returned, if the invocation completed in
+	// this process. It is nil if the invocation was found on the blockchain instead.
+	Payload  []byte `json:"payload,omitempty"`
+	Attempts int    `json:"attempts"`
+}
+
+// IdempotencyStore records idempotent invocations by key, so that they are retried
+// at most once (see IdempotentCaller).
+type IdempotencyStore interface {
+	// Load returns the record of key, or an error wrapping ErrIdempotencyKeyNotFound.
+	Load(key string) (IdempotencyRecord, error)
+	Save(record IdempotencyRecord) error
+	Delete(key string) error
+}
+
+// MemoryIdempotencyStore is an IdempotencyStore keeping records in memory.
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]IdempotencyRecord
+}
+
+// NewMemoryIdempotencyStore returns an empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]IdempotencyRecord)}
+}
+
+// Load implements IdempotencyStore.
+func (store *MemoryIdempotencyStore) Load(key string) (IdempotencyRecord, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	record, ok := store.records[key]
+	if !ok {
+		return IdempotencyRecord{}, ErrIdempotencyKeyNotFound
+	}
+	return record, nil
+}
+
+// Save implements IdempotencyStore.
+func (store *MemoryIdempotencyStore) Save(record IdempotencyRecord) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.records[record.Key] = record
+	return nil
+}
+
+// Delete implements IdempotencyStore.
+func (store *MemoryIdempotencyStore) Delete(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.records, key)
+	return nil
+}
+
+// IdempotentCaller invokes smart contracts at most once per idempotency key, retrying
+// invocations that fail with a transport error, whose outcome is unknown.
+//
+// The key travels in the IdempotencyKey field of the pb.ScTask sent to the smart
+// contract, which must record it by writing IdempotencyMutationKey(key) in the same
+// transaction (and should reject keys it has already recorded). Before retrying,
+// IdempotentCaller searches the latest Window transactions (see
+// ListLatestTransactions) for that mutation: if it finds one, the earlier attempt
+// committed, and is not resubmitted.
+//
+// Every invocation is recorded in Store before being submitted, so that a process
+// restarting with a persistent store searches the blockchain before resubmitting the
+// invocations it may have carried out before stopping.
+type IdempotentCaller struct {
+	Invoker Invoker
+	// Reader is searched for earlier attempts. If nil, Invoker is used, which must
+	// then be a BlockchainReader too (as Client and Pool are).
+	Reader BlockchainReader
+	Store  IdempotencyStore
+
+	// Window is the number of latest transactions searched. DEFAULT_IDEMPOTENCY_WINDOW
+	// if zero.
+	Window int
+	// Retries bounds the number of retries after transport errors.
+	// DEFAULT_IDEMPOTENCY_RETRIES if zero; negative for none.
+	Retries int
+	// Backoff is the delay before the first retry, doubled before every other.
+	// DEFAULT_IDEMPOTENCY_BACKOFF if zero.
+	Backoff time.Duration
+}
+
+// Invoke invokes the smart contract identified by smartContractSpec with task, unless
+// an invocation with the same key already completed, and returns its payload and
+// commit ID (see IdentifiedInvoke). The payload is nil if an earlier attempt was found
+// on the blockchain.
+//
+// Concurrent Invoke calls with the same key run one after the other, so that only the
+// first one submits the invocation. Invoke gives up when ctx is done, leaving the key
+// pending if an attempt was submitted.
+//
+// Failures reported by the smart contract are not retried, and forget the key. Once
+// the retries are exhausted, the key stays recorded as pending, and a later Invoke with
+// the same key searches the blockchain again before resubmitting.
+func (caller IdempotentCaller) Invoke(ctx context.Context, key string, smartContractSpec string, task pb.ScTask) ([]byte, string, error) {
+	reader := caller.Reader
+	if reader == nil {
+		var ok bool
+		if reader, ok = caller.Invoker.(BlockchainReader); !ok {
+			return nil, "", fmt.Errorf("CLIENT: IdempotentCaller: %T is not a BlockchainReader", caller.Invoker)
+		}
+	}
+	spec, err := ParseSmartContractSpec(smartContractSpec)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if err = idempotencyLocks.lock(ctx, key); err != nil {
+		return nil, "", fmt.Errorf("CLIENT: IdempotentCaller(%q): %w", key, err)
+	}
+	defer idempotencyLocks.unlock(key)
+
+	record, err := caller.Store.Load(key)
+	switch {
+	case errors.Is(err, ErrIdempotencyKeyNotFound):
+		record = IdempotencyRecord{Key: key, SmartContractSpec: smartContractSpec}
+	case err != nil:
+		return nil, "", fmt.Errorf("CLIENT: IdempotentCaller(%q): %w", key, err)
+	case record.SmartContractSpec != smartContractSpec:
+		return nil, "", fmt.Errorf("%w: %q was used for %s", ErrIdempotencyKeyReused, key, record.SmartContractSpec)
+	case record.Completed:
+		return record.Payload, record.CommitID, nil
+	}
+
+	task.IdempotencyKey = key
+	args, err := json.Marshal(task)
+	if err != nil {
+		return nil, "", fmt.Errorf(FMT_FUNC_X_TASK_ENCODE_ERROR_X, "IdempotentCaller", err)
+	}
+
+	retries, backoff := caller.Retries, caller.Backoff
+	if retries == 0 {
+		retries = DEFAULT_IDEMPOTENCY_RETRIES
+	}
+	if backoff <= 0 {
+		backoff = DEFAULT_IDEMPOTENCY_BACKOFF
+	}
+	for retry := 0; ; retry++ {
+		// Any earlier attempt, in this process or before it restarted, may have committed.
+		if record.Attempts > 0 {
+			commitID, err := caller.find(reader, spec.Name, key)
+			if err != nil {
+				return nil, "", err
+			}
+			if commitID != "" {
+				record.Completed, record.CommitID = true, commitID
+				return nil, commitID, caller.save(record)
+			}
+		}
+
+		record.Attempts++
+		if err = caller.save(record); err != nil {
+			return nil, "", err
+		}
+		payload, commitID, err := identifiedInvokeContext(ctx, caller.Invoker, smartContractSpec, args)
+		if err == nil {
+			record.Completed, record.CommitID, record.Payload = true, commitID, payload
+			return payload, commitID, caller.save(record)
+		}
+		if ctx.Err() != nil {
+			return nil, "", fmt.Errorf("CLIENT: IdempotentCaller(%q): %w", key, ctx.Err())
+		}
+		if !transportError(err) {
+			if deleteErr := caller.Store.Delete(key); deleteErr != nil {
+				return nil, "", fmt.Errorf("CLIENT: IdempotentCaller(%q): %w, and its record could not be deleted: %v", key, err, deleteErr)
+			}
+			return nil, "", err
+		}
+		if retry >= retries {
+			return nil, "", fmt.Errorf("CLIENT: IdempotentCaller(%q): %d attempts failed: %w", key, record.Attempts, err)
+		}
+
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return nil, "", fmt.Errorf("CLIENT: IdempotentCaller(%q): %w", key, ctx.Err())
+		}
+	}
+}
+
+// idempotencyLocks serializes the Invoke calls using the same idempotency key.
+var idempotencyLocks = keyLocks{locks: make(map[string]*keyLock)}
+
+// keyLocks is a set of mutexes, one per key in use.
+type keyLocks struct {
+	mu    sync.Mutex
+	locks map[string]*keyLock
+}
+
+type keyLock struct {
+	// held holds a value while the lock is held.
+	held chan struct{}
+	// users counts the goroutines holding or waiting for the lock.
+	users int
+}
+
+// lock locks key, unless ctx is done first.
+func (locks *keyLocks) lock(ctx context.Context, key string) error {
+	locks.mu.Lock()
+	l := locks.locks[key]
+	if l == nil {
+		l = &keyLock{held: make(chan struct{}, 1)}
+		locks.locks[key] = l
+	}
+	l.users++
+	locks.mu.Unlock()
+
+	select {
+	case l.held <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		locks.done(key, l)
+		return ctx.Err()
+	}
+}
+
+// unlock unlocks key, locked by lock.
+func (locks *keyLocks) unlock(key string) {
+	locks.mu.Lock()
+	l := locks.locks[key]
+	locks.mu.Unlock()
+	<-l.held
+	locks.done(key, l)
+}
+
+func (locks *keyLocks) done(key string, l *keyLock) {
+	locks.mu.Lock()
+	defer locks.mu.Unlock()
+	if l.users--; l.users == 0 {
+		delete(locks.locks, key)
+	}
+}
+
+func (caller IdempotentCaller) save(record IdempotencyRecord) error {
+	if err := caller.Store.Save(record); err != nil {
+		return fmt.Errorf("CLIENT: IdempotentCaller(%q): %w", record.Key, err)
+	}
+	return nil
+}
+
+// find returns the ID of the latest transaction of the smart contract scName recording
+// key, or "" if there is none among the latest Window transactions.
+func (caller IdempotentCaller) find(reader BlockchainReader, scName string, key string) (string, error) {
+	window := caller.Window
+	if window <= 0 {
+		window = DEFAULT_IDEMPOTENCY_WINDOW
+	}
+	raw, err := reader.ListLatestTransactions(window)
+	if err != nil {
+		return "", err
+	}
+	var latest struct {
+		TxIds []string `json:"tx_ids"`
+	}
+	if err = json.Unmarshal(raw, &latest); err != nil {
+		return "", fmt.Errorf(E_FUNC_X_OUTPUT_DECODE_ERROR_X, API_LIST_LATEST_TRANSACTION, err)
+	}
+
+	mutationKey := IdempotencyMutationKey(key)
+	for _, txID := range latest.TxIds {
+		raw, err := reader.GetSmartContractTransactionJson(txID)
+		if err != nil {
+			if transactionNotFound(err, txID) {
+				// Forgotten since it was listed.
+				continue
+			}
+			return "", err
+		}
+		var details struct {
+			ScName    string `json:"sc_name"`
+			Mutations []struct {
+				Key []byte `json:"key"`
+			} `json:"mutations"`
+		}
+		if err = json.Unmarshal(raw, &details); err != nil {
+			return "", fmt.Errorf(E_FUNC_X_OUTPUT_DECODE_ERROR_X, API_GET_SMARTCONTRACT_TRANSACTION_JSON, err)
+		}
+		if details.ScName != scName {
+			continue
+		}
+		for _, mutation := range details.Mutations {
+			if string(mutation.Key) == mutationKey {
+				return txID, nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// transportError reports whether err is a gRPC error telling that the request was
+// interrupted, rather than refused: it may or may not have been carried out.
+func transportError(err error) bool {
+	switch grpcCode(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Canceled, codes.Aborted, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,201 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+
+	"google.golang.org/grpc/codes"
+)
+
+// deposits counts deposits, once per idempotency key.
+func deposits(sc *pcoretest.Context) ([]byte, error) {
+	if sc.Task.Action == "fail" {
+		return nil, errors.New("deposit refused")
+	}
+	if !sc.RecordIdempotencyKey() {
+		return nil, errors.New("duplicate deposit")
+	}
+	count, _ := sc.Get("count")
+	count = append(count, '+')
+	sc.Put("count", count)
+	return count, nil
+}
+
+func TestIdempotentCaller(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+	server.AddSmartContract("deposits", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("deposits-v*", deposits)
+
+	injector := sdk.NewFaultInjector(1,
+		// The first deposit commits, but its response is lost.
+		sdk.FaultRule{Methods: []string{"IdentifiedInvoke"}, Times: 1, Code: codes.Unavailable, AfterCall: true},
+	)
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	store := sdk.NewMemoryIdempotencyStore()
+	caller := sdk.IdempotentCaller{Invoker: client, Store: store, Backoff: time.Millisecond}
+	ctx := context.Background()
+	task := pb.ScTask{Action: "deposit"}
+
+	payload, commitID, err := caller.Invoke(ctx, "first", "deposits-v1", task)
+	if err != nil || payload != nil || commitID == "" {
+		t.Fatalf("Invoke() = %q, %q, %v, want the commit of the lost attempt", payload, commitID, err)
+	}
+	if count, _ := server.Value("deposits", "count"); string(count) != "+" {
+		t.Fatalf("count = %q after a retried deposit, want one deposit", count)
+	}
+	record, err := store.Load("first")
+	if err != nil || !record.Completed || record.CommitID != commitID || record.Attempts != 1 {
+		t.Fatalf("record = %+v, %v", record, err)
+	}
+
+	// Completed invocations are not repeated.
+	transactions := len(server.Transactions())
+	if _, again, err := caller.Invoke(ctx, "first", "deposits-v1", task); err != nil || again != commitID {
+		t.Fatalf("repeated Invoke() = %q, %v, want %q", again, err, commitID)
+	}
+	if len(server.Transactions()) != transactions {
+		t.Fatal("repeated Invoke() committed a transaction")
+	}
+	if _, _, err = caller.Invoke(ctx, "first", "deposits-v2", task); !errors.Is(err, sdk.ErrIdempotencyKeyReused) {
+		t.Fatalf("Invoke() of another contract = %v, want ErrIdempotencyKeyReused", err)
+	}
+
+	// Invocations failing before reaching ParallelCore are resubmitted.
+	injector = sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"IdentifiedInvoke"}, Times: 2, Code: codes.Unavailable})
+	client, err = server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	caller.Invoker = client
+
+	payload, _, err = caller.Invoke(ctx, "second", "deposits-v1", task)
+	if err != nil || string(payload) != "++" {
+		t.Fatalf("Invoke() = %q, %v, want the second deposit", payload, err)
+	}
+	if record, _ = store.Load("second"); record.Attempts != 3 {
+		t.Fatalf("Attempts = %d, want 3", record.Attempts)
+	}
+
+	// Failures reported by the smart contract are final, and forget the key.
+	if _, _, err = caller.Invoke(ctx, "third", "deposits-v1", pb.ScTask{Action: "fail"}); err == nil || !strings.Contains(err.Error(), "deposit refused") {
+		t.Fatalf("Invoke() = %v, want the smart contract's error", err)
+	}
+	if _, err = store.Load("third"); !errors.Is(err, sdk.ErrIdempotencyKeyNotFound) {
+		t.Fatalf("Load() of a failed key = %v, want ErrIdempotencyKeyNotFound", err)
+	}
+
+	// A pending key left by a stopped process is searched for before resubmitting.
+	store.Save(sdk.IdempotencyRecord{Key: "fourth", SmartContractSpec: "deposits-v1", Attempts: 1})
+	payload, commitID, err = caller.Invoke(ctx, "fourth", "deposits-v1", task)
+	if err != nil || string(payload) != "+++" || commitID == "" {
+		t.Fatalf("Invoke() = %q, %q, %v, want the third deposit", payload, commitID, err)
+	}
+
+	// So are gRPC errors telling that the invocation was refused.
+	injector = sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"IdentifiedInvoke"}, Times: 1, Code: codes.PermissionDenied})
+	client, err = server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithFaultInjector(injector))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	caller.Invoker = client
+	if _, _, err = caller.Invoke(ctx, "fifth", "deposits-v1", task); err == nil {
+		t.Fatal("Invoke() retried an invocation refused with PermissionDenied")
+	}
+	if count, _ := server.Value("deposits", "count"); string(count) != "+++" {
+		t.Fatalf("count = %q after a refused deposit", count)
+	}
+}
+
+// undeletableStore is an IdempotencyStore failing to delete records.
+type undeletableStore struct {
+	*sdk.MemoryIdempotencyStore
+}
+
+func (undeletableStore) Delete(key string) error {
+	return errors.New("store offline")
+}
+
+func TestIdempotentCallerDeleteError(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+	server.AddSmartContract("deposits", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("deposits-v*", deposits)
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	caller := sdk.IdempotentCaller{Invoker: client, Store: undeletableStore{sdk.NewMemoryIdempotencyStore()}}
+	_, _, err = caller.Invoke(context.Background(), "key", "deposits-v1", pb.ScTask{Action: "fail"})
+	if err == nil || !strings.Contains(err.Error(), "deposit refused") || !strings.Contains(err.Error(), "store offline") {
+		t.Fatalf("Invoke() = %v, want both the smart contract's and the store's errors", err)
+	}
+}
+
+func TestIdempotentCallerSerializesKeys(t *testing.T) {
+	client, server := pcoretest.NewClient(t)
+	server.AddSmartContract("deposits", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("deposits-v*", deposits)
+	caller := sdk.IdempotentCaller{Invoker: client, Store: sdk.NewMemoryIdempotencyStore(), Backoff: time.Millisecond}
+	ctx := context.Background()
+
+	const callers = 10
+	commitIDs := make(chan string, callers)
+	var wg sync.WaitGroup
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, commitID, err := caller.Invoke(ctx, "shared", "deposits-v1", pb.ScTask{Action: "deposit"})
+			if err != nil {
+				t.Errorf("concurrent Invoke() = %v", err)
+			}
+			commitIDs <- commitID
+		}()
+	}
+	wg.Wait()
+	close(commitIDs)
+	first := <-commitIDs
+	for commitID := range commitIDs {
+		if commitID != first {
+			t.Errorf("concurrent Invoke() calls returned commits %q and %q", first, commitID)
+		}
+	}
+	if count, _ := server.Value("deposits", "count"); string(count) != "+" {
+		t.Errorf("count = %q after concurrent deposits with one key, want one deposit", count)
+	}
+
+	// The invocation itself stops when the context is done.
+	release := make(chan struct{})
+	defer close(release)
+	server.AddSmartContract("blocking", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("blocking-v1", func(*pcoretest.Context) ([]byte, error) {
+		<-release
+		return nil, nil
+	})
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := caller.Invoke(short, "blocked", "blocking-v1", pb.ScTask{}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Invoke() of a blocked contract = %v, want context.DeadlineExceeded", err)
+	}
+}
@@ -0,0 +1,93 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+//go:generate go run ./mocks/gen.go -source api_interfaces.go -output mocks/mocks.go
+
+// The interfaces below group the ParallelCore APIs by capability, so that code built on
+// the SDK can depend on (and tests can mock) only what it uses. Client and Pool satisfy
+// all of them; package mocks provides generated implementations.
+
+// Invoker invokes smart contracts.
+type Invoker interface {
+	Invoke(smartContractSpec string, args []byte) ([]byte, error)
+	IdentifiedInvoke(smartContractSpec string, args []byte) ([]byte, string, error)
+	ListInvokableSC() ([]byte, error)
+}
+
+// UserAdmin manages users and their access to APIs.
+type UserAdmin interface {
+	CreateClient(clientDataJSON []byte) ([]byte, error)
+	CreateUser(userID string, password string, roles []string, domains []string) (string, error)
+	UpdateClient(clientDataJSON []byte) ([]byte, error)
+	UpdateUser(userID string, password string, roles []string, domains []string) (string, error)
+	ListClient(clientID []byte) ([]byte, error)
+	GetUserInfo(clientID string) (UserFullData, error)
+	ListClients(query []byte) ([]byte, error)
+	GetUserInfos(allDomains bool, domainName string) ([]UserFullDataWrapper, error)
+	RemoveClient(clientDomainDataJSON []byte) ([]byte, error)
+	DeleteUser(userID string, userDomainName string) (string, error)
+	CheckApiAccess(apiAccessControllerJSON []byte) ([]byte, error)
+	ManageApiAccess(in []byte) ([]byte, error)
+}
+
+// DomainAdmin manages domains and their domain-admins.
+type DomainAdmin interface {
+	CreateDomain(domainName []byte) ([]byte, error)
+	ListDomain(domainName []byte) ([]byte, error)
+	ListManagedDomains(userID []byte) ([]byte, error)
+	GrantDomainAdmin(in []byte) ([]byte, error)
+	RevokeDomainAdmin(in []byte) ([]byte, error)
+}
+
+// SmartContractRegistry registers smart contracts and manages their access lists.
+type SmartContractRegistry interface {
+	RegisterSmartContract(scRegistration []byte) ([]byte, error)
+	ListSmartContract(scName []byte) ([]byte, error)
+	ListSmartContracts(query []byte) ([]byte, error)
+	GrantAccess(clientAccessDataJSON []byte) ([]byte, error)
+	RevokeAccess(clientAccessDataJSON []byte) ([]byte, error)
+}
+
+// BlockchainReader reads blocks and transactions.
+type BlockchainReader interface {
+	GetBlockchainSummaryJson() ([]byte, error)
+	GetBlockchainSummary() (BlockchainSummary, error)
+	GetBlockDetailsJson(chainID string, blockID string) ([]byte, error)
+	CalculateBlockHash(chainID string, blockID string) ([]byte, error)
+	GetSmartContractTransactionJson(transactionId string) ([]byte, error)
+	GetSmartContractTransactionMetadataJson(transactionId string) ([]byte, error)
+	ListLatestTransactions(count int) ([]byte, error)
+}
+
+// ForgetAdmin runs the right-to-forget workflow.
+type ForgetAdmin interface {
+	RequestForget(txIds []string) (string, error)
+	ApproveForget(forgetRequestTxID string) (string, error)
+	CommitForget(forgetRequestTxID string, forgetApprovalTxID []string) (ForgetReport, error)
+	ListForgetGroups(txIds []string) ([]ForgetGroup, error)
+}
+
+// EventSubscriber listens to smart contract events.
+type EventSubscriber interface {
+	RegisterEventListener(scName string, eventFilter string) (*ListenerController, <-chan *EventWrapper, error)
+}
+
+// API is the whole of the ParallelCore API.
+type API interface {
+	Invoker
+	UserAdmin
+	DomainAdmin
+	SmartContractRegistry
+	BlockchainReader
+	ForgetAdmin
+	EventSubscriber
+}
+
+var (
+	_ API = (*Client)(nil)
+	_ API = (*Pool)(nil)
+)
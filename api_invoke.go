@@ -31,26 +31,95 @@ func (client *Client) ListInvokableSC() ([]byte, error) {
 //  - smartContractSpec string: SC identifier with the format: <SC name>-v<SC version number>
 //  - args string: passed into the invoke SC's Handle function as its 2nd 'in' parameter.
 func (client *Client) Invoke(smartContractSpec string, args []byte) ([]byte, error) {
-	return client.invoke(append([]byte(smartContractSpec+" "), args...))
+	return client.invoke(context.Background(), append([]byte(smartContractSpec+" "), args...))
 }
 
 // IdentifiedInvoke is similar to Invoke but has as its 2nd returned value the SC's transaction
 // commit ID. If the transaction the SC produces is a read-only transaction, or if the invocation
 // errors, commit ID will be an empty string.
 func (client *Client) IdentifiedInvoke(smartContractSpec string, args []byte) ([]byte, string, error) {
-	return client.identifiedInvoke(append([]byte(smartContractSpec+" "), args...))
+	return client.identifiedInvoke(context.Background(), append([]byte(smartContractSpec+" "), args...))
 }
 
-func (client *Client) invoke(in []byte) ([]byte, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+// invokeContext invokes the smart contract identified by smartContractSpec through
+// invoker, giving up when ctx is done. The invocations of a *Client or a *Pool are then
+// cancelled; those of other Invokers, which take no context, are left running.
+func invokeContext(ctx context.Context, invoker Invoker, smartContractSpec string, args []byte) ([]byte, error) {
+	var client *Client
+	switch each := invoker.(type) {
+	case *Client:
+		client = each
+	case *Pool:
+		var release func()
+		var err error
+		if client, release, err = each.acquire(); err != nil {
+			return nil, err
+		}
+		defer release()
+	default:
+		type outcome struct {
+			payload []byte
+			err     error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			payload, err := invoker.Invoke(smartContractSpec, args)
+			done <- outcome{payload, err}
+		}()
+		select {
+		case out := <-done:
+			return out.payload, out.err
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	return client.invoke(ctx, append([]byte(smartContractSpec+" "), args...))
+}
+
+// identifiedInvokeContext is invokeContext for IdentifiedInvoke.
+func identifiedInvokeContext(ctx context.Context, invoker Invoker, smartContractSpec string, args []byte) ([]byte, string, error) {
+	var client *Client
+	switch each := invoker.(type) {
+	case *Client:
+		client = each
+	case *Pool:
+		var release func()
+		var err error
+		if client, release, err = each.acquire(); err != nil {
+			return nil, "", err
+		}
+		defer release()
+	default:
+		type outcome struct {
+			payload  []byte
+			commitID string
+			err      error
+		}
+		done := make(chan outcome, 1)
+		go func() {
+			payload, commitID, err := invoker.IdentifiedInvoke(smartContractSpec, args)
+			done <- outcome{payload, commitID, err}
+		}()
+		select {
+		case out := <-done:
+			return out.payload, out.commitID, out.err
+		case <-ctx.Done():
+			return nil, "", ctx.Err()
+		}
+	}
+	return client.identifiedInvoke(ctx, append([]byte(smartContractSpec+" "), args...))
+}
+
+func (client *Client) invoke(ctx context.Context, in []byte) ([]byte, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	response, err := client.grpcClient.Invoke(ctx, &pb.Request{Payload: in})
 	return handleResponse(response, err, "invoke")
 }
 
-func (client *Client) identifiedInvoke(in []byte) ([]byte, string, error) {
-	ctx, cancel := context.WithCancel(context.Background())
+func (client *Client) identifiedInvoke(ctx context.Context, in []byte) ([]byte, string, error) {
+	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
 	response, err := client.grpcClient.IdentifiedInvoke(ctx, &pb.Request{Payload: in})
@@ -0,0 +1,380 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// InvokeCacheOptions configures an InvokeCache.
+type InvokeCacheOptions struct {
+	// TTL is how long results are served from the cache. DEFAULT_INVOKE_CACHE_TTL if
+	// zero.
+	TTL time.Duration
+	// MaxEntries bounds the number of results cached, the least recently used being
+	// evicted first. DEFAULT_INVOKE_CACHE_MAX_ENTRIES if zero.
+	MaxEntries int
+	// Subscriber, if set, is listened to for the events of every smart contract whose
+	// results are cached: any event matching EventFilter (".*" if empty) invalidates
+	// them. Since closing a listener closes the connection it listens over, Subscriber
+	// should be a Pool, or a Client dedicated to the cache.
+	Subscriber  EventSubscriber
+	EventFilter string
+	// SubscribeBackoff is the delay before subscribing again to the events of a smart
+	// contract after a failed subscription, doubled after every other failure up to
+	// SubscribeMaxBackoff. Results of the smart contract are not cached meanwhile.
+	// DEFAULT_INVOKE_CACHE_SUBSCRIBE_BACKOFF and
+	// DEFAULT_INVOKE_CACHE_SUBSCRIBE_MAX_BACKOFF if zero.
+	SubscribeBackoff    time.Duration
+	SubscribeMaxBackoff time.Duration
+}
+
+// InvokeCacheStats counts the invocations made through an InvokeCache.
+type InvokeCacheStats struct {
+	Hits   uint64
+	Misses uint64
+	// Coalesced counts the invocations that waited for an identical one in progress.
+	Coalesced     uint64
+	Evictions     uint64
+	Invalidations uint64
+	// SubscribeFailures counts the failed subscriptions to the events of smart
+	// contracts.
+	SubscribeFailures uint64
+}
+
+// InvokeCache is an Invoker caching the results of read-only invocations (those for
+// which IdentifiedInvoke returns no commit ID), keyed by smart contract spec and
+// arguments.
+//
+// Cached results are dropped after a TTL, when the cache is full, and whenever the
+// smart contract is known to have changed: when an invocation through the cache
+// commits a transaction, when Invalidate is called, and, if the cache has a
+// Subscriber, when the smart contract emits an event. With a Subscriber, results are
+// only cached while the cache listens to the smart contract's events.
+//
+// Identical invocations made at once are coalesced into one, provided an earlier
+// invocation showed them to be read-only; invocations never made before are never
+// coalesced, so that writes are not lost.
+type InvokeCache struct {
+	invoker Invoker
+	opts    InvokeCacheOptions
+
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	lru       *list.List
+	flights   map[string]*invokeFlight
+	contracts map[string]*cachedContract
+	stats     InvokeCacheStats
+	closed    bool
+}
+
+type cacheEntry struct {
+	key     string
+	scName  string
+	payload []byte
+	// expires is zero once the entry is invalidated: it only records that key is
+	// read-only.
+	expires time.Time
+}
+
+type invokeFlight struct {
+	done     chan struct{}
+	payload  []byte
+	commitID string
+	err      error
+}
+
+type cachedContract struct {
+	// generation changes whenever the contract's entries are invalidated, so that
+	// results read before are not cached.
+	generation  uint64
+	listener    *ListenerController
+	subscribing chan struct{}
+	// failures counts the subscriptions failed in a row; none is attempted again
+	// before retryAt.
+	failures int
+	retryAt  time.Time
+}
+
+// NewInvokeCache returns an empty InvokeCache over invoker.
+func NewInvokeCache(invoker Invoker, opts InvokeCacheOptions) *InvokeCache {
+	if opts.TTL <= 0 {
+		opts.TTL = DEFAULT_INVOKE_CACHE_TTL
+	}
+	if opts.MaxEntries <= 0 {
+		opts.MaxEntries = DEFAULT_INVOKE_CACHE_MAX_ENTRIES
+	}
+	if opts.EventFilter == "" {
+		opts.EventFilter = ".*"
+	}
+	if opts.SubscribeBackoff <= 0 {
+		opts.SubscribeBackoff = DEFAULT_INVOKE_CACHE_SUBSCRIBE_BACKOFF
+	}
+	if opts.SubscribeMaxBackoff <= 0 {
+		opts.SubscribeMaxBackoff = DEFAULT_INVOKE_CACHE_SUBSCRIBE_MAX_BACKOFF
+	}
+	return &InvokeCache{
+		invoker:   invoker,
+		opts:      opts,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+		flights:   make(map[string]*invokeFlight),
+		contracts: make(map[string]*cachedContract),
+	}
+}
+
+// Invoke is similar to IdentifiedInvoke, without the commit ID.
+func (cache *InvokeCache) Invoke(smartContractSpec string, args []byte) ([]byte, error) {
+	payload, _, err := cache.IdentifiedInvoke(smartContractSpec, args)
+	return payload, err
+}
+
+// IdentifiedInvoke returns the cached result of the invocation, if any, and invokes
+// the smart contract otherwise (see Client.IdentifiedInvoke).
+func (cache *InvokeCache) IdentifiedInvoke(smartContractSpec string, args []byte) ([]byte, string, error) {
+	spec, err := ParseSmartContractSpec(smartContractSpec)
+	if err != nil {
+		return cache.invoker.IdentifiedInvoke(smartContractSpec, args)
+	}
+	key := smartContractSpec + " " + string(args)
+
+	cache.mu.Lock()
+	if cache.closed {
+		cache.mu.Unlock()
+		return cache.invoker.IdentifiedInvoke(smartContractSpec, args)
+	}
+	var flight *invokeFlight
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*cacheEntry)
+		if time.Now().Before(entry.expires) {
+			cache.lru.MoveToFront(element)
+			cache.stats.Hits++
+			payload := append([]byte(nil), entry.payload...)
+			cache.mu.Unlock()
+			return payload, "", nil
+		}
+		if leader, ok := cache.flights[key]; ok {
+			cache.stats.Coalesced++
+			cache.mu.Unlock()
+			return cache.follow(leader, smartContractSpec, args)
+		}
+		flight = &invokeFlight{done: make(chan struct{})}
+		cache.flights[key] = flight
+	}
+	cache.stats.Misses++
+	cache.mu.Unlock()
+
+	listening := cache.listen(spec.Name)
+	cache.mu.Lock()
+	generation := cache.contract(spec.Name).generation
+	cache.mu.Unlock()
+
+	payload, commitID, err := cache.invoker.IdentifiedInvoke(smartContractSpec, args)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	if flight != nil {
+		flight.payload, flight.commitID, flight.err = payload, commitID, err
+		delete(cache.flights, key)
+		close(flight.done)
+	}
+	switch {
+	case err != nil:
+	case commitID != "":
+		cache.remove(key)
+		cache.invalidate(spec.Name)
+	case listening && !cache.closed && cache.contract(spec.Name).generation == generation:
+		cache.store(key, spec.Name, payload)
+	}
+	return payload, commitID, err
+}
+
+// follow waits for the invocation leader, and returns its result. Invocations that
+// turned out to commit are made again, since coalescing them would lose writes.
+func (cache *InvokeCache) follow(leader *invokeFlight, smartContractSpec string, args []byte) ([]byte, string, error) {
+	<-leader.done
+	if leader.commitID != "" {
+		return cache.IdentifiedInvoke(smartContractSpec, args)
+	}
+	return append([]byte(nil), leader.payload...), "", leader.err
+}
+
+// ListInvokableSC is not cached.
+func (cache *InvokeCache) ListInvokableSC() ([]byte, error) {
+	return cache.invoker.ListInvokableSC()
+}
+
+// Invalidate drops the cached results of the smart contract named scName.
+func (cache *InvokeCache) Invalidate(scName string) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.invalidate(scName)
+}
+
+// Stats returns the cache's counters.
+func (cache *InvokeCache) Stats() InvokeCacheStats {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.stats
+}
+
+// Len returns the number of results cached.
+func (cache *InvokeCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	count := 0
+	now := time.Now()
+	for element := cache.lru.Front(); element != nil; element = element.Next() {
+		if now.Before(element.Value.(*cacheEntry).expires) {
+			count++
+		}
+	}
+	return count
+}
+
+// Close drops the cached results and stops listening to events. Invocations made
+// afterwards go straight to the cache's Invoker.
+func (cache *InvokeCache) Close() {
+	cache.mu.Lock()
+	cache.closed = true
+	listeners := make([]*ListenerController, 0)
+	for _, contract := range cache.contracts {
+		if contract.listener != nil {
+			listeners = append(listeners, contract.listener)
+			contract.listener = nil
+		}
+	}
+	cache.entries = make(map[string]*list.Element)
+	cache.lru.Init()
+	cache.mu.Unlock()
+
+	for _, each := range listeners {
+		each.Close()
+	}
+}
+
+// listen makes sure the cache listens to the events of the smart contract scName, if
+// it has a Subscriber, and reports whether results of scName can be cached. After a
+// failed subscription, it reports false without subscribing until the backoff is over.
+func (cache *InvokeCache) listen(scName string) bool {
+	if cache.opts.Subscriber == nil {
+		return true
+	}
+	cache.mu.Lock()
+	contract := cache.contract(scName)
+	if contract.listener != nil {
+		cache.mu.Unlock()
+		return true
+	}
+	if subscribing := contract.subscribing; subscribing != nil {
+		cache.mu.Unlock()
+		<-subscribing
+		cache.mu.Lock()
+		defer cache.mu.Unlock()
+		return contract.listener != nil
+	}
+	if time.Now().Before(contract.retryAt) {
+		cache.mu.Unlock()
+		return false
+	}
+	subscribing := make(chan struct{})
+	contract.subscribing = subscribing
+	cache.mu.Unlock()
+
+	listener, events, err := cache.opts.Subscriber.RegisterEventListener(scName, cache.opts.EventFilter)
+
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	contract.subscribing = nil
+	close(subscribing)
+	if err != nil {
+		cache.stats.SubscribeFailures++
+		backoff := cache.opts.SubscribeBackoff
+		for i := 0; i < contract.failures && backoff < cache.opts.SubscribeMaxBackoff; i++ {
+			backoff *= 2
+		}
+		if backoff > cache.opts.SubscribeMaxBackoff {
+			backoff = cache.opts.SubscribeMaxBackoff
+		}
+		contract.failures++
+		contract.retryAt = time.Now().Add(backoff)
+		return false
+	}
+	contract.failures = 0
+	if cache.closed {
+		go listener.Close()
+		return false
+	}
+	contract.listener = listener
+	go cache.watch(scName, listener, events)
+	return true
+}
+
+// watch invalidates the results of scName on every event, until events is closed.
+func (cache *InvokeCache) watch(scName string, listener *ListenerController, events <-chan *EventWrapper) {
+	for range events {
+		cache.Invalidate(scName)
+	}
+
+	// Events may be missed until the cache listens again.
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	cache.invalidate(scName)
+	if contract := cache.contract(scName); contract.listener == listener {
+		contract.listener = nil
+	}
+}
+
+// contract returns the state of the smart contract scName. It must be called with
+// cache.mu held.
+func (cache *InvokeCache) contract(scName string) *cachedContract {
+	contract, ok := cache.contracts[scName]
+	if !ok {
+		contract = &cachedContract{}
+		cache.contracts[scName] = contract
+	}
+	return contract
+}
+
+// invalidate expires the entries of scName. It must be called with cache.mu held.
+func (cache *InvokeCache) invalidate(scName string) {
+	cache.contract(scName).generation++
+	for element := cache.lru.Front(); element != nil; element = element.Next() {
+		if entry := element.Value.(*cacheEntry); entry.scName == scName && !entry.expires.IsZero() {
+			entry.expires, entry.payload = time.Time{}, nil
+			cache.stats.Invalidations++
+		}
+	}
+}
+
+// store caches payload under key, evicting the least recently used entries beyond
+// MaxEntries. It must be called with cache.mu held.
+func (cache *InvokeCache) store(key string, scName string, payload []byte) {
+	entry := &cacheEntry{key: key, scName: scName, payload: append([]byte(nil), payload...), expires: time.Now().Add(cache.opts.TTL)}
+	if element, ok := cache.entries[key]; ok {
+		element.Value = entry
+		cache.lru.MoveToFront(element)
+		return
+	}
+	cache.entries[key] = cache.lru.PushFront(entry)
+	for cache.lru.Len() > cache.opts.MaxEntries {
+		cache.remove(cache.lru.Back().Value.(*cacheEntry).key)
+		cache.stats.Evictions++
+	}
+}
+
+// remove forgets key. It must be called with cache.mu held.
+func (cache *InvokeCache) remove(key string) {
+	if element, ok := cache.entries[key]; ok {
+		cache.lru.Remove(element)
+		delete(cache.entries, key)
+	}
+}
+
+var _ Invoker = (*InvokeCache)(nil)
@@ -0,0 +1,186 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestInvokeCache(t *testing.T) {
+	client, server := pcoretest.NewClient(t)
+	server.AddSmartContract("counter", "1", sdk.DOMAIN_DEFAULT)
+	var calls int32
+	var gate chan struct{}
+	var gateMu sync.Mutex
+	server.Handle("counter-v*", func(sc *pcoretest.Context) ([]byte, error) {
+		atomic.AddInt32(&calls, 1)
+		gateMu.Lock()
+		wait := gate
+		gateMu.Unlock()
+		if wait != nil {
+			<-wait
+		}
+		return counter(sc)
+	})
+
+	subscriber, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := sdk.NewInvokeCache(client, sdk.InvokeCacheOptions{TTL: time.Hour, MaxEntries: 2, Subscriber: subscriber})
+	defer cache.Close()
+
+	get := func(key string) string {
+		t.Helper()
+		payload, err := cache.Invoke("counter-v1", []byte(`{"action":"get","data":"`+key+`"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(payload)
+	}
+
+	if get("a") != "" || get("a") != "" || atomic.LoadInt32(&calls) != 1 {
+		t.Fatalf("two reads made %d invocations, want 1", atomic.LoadInt32(&calls))
+	}
+	if stats := cache.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Fatalf("Stats() = %+v", stats)
+	}
+
+	// Writes through the cache are not cached, and invalidate the smart contract.
+	for i := 0; i < 2; i++ {
+		_, commitID, err := cache.IdentifiedInvoke("counter-v1", []byte(`{"action":"set","data":"a"}`))
+		if err != nil || commitID == "" {
+			t.Fatalf("write %d = %q, %v", i, commitID, err)
+		}
+	}
+	if atomic.LoadInt32(&calls) != 3 || get("a") != "1" || atomic.LoadInt32(&calls) != 4 {
+		t.Fatalf("reads after writes made %d invocations, want 4", atomic.LoadInt32(&calls))
+	}
+
+	// Events invalidate results, whoever causes them.
+	get("b")
+	if _, err = client.Invoke("counter-v1", []byte(`{"action":"set","data":"b"}`)); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for get("b") != "1" {
+		if time.Now().After(deadline) {
+			t.Fatal("event did not invalidate the cached result")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// Beyond MaxEntries, the least recently used results are evicted.
+	get("c")
+	get("d")
+	if stats := cache.Stats(); stats.Evictions == 0 || cache.Len() > 2 {
+		t.Fatalf("Stats() = %+v, Len() = %d", stats, cache.Len())
+	}
+
+	// Identical reads made at once are coalesced.
+	cache.Invalidate("counter")
+	gateMu.Lock()
+	gate = make(chan struct{})
+	gateMu.Unlock()
+	before := atomic.LoadInt32(&calls)
+	var wg sync.WaitGroup
+	results := make([]string, 5)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			payload, _ := cache.Invoke("counter-v1", []byte(`{"action":"get","data":"d"}`))
+			results[i] = string(payload)
+		}(i)
+	}
+	for atomic.LoadInt32(&calls) == before {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	gateMu.Lock()
+	close(gate)
+	gate = nil
+	gateMu.Unlock()
+	wg.Wait()
+	if n := atomic.LoadInt32(&calls) - before; n != 1 {
+		t.Fatalf("5 identical reads made %d invocations, want 1", n)
+	}
+	if stats := cache.Stats(); stats.Coalesced == 0 {
+		t.Fatalf("Stats() = %+v, want coalesced reads", stats)
+	}
+
+	// A closed cache invokes straight through.
+	cache.Close()
+	before = atomic.LoadInt32(&calls)
+	get("d")
+	get("d")
+	if n := atomic.LoadInt32(&calls) - before; n != 2 {
+		t.Fatalf("2 reads through a closed cache made %d invocations", n)
+	}
+}
+
+// flakySubscriber fails its first subscriptions.
+type flakySubscriber struct {
+	sdk.EventSubscriber
+	failures int32
+	calls    int32
+}
+
+func (s *flakySubscriber) RegisterEventListener(scName string, eventFilter string) (*sdk.ListenerController, <-chan *sdk.EventWrapper, error) {
+	if atomic.AddInt32(&s.calls, 1) <= s.failures {
+		return nil, nil, errors.New("subscriber offline")
+	}
+	return s.EventSubscriber.RegisterEventListener(scName, eventFilter)
+}
+
+func TestInvokeCacheSubscribeFailures(t *testing.T) {
+	client, server := newCounterServer(t)
+	listener, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	subscriber := &flakySubscriber{EventSubscriber: listener, failures: 2}
+	cache := sdk.NewInvokeCache(client, sdk.InvokeCacheOptions{Subscriber: subscriber, SubscribeBackoff: 100 * time.Millisecond})
+	defer cache.Close()
+	get := func() {
+		t.Helper()
+		if _, err := cache.Invoke("counter-v1", []byte(`{"action":"get","data":"a"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Failed subscriptions are not attempted again on every miss.
+	for i := 0; i < 5; i++ {
+		get()
+	}
+	if stats := cache.Stats(); stats.Misses != 5 || stats.SubscribeFailures != 1 || atomic.LoadInt32(&subscriber.calls) != 1 {
+		t.Fatalf("Stats() = %+v after %d subscriptions, want 5 misses and 1 failure", stats, atomic.LoadInt32(&subscriber.calls))
+	}
+
+	// The delay doubles after every failure.
+	time.Sleep(120 * time.Millisecond)
+	get()
+	time.Sleep(100 * time.Millisecond)
+	get()
+	if calls := atomic.LoadInt32(&subscriber.calls); calls != 2 {
+		t.Fatalf("%d subscriptions, want 2 before the doubled delay is over", calls)
+	}
+
+	// Once subscribed, results are cached.
+	time.Sleep(150 * time.Millisecond)
+	get()
+	get()
+	if stats := cache.Stats(); stats.Hits != 1 || stats.SubscribeFailures != 2 {
+		t.Fatalf("Stats() = %+v, want a hit after subscribing", stats)
+	}
+}
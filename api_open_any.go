@@ -21,7 +21,8 @@ import (
 //  - clientCredential string
 //  - certPath string: file path to a TLS certificate to set up an encrypted connection. If certPath is empty,
 //  the system certificate pool will be used.
-func OpenAny(endpointSpecs string, clientID string, credential string, certPath string) (*Client, error) {
+//  - opts ...Option: optional connection settings, for example WithProxy.
+func OpenAny(endpointSpecs string, clientID string, credential string, certPath string, opts ...Option) (*Client, error) {
 	// do until endpoints is empty:
 	//  select first endpoint from endpoints
 	//  try to openOne(endpoint) -> c
@@ -30,6 +31,7 @@ func OpenAny(endpointSpecs string, clientID string, credential string, certPath
 	//  remove endpoint from endpoints
 	// return error
 	var lastError error
+	cfg := newOpenConfig(opts)
 
 	if (oldEndpoints == "") || (oldEndpoints != endpointSpecs) {
 		oldEndpoints = endpointSpecs
@@ -41,7 +43,7 @@ func OpenAny(endpointSpecs string, clientID string, credential string, certPath
 		i := endpointsCalled % len(endpoints)
 		endpoint := endpoints[i]
 
-		client, err := openOne(endpoint, certPath, "")
+		client, err := openOne(endpoint, certPath, "", cfg)
 		if err != nil {
 			lastError = err
 			endpoints = append(endpoints[:i], endpoints[i+1:]...)
@@ -58,13 +60,17 @@ func OpenAny(endpointSpecs string, clientID string, credential string, certPath
 		if err != nil {
 			return nil, fmt.Errorf("CLIENT: OpenAny(%q): Failed to parse Token. %w", endpointSpecs, err)
 		}
-		client, err = openOne(endpoint, certPath, token)
+		client, err = openOne(endpoint, certPath, token, cfg)
 		if err != nil {
 			return nil, fmt.Errorf("CLIENT: OpenAny(%q): Failed to open a connection. %w", endpointSpecs, err)
 		}
 		endpointsCalled++
 		client.expireTimestamp = expireTimestamp
 		client.endpointSpecs = endpointSpecs
+		if err = client.saveToken(); err != nil {
+			client.Close()
+			return nil, err
+		}
 
 		return client, nil
 	}
@@ -74,6 +80,6 @@ func OpenAny(endpointSpecs string, clientID string, credential string, certPath
 
 // OpenAnyWithCert is a wrapper around OpenAny. It calls OpenAny with os.Getenv("PCORE_CERT_PATH")
 // as the certPath parameter.
-func OpenAnyWithCert(endpointSpecs string, clientID string, credential string) (*Client, error) {
-	return OpenAny(endpointSpecs, clientID, credential, os.Getenv("PCORE_CERT_PATH"))
+func OpenAnyWithCert(endpointSpecs string, clientID string, credential string, opts ...Option) (*Client, error) {
+	return OpenAny(endpointSpecs, clientID, credential, os.Getenv("PCORE_CERT_PATH"), opts...)
 }
@@ -26,7 +26,7 @@ import (
 //  - token string: JWT token generated by a ParallelCore engine
 //  - expireTimestamp: used to populate returned client.ExpireTimestamp. This helps applications
 //  determine when to Renew clients. See Client.Renew().
-func OpenAnyByToken(endpointSpecs string, token string, expireTimestamp int64, certPath string) (*Client, error) {
+func OpenAnyByToken(endpointSpecs string, token string, expireTimestamp int64, certPath string, opts ...Option) (*Client, error) {
 	// do until endpoints is empty:
 	//  randomly select endpoint from endpoints
 	//  try to openOne(endpoint) -> c
@@ -36,6 +36,7 @@ func OpenAnyByToken(endpointSpecs string, token string, expireTimestamp int64, c
 	// return error
 
 	var lastError error
+	cfg := newOpenConfig(opts)
 
 	if (oldEndpoints == "") || (oldEndpoints != endpointSpecs) {
 		oldEndpoints = endpointSpecs
@@ -47,7 +48,7 @@ func OpenAnyByToken(endpointSpecs string, token string, expireTimestamp int64, c
 		i := endpointsCalled % len(endpoints)
 		endpoint := endpoints[i]
 
-		client, err := openOne(endpoint, certPath, token)
+		client, err := openOne(endpoint, certPath, token, cfg)
 		if err != nil {
 			lastError = err
 			endpoints = append(endpoints[:i], endpoints[i+1:]...)
@@ -56,6 +57,10 @@ func OpenAnyByToken(endpointSpecs string, token string, expireTimestamp int64, c
 		endpointsCalled++
 		client.expireTimestamp = expireTimestamp
 		client.endpointSpecs = endpointSpecs
+		if err = client.saveToken(); err != nil {
+			client.Close()
+			return nil, err
+		}
 
 		return client, nil
 	}
@@ -65,6 +70,6 @@ func OpenAnyByToken(endpointSpecs string, token string, expireTimestamp int64, c
 
 // OpenAnyByTokenWithCert is a wrapper around OpenAnyByToken. It calls OpenAnyByToken with os.Getenv("PCORE_CERT_PATH")
 // as the certPath parameter.
-func OpenAnyByTokenWithCert(endpointSpecs string, token string, expireTimestamp int64) (*Client, error) {
-	return OpenAnyByToken(endpointSpecs, token, expireTimestamp, os.Getenv("PCORE_CERT_PATH"))
+func OpenAnyByTokenWithCert(endpointSpecs string, token string, expireTimestamp int64, opts ...Option) (*Client, error) {
+	return OpenAnyByToken(endpointSpecs, token, expireTimestamp, os.Getenv("PCORE_CERT_PATH"), opts...)
 }
@@ -20,17 +20,18 @@ import (
 // If all connection attempts fail, it will return (nil, error). Otherwise, error is the last error
 // encountered during connection attempts. Applications should check whether or not []*Client is nil
 // to determine if OpenMany succeeded to establish some connections. Do not use error for this purpose.
-func OpenMany(endpointSpecs string, clientID string, credential string, certPath string) ([]*Client, error) {
+func OpenMany(endpointSpecs string, clientID string, credential string, certPath string, opts ...Option) ([]*Client, error) {
 	endpoints := strings.Split(endpointSpecs, " ")
 	lastError := fmt.Errorf("")
 	clients := make([]*Client, 0)
+	cfg := newOpenConfig(opts)
 	// these clients can use the same token
 	token := ""
 	var expireTimestamp int64
 	for _, endpoint := range endpoints {
 		// Create a connection first.
 		// If the connection is not connect with token, then will run next block to fetch JWT and expireTimestamp
-		client, err := openOne(endpoint, certPath, token)
+		client, err := openOne(endpoint, certPath, token, cfg)
 		if err != nil {
 			lastError = fmt.Errorf("CLIENT: OpenMany(%q): %w. Last error: %v", endpointSpecs, err, lastError)
 			continue
@@ -44,12 +45,12 @@ func OpenMany(endpointSpecs string, clientID string, credential string, certPath
 			}
 
 			// Parse returnBytes
-			token, _, err := parseTokenAndExpireTimestamp(string(returnBytes))
+			token, expireTimestamp, err = parseTokenAndExpireTimestamp(string(returnBytes))
 			if err != nil {
 				return nil, fmt.Errorf("CLIENT: OpenMany(%q): Failed to parse Token. %w", endpointSpecs, err)
 			}
 
-			client, err = openOne(endpoint, certPath, token)
+			client, err = openOne(endpoint, certPath, token, cfg)
 			if err != nil {
 				return nil, fmt.Errorf("CLIENT: OpenMany(%q): Failed to open a connection. %w", endpointSpecs, err)
 			}
@@ -62,6 +63,10 @@ func OpenMany(endpointSpecs string, clientID string, credential string, certPath
 		// All attemps to connect are failed.
 		return nil, fmt.Errorf("CLIENT: OpenMany(%q): Failed to open all clients. Last error: %w", endpointSpecs, lastError)
 	}
+	if err := clients[0].saveToken(); err != nil {
+		CloseMany(clients)
+		return nil, err
+	}
 	if lastError.Error() == "" {
 		return clients, nil
 	}
@@ -71,8 +76,8 @@ func OpenMany(endpointSpecs string, clientID string, credential string, certPath
 
 // OpenManyWithCert is a wrapper around OpenMany. It calls OpenMany with os.Getenv("PCORE_CERT_PATH")
 // as the certPath parameter.
-func OpenManyWithCert(endpointSpecs string, clientID string, credential string) ([]*Client, error) {
-	return OpenMany(endpointSpecs, clientID, credential, os.Getenv("PCORE_CERT_PATH"))
+func OpenManyWithCert(endpointSpecs string, clientID string, credential string, opts ...Option) ([]*Client, error) {
+	return OpenMany(endpointSpecs, clientID, credential, os.Getenv("PCORE_CERT_PATH"), opts...)
 }
 
 // CloseMany is like Close, but closes every Client in clients.
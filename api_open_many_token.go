@@ -16,12 +16,13 @@ import (
 // OpenManyByToken is similar to OpenMany, but uses the the token-based authentication
 // OpenAnyByToken uses. An authentication token generated by a ParallelChain peer node
 // is valid for all peer nodes.
-func OpenManyByToken(endpointSpecs string, token string, expireTimestamp int64, certPath string) ([]*Client, error) {
+func OpenManyByToken(endpointSpecs string, token string, expireTimestamp int64, certPath string, opts ...Option) ([]*Client, error) {
 	endpoints := strings.Split(endpointSpecs, " ")
 	lastError := fmt.Errorf("")
 	clients := make([]*Client, 0)
+	cfg := newOpenConfig(opts)
 	for _, endpoint := range endpoints {
-		client, err := openOne(endpoint, certPath, token)
+		client, err := openOne(endpoint, certPath, token, cfg)
 		if err != nil {
 			lastError = fmt.Errorf("CLIENT: OpenManyByToken(%q): %w. Last error: %v", endpointSpecs, err, lastError)
 			continue
@@ -34,6 +35,10 @@ func OpenManyByToken(endpointSpecs string, token string, expireTimestamp int64,
 		// All attemps to connect are failed.
 		return nil, fmt.Errorf("CLIENT: OpenManyByToken(%q): Failed to open all clients using token. Last error: %w", endpointSpecs, lastError)
 	}
+	if err := clients[0].saveToken(); err != nil {
+		CloseMany(clients)
+		return nil, err
+	}
 	if lastError.Error() == "" {
 		return clients, nil
 	}
@@ -43,6 +48,6 @@ func OpenManyByToken(endpointSpecs string, token string, expireTimestamp int64,
 
 // OpenManyByTokenWithCert is a wrapper around OpenManyByToken. It calls OpenManyByToken with os.Getenv("PCORE_CERT_PATH")
 // as the certPath parameter.
-func OpenManyByTokenWithCert(endpointSpecs string, token string, expireTimestamp int64) ([]*Client, error) {
-	return OpenManyByToken(endpointSpecs, token, expireTimestamp, os.Getenv("PCORE_CERT_PATH"))
+func OpenManyByTokenWithCert(endpointSpecs string, token string, expireTimestamp int64, opts ...Option) ([]*Client, error) {
+	return OpenManyByToken(endpointSpecs, token, expireTimestamp, os.Getenv("PCORE_CERT_PATH"), opts...)
 }
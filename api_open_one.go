@@ -7,6 +7,7 @@
 package parallelcore_client_sdk_go
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 
@@ -17,7 +18,15 @@ import (
 	"google.golang.org/grpc/credentials"
 )
 
-func openOne(endpoint string, certPath string, token string) (_ *Client, err error) {
+func openOne(endpoint string, certPath string, token string, cfg *openConfig) (_ *Client, err error) {
+	if cfg == nil {
+		cfg = newOpenConfig(nil)
+	}
+	if cfg.replay != nil {
+		// Replayed clients are served from a cassette, without a connection.
+		return &Client{grpcClient: cfg.replay.handlerClient(), certPath: certPath, token: token, cfg: cfg}, nil
+	}
+
 	var (
 		creds credentials.TransportCredentials
 		conn  *grpc.ClientConn
@@ -31,7 +40,13 @@ func openOne(endpoint string, certPath string, token string) (_ *Client, err err
 		creds = credentials.NewTLS(&tls.Config{})
 	}
 
-	grpcOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds)}
+	grpcOpts := []grpc.DialOption{grpc.WithTransportCredentials(creds), grpc.WithContextDialer(cfg.dialContext)}
+	if len(cfg.unaryInterceptors) != 0 {
+		grpcOpts = append(grpcOpts, grpc.WithChainUnaryInterceptor(cfg.unaryInterceptors...))
+	}
+	if len(cfg.streamInterceptors) != 0 {
+		grpcOpts = append(grpcOpts, grpc.WithChainStreamInterceptor(cfg.streamInterceptors...))
+	}
 	if token != "" {
 		grpcOpts = append(grpcOpts, grpc.WithPerRPCCredentials(customCredential{token: token}))
 	}
@@ -39,7 +54,13 @@ func openOne(endpoint string, certPath string, token string) (_ *Client, err err
 	// Without this, Dial returns immediately and connecting the server happens in background.
 	grpcOpts = append(grpcOpts, grpc.WithBlock())
 
-	conn, err = grpc.Dial(endpoint, grpcOpts...)
+	ctx := context.Background()
+	if cfg.dialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.dialTimeout)
+		defer cancel()
+	}
+	conn, err = grpc.DialContext(ctx, endpoint, grpcOpts...)
 	if err != nil {
 		return nil, fmt.Errorf("CLIENT: openOne(%q): Failed to dial. %w", endpoint, err)
 	}
@@ -49,7 +70,7 @@ func openOne(endpoint string, certPath string, token string) (_ *Client, err err
 
 	grpcClient := pb.NewRequestHandlerClient(conn)
 
-	return &Client{conn, grpcClient, "", certPath, token, 0}, nil
+	return &Client{conn: conn, grpcClient: grpcClient, certPath: certPath, token: token, cfg: cfg}, nil
 }
 
 // Close closes a Client's connection.
@@ -0,0 +1,111 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Option customizes how the Open* group of functions connect to ParallelCore
+// endpoints. Options are remembered by the returned Client(s), so connections
+// re-established later (for example by Renew) are configured the same way.
+type Option func(*openConfig)
+
+type openConfig struct {
+	dialer          func(ctx context.Context, endpoint string) (net.Conn, error)
+	proxyURL        string
+	dialTimeout     time.Duration
+	refreshInterval time.Duration
+	resolver        EndpointResolver
+
+	tokenVerificationKey crypto.PublicKey
+	clockSkew            time.Duration
+
+	tokenStore    TokenStore
+	tokenStoreKey string
+
+	credentialProvider CredentialProvider
+
+	unaryInterceptors  []grpc.UnaryClientInterceptor
+	streamInterceptors []grpc.StreamClientInterceptor
+	// connHooks wrap every connection dialContext establishes, in order.
+	connHooks []func(endpoint string, conn net.Conn) net.Conn
+	replay    *Cassette
+
+	codecs map[string]Codec
+
+	pollInterval time.Duration
+	sealedStatus *int
+}
+
+func newOpenConfig(opts []Option) *openConfig {
+	cfg := &openConfig{}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg
+}
+
+// WithProxy makes connections go through the proxy identified by proxyURL instead
+// of the one (if any) configured in the HTTPS_PROXY and NO_PROXY environment variables.
+//
+// Supported schemes are:
+//  - http://[user:password@]host:port: HTTP CONNECT proxy.
+//  - https://[user:password@]host:port: HTTP CONNECT proxy reached over TLS.
+//  - socks5://[user:password@]host:port: SOCKS5 proxy.
+//
+// PROXY_DIRECT disables proxying altogether, ignoring the environment.
+func WithProxy(proxyURL string) Option {
+	return func(cfg *openConfig) {
+		cfg.proxyURL = proxyURL
+	}
+}
+
+// WithContextDialer makes connections use dialer to reach endpoints instead of dialing
+// them directly (or through a proxy, see WithProxy). It is mainly useful to connect
+// to in-process servers, such as the fake server in the pcoretest package.
+func WithContextDialer(dialer func(ctx context.Context, endpoint string) (net.Conn, error)) Option {
+	return func(cfg *openConfig) {
+		cfg.dialer = dialer
+	}
+}
+
+// WithDialTimeout bounds how long opening a connection to a single endpoint may take.
+// By default, the Open* group of functions wait until the endpoint becomes reachable.
+func WithDialTimeout(timeout time.Duration) Option {
+	return func(cfg *openConfig) {
+		cfg.dialTimeout = timeout
+	}
+}
+
+// WithRefreshInterval sets how often a Pool asks its EndpointResolver for the current
+// set of endpoints. The default is DEFAULT_POOL_REFRESH_INTERVAL.
+func WithRefreshInterval(interval time.Duration) Option {
+	return func(cfg *openConfig) {
+		cfg.refreshInterval = interval
+	}
+}
+
+// WithTokenVerificationKey makes Client.TokenClaims verify the signature of the token
+// with key. See ParseTokenClaims for the supported key types.
+func WithTokenVerificationKey(key crypto.PublicKey) Option {
+	return func(cfg *openConfig) {
+		cfg.tokenVerificationKey = key
+	}
+}
+
+// WithClockSkew sets how much difference between the local clock and the clock of
+// ParallelCore engines Client.TokenClaims tolerates. The default is DEFAULT_CLOCK_SKEW.
+func WithClockSkew(skew time.Duration) Option {
+	return func(cfg *openConfig) {
+		cfg.clockSkew = skew
+	}
+}
@@ -0,0 +1,488 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// The files of an Outbox, in its directory.
+const (
+	outboxLogFile        = "outbox.wal"
+	outboxCommittedFile  = "committed.jsonl"
+	outboxDeadLetterFile = "dead-letter.jsonl"
+)
+
+var ErrOutboxClosed = errors.New("CLIENT: Outbox closed")
+
+// OutboxEntry is an invocation stored in an Outbox.
+type OutboxEntry struct {
+	ID                uint64    `json:"id"`
+	SmartContractSpec string    `json:"spec"`
+	Args              []byte    `json:"args"`
+	Enqueued          time.Time `json:"enqueued"`
+	// Attempts counts the invocations made, including before the Outbox was last
+	// opened (but for one in progress when its process stopped).
+	Attempts int    `json:"attempts,omitempty"`
+	CommitID string `json:"commitId,omitempty"`
+	Payload  []byte `json:"payload,omitempty"`
+	// Error is the error that dead-lettered the entry.
+	Error string `json:"error,omitempty"`
+}
+
+// OutboxOptions configures an Outbox.
+type OutboxOptions struct {
+	// MaxAttempts dead-letters entries whose invocation failed with a transport error
+	// that many times. Zero retries them until they succeed.
+	MaxAttempts int
+	// Backoff is the delay before the first retry of an entry, doubled before every
+	// other up to MaxBackoff. DEFAULT_OUTBOX_BACKOFF and DEFAULT_OUTBOX_MAX_BACKOFF if
+	// zero.
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// JournalSize bounds the number of entries kept in the journal of committed
+	// entries, and in that of dead-lettered ones, dropping the oldest: journals are
+	// trimmed when the Outbox is opened, and once they hold twice as many entries.
+	// DEFAULT_OUTBOX_JOURNAL_SIZE if zero; negative keeps every entry.
+	JournalSize int
+	// OnCommit and OnDeadLetter, if set, are called by the Outbox's worker once an
+	// entry is committed or dead-lettered.
+	OnCommit     func(OutboxEntry)
+	OnDeadLetter func(OutboxEntry)
+}
+
+// Outbox stores invocations on disk, and carries them out in the background with
+// IdentifiedInvoke, in the order they were enqueued. It lets applications that may
+// lose connectivity to ParallelCore queue invocations rather than drop them.
+//
+// An Outbox keeps its state in a directory: a write-ahead log of the entries enqueued
+// (outbox.wal), and journals of the entries committed (committed.jsonl, with their
+// commit IDs) and dead-lettered (dead-letter.jsonl), holding the most recent ones
+// (see OutboxOptions.JournalSize). Entries are written to disk before Enqueue returns,
+// and entries left pending by a stopped process are carried out once the directory is
+// opened again.
+//
+// Invocations failing with a transport error are retried with exponential backoff,
+// blocking the entries behind them; invocations failing with an error reported by
+// ParallelCore are dead-lettered. Entries are carried out at least once: one being
+// invoked when the process stops is invoked again when the outbox is next opened.
+type Outbox struct {
+	dir     string
+	invoker Invoker
+	opts    OutboxOptions
+
+	mu      sync.Mutex
+	log     *os.File
+	pending []OutboxEntry
+	nextID  uint64
+	closed  bool
+	// changed is closed and replaced whenever pending changes.
+	changed chan struct{}
+
+	// journalSize counts the entries of the journals, by name. Only the worker uses it
+	// once the outbox is open.
+	journalSize map[string]int
+
+	stop      chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// OpenOutbox opens the Outbox stored in dir, creating dir (with mode 0700) if needed,
+// and starts carrying out its pending entries through invoker.
+func OpenOutbox(dir string, invoker Invoker, opts OutboxOptions) (*Outbox, error) {
+	if opts.Backoff <= 0 {
+		opts.Backoff = DEFAULT_OUTBOX_BACKOFF
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DEFAULT_OUTBOX_MAX_BACKOFF
+	}
+	if opts.JournalSize == 0 {
+		opts.JournalSize = DEFAULT_OUTBOX_JOURNAL_SIZE
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("CLIENT: Outbox(%q): %w", dir, err)
+	}
+	outbox := &Outbox{
+		dir:     dir,
+		invoker: invoker,
+		opts:    opts,
+		changed: make(chan struct{}),
+		stop:    make(chan struct{}),
+		done:    make(chan struct{}),
+
+		journalSize: make(map[string]int),
+	}
+	if err := outbox.recover(); err != nil {
+		return nil, fmt.Errorf("CLIENT: Outbox(%q): %w", dir, err)
+	}
+	go outbox.work()
+	return outbox, nil
+}
+
+// recover rebuilds the pending entries from the outbox's files, compacts its log down
+// to the pending entries, then rewrites its journals without any line cut short by a
+// crash, trimmed. The log is compacted first, so that it never holds entries trimmed
+// from the journals.
+func (outbox *Outbox) recover() error {
+	enqueued, err := readJournal(filepath.Join(outbox.dir, outboxLogFile))
+	if err != nil {
+		return err
+	}
+	finished := make(map[uint64]bool)
+	journals := make(map[string][]OutboxEntry)
+	for _, name := range []string{outboxCommittedFile, outboxDeadLetterFile} {
+		entries, err := readJournal(filepath.Join(outbox.dir, name))
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			finished[entry.ID] = true
+			if entry.ID >= outbox.nextID {
+				outbox.nextID = entry.ID + 1
+			}
+		}
+		journals[name] = entries
+	}
+	// The log holds an entry once enqueued, then again every time its attempts are
+	// counted: the last one is current.
+	index := make(map[uint64]int)
+	for _, entry := range enqueued {
+		if entry.ID >= outbox.nextID {
+			outbox.nextID = entry.ID + 1
+		}
+		if finished[entry.ID] {
+			continue
+		}
+		if i, ok := index[entry.ID]; ok {
+			outbox.pending[i] = entry
+			continue
+		}
+		index[entry.ID] = len(outbox.pending)
+		outbox.pending = append(outbox.pending, entry)
+	}
+	if outbox.nextID == 0 {
+		outbox.nextID = 1
+	}
+
+	if outbox.log, err = replaceJournal(outbox.dir, outboxLogFile, outbox.pending); err != nil {
+		return err
+	}
+	for name, entries := range journals {
+		if err = outbox.trimJournal(name, entries); err != nil {
+			outbox.log.Close()
+			return err
+		}
+	}
+	return nil
+}
+
+// trimJournal replaces the journal name with one holding its last opts.JournalSize
+// entries, of entries. The log must not hold the entries dropped.
+func (outbox *Outbox) trimJournal(name string, entries []OutboxEntry) error {
+	if keep := outbox.opts.JournalSize; keep > 0 && len(entries) > keep {
+		entries = entries[len(entries)-keep:]
+	}
+	file, err := replaceJournal(outbox.dir, name, entries)
+	if err != nil {
+		return err
+	}
+	file.Close()
+	outbox.journalSize[name] = len(entries)
+	return nil
+}
+
+// compactLog replaces the outbox's log with one holding pending, the entries still
+// pending. It must be called with outbox.mu held.
+func (outbox *Outbox) compactLog(pending []OutboxEntry) error {
+	log, err := replaceJournal(outbox.dir, outboxLogFile, pending)
+	if err != nil {
+		return err
+	}
+	outbox.log.Close()
+	outbox.log = log
+	return nil
+}
+
+// Enqueue stores an invocation of the smart contract identified by smartContractSpec
+// with args, and returns its entry ID once it is on disk.
+func (outbox *Outbox) Enqueue(smartContractSpec string, args []byte) (uint64, error) {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+
+	if outbox.closed {
+		return 0, ErrOutboxClosed
+	}
+	entry := OutboxEntry{ID: outbox.nextID, SmartContractSpec: smartContractSpec, Args: append([]byte(nil), args...), Enqueued: time.Now()}
+	if err := writeJournal(outbox.log, entry, true); err != nil {
+		return 0, fmt.Errorf("CLIENT: Outbox(%q): %w", outbox.dir, err)
+	}
+	outbox.nextID++
+	outbox.pending = append(outbox.pending, entry)
+	outbox.notify()
+	return entry.ID, nil
+}
+
+// Pending returns the entries not yet committed nor dead-lettered, in order.
+func (outbox *Outbox) Pending() []OutboxEntry {
+	outbox.mu.Lock()
+	defer outbox.mu.Unlock()
+	return append([]OutboxEntry(nil), outbox.pending...)
+}
+
+// Committed returns the entries committed so far, with their commit IDs, as recorded
+// in the outbox's journal (see OutboxOptions.JournalSize).
+func (outbox *Outbox) Committed() ([]OutboxEntry, error) {
+	return readJournal(filepath.Join(outbox.dir, outboxCommittedFile))
+}
+
+// DeadLetters returns the entries dead-lettered so far, with their errors, as recorded
+// in the outbox's journal (see OutboxOptions.JournalSize).
+func (outbox *Outbox) DeadLetters() ([]OutboxEntry, error) {
+	return readJournal(filepath.Join(outbox.dir, outboxDeadLetterFile))
+}
+
+// Drain waits until no entries are pending, or ctx is done.
+func (outbox *Outbox) Drain(ctx context.Context) error {
+	for {
+		outbox.mu.Lock()
+		empty, changed := len(outbox.pending) == 0, outbox.changed
+		outbox.mu.Unlock()
+		if empty {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Close stops carrying out entries, waiting for the invocation in progress, if any.
+// Pending entries are carried out once the outbox's directory is opened again.
+func (outbox *Outbox) Close() {
+	outbox.closeOnce.Do(func() {
+		close(outbox.stop)
+		<-outbox.done
+
+		outbox.mu.Lock()
+		outbox.closed = true
+		outbox.log.Close()
+		outbox.mu.Unlock()
+	})
+}
+
+// notify wakes up the goroutines waiting for pending to change. It must be called with
+// outbox.mu held.
+func (outbox *Outbox) notify() {
+	close(outbox.changed)
+	outbox.changed = make(chan struct{})
+}
+
+// work carries out the pending entries, in order, until the outbox is closed.
+func (outbox *Outbox) work() {
+	defer close(outbox.done)
+
+	var lastID uint64
+	attempts, backoff := 0, outbox.opts.Backoff
+	for {
+		outbox.mu.Lock()
+		changed, ok := outbox.changed, len(outbox.pending) != 0
+		var entry OutboxEntry
+		if ok {
+			entry = outbox.pending[0]
+		}
+		outbox.mu.Unlock()
+
+		if !ok {
+			select {
+			case <-changed:
+				continue
+			case <-outbox.stop:
+				return
+			}
+		}
+		if entry.ID != lastID {
+			lastID, attempts, backoff = entry.ID, entry.Attempts, outbox.opts.Backoff
+		}
+
+		attempts++
+		payload, commitID, err := outbox.invoker.IdentifiedInvoke(entry.SmartContractSpec, entry.Args)
+		outbox.mu.Lock()
+		outbox.pending[0].Attempts = attempts
+		outbox.mu.Unlock()
+
+		entry.Attempts = attempts
+		switch {
+		case err == nil:
+			entry.CommitID, entry.Payload = commitID, payload
+			outbox.finish(entry, outboxCommittedFile, outbox.opts.OnCommit)
+			continue
+		case !transportError(err) || (outbox.opts.MaxAttempts > 0 && attempts >= outbox.opts.MaxAttempts):
+			entry.Error = err.Error()
+			outbox.finish(entry, outboxDeadLetterFile, outbox.opts.OnDeadLetter)
+			continue
+		}
+
+		// A count that cannot be logged is logged with the next attempt: it is only lost
+		// if the process stops first.
+		outbox.mu.Lock()
+		writeJournal(outbox.log, entry, true)
+		outbox.mu.Unlock()
+
+		select {
+		case <-time.After(backoff):
+			if backoff *= 2; backoff > outbox.opts.MaxBackoff {
+				backoff = outbox.opts.MaxBackoff
+			}
+		case <-outbox.stop:
+			return
+		}
+	}
+}
+
+// finish records entry, the first pending entry, in the journal name, calls callback,
+// then removes entry from the pending entries.
+func (outbox *Outbox) finish(entry OutboxEntry, name string, callback func(OutboxEntry)) {
+	for {
+		err := appendJournal(filepath.Join(outbox.dir, name), entry)
+		if err == nil {
+			break
+		}
+		// Leaving the entry pending would invoke it again: retry until the disk recovers.
+		select {
+		case <-time.After(outbox.opts.Backoff):
+		case <-outbox.stop:
+			return
+		}
+	}
+
+	// The log still holds the entries finished since it was last compacted: it is
+	// compacted before they are trimmed from the journal, lest they be invoked again
+	// once the outbox is opened again. A journal that cannot be trimmed is trimmed once
+	// the next entry is recorded.
+	outbox.journalSize[name]++
+	if keep := outbox.opts.JournalSize; keep > 0 && outbox.journalSize[name] >= 2*keep {
+		outbox.mu.Lock()
+		err := outbox.compactLog(outbox.pending[1:])
+		outbox.mu.Unlock()
+		if err == nil {
+			if entries, err := readJournal(filepath.Join(outbox.dir, name)); err == nil {
+				outbox.trimJournal(name, entries)
+			}
+		}
+	}
+
+	if callback != nil {
+		callback(entry)
+	}
+	outbox.mu.Lock()
+	outbox.pending = outbox.pending[1:]
+	outbox.notify()
+	outbox.mu.Unlock()
+}
+
+// replaceJournal atomically replaces the journal name of dir with one holding entries,
+// and returns it open for appending.
+func replaceJournal(dir string, name string, entries []OutboxEntry) (*os.File, error) {
+	// ioutil.TempFile creates files with mode 0600.
+	file, err := ioutil.TempFile(dir, ".outbox-*")
+	if err != nil {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if err = writeJournal(file, entry, false); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(dir, name))
+	}
+	if err == nil {
+		err = syncDir(dir)
+	}
+	if err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return file, nil
+}
+
+func appendJournal(path string, entry OutboxEntry) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	err = writeJournal(file, entry, true)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// writeJournal writes entry to file as a line of JSON, and syncs file if sync is set.
+func writeJournal(file *os.File, entry OutboxEntry, sync bool) error {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	if _, err = file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if sync {
+		return file.Sync()
+	}
+	return nil
+}
+
+// readJournal reads the entries of the journal at path, which may not exist. A last
+// line cut short by a crash is ignored.
+func readJournal(path string) ([]OutboxEntry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var entries []OutboxEntry
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if line = bytes.TrimSpace(line); len(line) == 0 {
+			continue
+		}
+		var entry OutboxEntry
+		if err = json.Unmarshal(line, &entry); err != nil {
+			return nil, fmt.Errorf("%s: %w", filepath.Base(path), err)
+		}
+		entries = append(entries, entry)
+	}
+}
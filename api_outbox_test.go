@@ -0,0 +1,261 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+
+	"google.golang.org/grpc/codes"
+)
+
+func TestOutbox(t *testing.T) {
+	_, server := newCounterServer(t)
+	dir := tempDir(t)
+
+	// Offline: every invocation fails, and entries stay pending.
+	offline, err := server.Open(pcoretest.RootID, pcoretest.RootPassword,
+		sdk.WithFaultInjector(sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"IdentifiedInvoke"}, Code: codes.Unavailable})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offline.Close()
+	outbox, err := sdk.OpenOutbox(dir, offline, sdk.OutboxOptions{Backoff: time.Millisecond, MaxBackoff: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, err = outbox.Enqueue("counter-v1", []byte(fmt.Sprintf(`{"action":"set","data":"key%d"}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err = outbox.Enqueue("counter-v1", []byte(`{"action":"explode"}`)); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	outbox.Close()
+	if _, err = outbox.Enqueue("counter-v1", nil); err != sdk.ErrOutboxClosed {
+		t.Fatalf("Enqueue() after Close() = %v, want ErrOutboxClosed", err)
+	}
+	if pending := outbox.Pending(); len(pending) != 4 || pending[0].Attempts == 0 {
+		t.Fatalf("Pending() = %+v, want 4 attempted entries", pending)
+	}
+
+	// Back online, in a new process: pending entries are carried out in order.
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword,
+		sdk.WithFaultInjector(sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"IdentifiedInvoke"}, Times: 2, Code: codes.Unavailable})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	var dead []sdk.OutboxEntry
+	outbox, err = sdk.OpenOutbox(dir, client, sdk.OutboxOptions{
+		Backoff:      time.Millisecond,
+		OnDeadLetter: func(entry sdk.OutboxEntry) { dead = append(dead, entry) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outbox.Close()
+	id, err := outbox.Enqueue("counter-v1", []byte(`{"action":"set","data":"key3"}`))
+	if err != nil || id != 5 {
+		t.Fatalf("Enqueue() = %d, %v, want entry 5", id, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = outbox.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	committed, err := outbox.Committed()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(committed) != 4 {
+		t.Fatalf("Committed() = %+v, want 4 entries", committed)
+	}
+	for i, entry := range committed {
+		if entry.CommitID == "" || entry.ID != []uint64{1, 2, 3, 5}[i] {
+			t.Errorf("committed entry %d = %+v", i, entry)
+		}
+		if _, ok := server.Value("counter", fmt.Sprintf("key%d", i)); !ok {
+			t.Errorf("key%d not set", i)
+		}
+	}
+	letters, err := outbox.DeadLetters()
+	if err != nil || len(letters) != 1 || letters[0].ID != 4 || letters[0].Error == "" || len(dead) != 1 {
+		t.Fatalf("DeadLetters() = %+v, %v, want entry 4", letters, err)
+	}
+
+	// Nothing is left to carry out once reopened.
+	outbox.Close()
+	outbox, err = sdk.OpenOutbox(dir, client, sdk.OutboxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outbox.Close()
+	if pending := outbox.Pending(); len(pending) != 0 {
+		t.Fatalf("Pending() after reopening = %+v", pending)
+	}
+}
+
+func TestOutboxMaxAttemptsAcrossRestarts(t *testing.T) {
+	_, server := newCounterServer(t)
+	dir := tempDir(t)
+	offline, err := server.Open(pcoretest.RootID, pcoretest.RootPassword,
+		sdk.WithFaultInjector(sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"IdentifiedInvoke"}, Code: codes.Unavailable})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer offline.Close()
+	// Retries are an hour apart: only restarts make new attempts.
+	opts := sdk.OutboxOptions{MaxAttempts: 2, Backoff: time.Hour}
+
+	outbox, err := sdk.OpenOutbox(dir, offline, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = outbox.Enqueue("counter-v1", []byte(`{"action":"set","data":"key"}`)); err != nil {
+		t.Fatal(err)
+	}
+	for deadline := time.Now().Add(5 * time.Second); outbox.Pending()[0].Attempts == 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("the entry was not attempted")
+		}
+	}
+	outbox.Close()
+
+	outbox, err = sdk.OpenOutbox(dir, offline, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outbox.Close()
+	if pending := outbox.Pending(); len(pending) != 1 || pending[0].Attempts != 1 {
+		t.Fatalf("Pending() after reopening = %+v, want 1 attempt", pending)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = outbox.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if letters, err := outbox.DeadLetters(); err != nil || len(letters) != 1 || letters[0].Attempts != 2 {
+		t.Fatalf("DeadLetters() = %+v, %v, want the entry after 2 attempts", letters, err)
+	}
+}
+
+func TestOutboxJournalSize(t *testing.T) {
+	client, _ := newCounterServer(t)
+	dir := tempDir(t)
+	opts := sdk.OutboxOptions{JournalSize: 2}
+	outbox, err := sdk.OpenOutbox(dir, client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if _, err = outbox.Enqueue("counter-v1", []byte(fmt.Sprintf(`{"action":"set","data":"key%d"}`, i))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err = outbox.Drain(ctx); err != nil {
+		t.Fatal(err)
+	}
+	// The journal was trimmed to 2 entries once it held 4.
+	if committed, err := outbox.Committed(); err != nil || len(committed) != 3 {
+		t.Fatalf("Committed() = %+v, %v, want 3 entries", committed, err)
+	}
+	outbox.Close()
+
+	outbox, err = sdk.OpenOutbox(dir, client, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer outbox.Close()
+	committed, err := outbox.Committed()
+	if err != nil || len(committed) != 2 || committed[0].ID != 4 || committed[1].ID != 5 {
+		t.Fatalf("Committed() after reopening = %+v, %v, want entries 4 and 5", committed, err)
+	}
+	if id, err := outbox.Enqueue("counter-v1", []byte(`{"action":"get","data":"key0"}`)); err != nil || id != 6 {
+		t.Fatalf("Enqueue() = %d, %v, want entry 6", id, err)
+	}
+}
+
+func TestOutboxJournalSizeAcrossRestarts(t *testing.T) {
+	client, server := pcoretest.NewClient(t)
+	var mu sync.Mutex
+	calls := make(map[string]int)
+	server.AddSmartContract("tally", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("tally-v1", func(sc *pcoretest.Context) ([]byte, error) {
+		mu.Lock()
+		calls[string(sc.Args)]++
+		mu.Unlock()
+		sc.Put(string(sc.Args), []byte("x"))
+		return nil, nil
+	})
+	dir := tempDir(t)
+	opts := sdk.OutboxOptions{JournalSize: 2}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// Entries trimmed from the journal are not invoked again once reopened.
+	for round := 0; round < 2; round++ {
+		outbox, err := sdk.OpenOutbox(dir, client, opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if round == 0 {
+			for _, key := range []string{"a", "b", "c", "d", "e", "f"} {
+				if _, err = outbox.Enqueue("tally-v1", []byte(key)); err != nil {
+					t.Fatal(err)
+				}
+			}
+		}
+		if err = outbox.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		outbox.Close()
+	}
+	mu.Lock()
+	for key, n := range calls {
+		if n != 1 {
+			t.Errorf("entry %s invoked %d times", key, n)
+		}
+	}
+	mu.Unlock()
+
+	// A journal line cut short by a crash is dropped, rather than appended to.
+	file, err := os.OpenFile(filepath.Join(dir, "committed.jsonl"), os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id":7,"spec":"tal`)
+	file.Close()
+	for i := 0; i < 2; i++ {
+		outbox, err := sdk.OpenOutbox(dir, client, opts)
+		if err != nil {
+			t.Fatalf("OpenOutbox() after a torn line, %d: %v", i, err)
+		}
+		if _, err = outbox.Enqueue("tally-v1", []byte(fmt.Sprintf("g%d", i))); err != nil {
+			t.Fatal(err)
+		}
+		if err = outbox.Drain(ctx); err != nil {
+			t.Fatal(err)
+		}
+		outbox.Close()
+	}
+	if n := len(calls); n != 8 {
+		t.Errorf("%d entries invoked, want 8", n)
+	}
+}
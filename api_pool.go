@@ -0,0 +1,436 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Pool keeps one Client per endpoint returned by an EndpointResolver, similar to the
+// []*Client returned by OpenMany. Unlike OpenMany, a Pool periodically asks its resolver
+// for the current endpoints, connecting to new ParallelCore nodes and closing the
+// connections to retired ones.
+//
+// All Clients in a Pool share one authentication token. Clients handed out by a Pool
+// must not be closed or renewed individually; use Pool.Renew and Pool.Close instead.
+//
+// The Pool's methods keep the Client they call in use until the call returns: Clients
+// retired by Refresh, Renew or Close meanwhile are only closed then.
+type Pool struct {
+	resolver    EndpointResolver
+	credentials CredentialProvider
+	certPath    string
+	cfg         *openConfig
+
+	mu              sync.RWMutex
+	endpoints       []string
+	clients         map[string]*Client
+	token           string
+	expireTimestamp int64
+	lastError       error
+	next            uint64
+	closed          bool
+	// inUse counts the calls in progress on each Client; retiring holds the Clients to
+	// close once they have none.
+	inUse    map[*Client]int
+	retiring map[*Client]bool
+
+	refreshMu sync.Mutex
+	closeOnce sync.Once
+	onClose   func()
+	stop      chan struct{}
+	done      chan struct{}
+}
+
+// OpenPool authenticates against one of the endpoints returned by resolver and connects
+// to all of them. It fails only if no endpoint can be connected to; endpoints that cannot
+// be reached are retried on every refresh (see WithRefreshInterval).
+func OpenPool(resolver EndpointResolver, clientID string, credential string, certPath string, opts ...Option) (*Pool, error) {
+	return OpenPoolWithProvider(resolver, StaticCredentials{ClientID: clientID, Credential: credential}, certPath, opts...)
+}
+
+// OpenPoolWithProvider is similar to OpenPool, but takes the client ID and credential
+// from provider every time the pool needs to authenticate: when first opened, when a
+// refresh finds that the pool's token has expired, and when Renew finds that it can no
+// longer be renewed.
+func OpenPoolWithProvider(resolver EndpointResolver, provider CredentialProvider, certPath string, opts ...Option) (*Pool, error) {
+	pool := &Pool{
+		resolver:    resolver,
+		credentials: provider,
+		certPath:    certPath,
+		cfg:         newOpenConfig(opts),
+		clients:     make(map[string]*Client),
+		inUse:       make(map[*Client]int),
+		retiring:    make(map[*Client]bool),
+		stop:        make(chan struct{}),
+		done:        make(chan struct{}),
+	}
+	if err := pool.Refresh(); len(pool.Clients()) == 0 {
+		return nil, fmt.Errorf("CLIENT: OpenPool: Failed to open any client. Last error: %v", err)
+	}
+
+	interval := pool.cfg.refreshInterval
+	if interval <= 0 {
+		interval = DEFAULT_POOL_REFRESH_INTERVAL
+	}
+	go pool.refreshLoop(interval)
+	if err := pool.saveToken(); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return pool, nil
+}
+
+// ErrPoolClosed is the error of the calls made through a Pool once it is closed.
+var ErrPoolClosed = errors.New("CLIENT: Pool closed")
+
+// Any returns one of the Clients in the pool, rotating through them on every call.
+//
+// Unlike calls made with the Pool's methods, calls made on the returned Client fail if
+// Refresh, Renew or Close retires it meanwhile.
+func (pool *Pool) Any() (*Client, error) {
+	_, client, err := pool.pick()
+	return client, err
+}
+
+func (pool *Pool) pick() (string, *Client, error) {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return pool.pickLocked()
+}
+
+// acquire returns one of the Clients in the pool, as Any does, and keeps it from being
+// closed until release is called.
+func (pool *Pool) acquire() (client *Client, release func(), err error) {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	if _, client, err = pool.pickLocked(); err != nil {
+		return nil, nil, err
+	}
+	pool.inUse[client]++
+	return client, func() { pool.release(client) }, nil
+}
+
+func (pool *Pool) release(client *Client) {
+	pool.mu.Lock()
+	pool.inUse[client]--
+	idle := pool.inUse[client] == 0
+	if idle {
+		delete(pool.inUse, client)
+	}
+	retired := idle && pool.retiring[client]
+	if retired {
+		delete(pool.retiring, client)
+	}
+	pool.mu.Unlock()
+
+	if retired {
+		client.Close()
+	}
+}
+
+// retire returns those of clients that can be closed right away, and marks the others
+// to be closed once their calls return. It must be called with pool.mu held.
+func (pool *Pool) retire(clients []*Client) []*Client {
+	idle := make([]*Client, 0, len(clients))
+	for _, client := range clients {
+		if pool.inUse[client] > 0 {
+			pool.retiring[client] = true
+		} else {
+			idle = append(idle, client)
+		}
+	}
+	return idle
+}
+
+// pickLocked is pick, called with pool.mu held.
+func (pool *Pool) pickLocked() (string, *Client, error) {
+	if pool.closed {
+		return "", nil, ErrPoolClosed
+	}
+	for range pool.endpoints {
+		i := atomic.AddUint64(&pool.next, 1)
+		endpoint := pool.endpoints[i%uint64(len(pool.endpoints))]
+		if client, ok := pool.clients[endpoint]; ok {
+			return endpoint, client, nil
+		}
+	}
+	return "", nil, fmt.Errorf("CLIENT: Pool: No connected endpoint. Last error: %v", pool.lastError)
+}
+
+// client returns the Client connected to endpoint, or nil.
+func (pool *Pool) client(endpoint string) *Client {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return pool.clients[endpoint]
+}
+
+// Clients returns the currently connected Clients, in the order their endpoints were
+// last resolved.
+func (pool *Pool) Clients() []*Client {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+
+	clients := make([]*Client, 0, len(pool.clients))
+	for _, endpoint := range pool.endpoints {
+		if client, ok := pool.clients[endpoint]; ok {
+			clients = append(clients, client)
+		}
+	}
+	return clients
+}
+
+// Endpoints returns the endpoints last returned by the pool's resolver, whether or not
+// a connection to them could be established.
+func (pool *Pool) Endpoints() []string {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return append([]string(nil), pool.endpoints...)
+}
+
+// LastError returns the last error encountered while refreshing the pool, or nil.
+func (pool *Pool) LastError() error {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return pool.lastError
+}
+
+// GetToken returns the authentication token shared by the Clients in the pool.
+func (pool *Pool) GetToken() string {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return pool.token
+}
+
+// GetTokenExpTime returns the expiry of the token shared by the Clients in the pool.
+func (pool *Pool) GetTokenExpTime() int64 {
+	pool.mu.RLock()
+	defer pool.mu.RUnlock()
+	return pool.expireTimestamp
+}
+
+// Refresh asks the pool's resolver for the current endpoints right away, opening
+// connections to new endpoints and closing connections to endpoints that are gone.
+func (pool *Pool) Refresh() error {
+	pool.refreshMu.Lock()
+	defer pool.refreshMu.Unlock()
+
+	endpoints, err := pool.resolver.Resolve(context.Background())
+	if err != nil {
+		pool.setLastError(err)
+		return err
+	}
+	return pool.reconcile(endpoints, false)
+}
+
+// Renew renews the token shared by the Clients in the pool and reconnects every
+// Client with the new token. If no Client is connected, or if ParallelCore refuses to
+// renew the token because it has expired or is otherwise rejected, the pool
+// authenticates again with the credentials of its CredentialProvider.
+func (pool *Pool) Renew() error {
+	pool.refreshMu.Lock()
+	defer pool.refreshMu.Unlock()
+
+	token, expireTimestamp := "", int64(0)
+	client, err := pool.Any()
+	if err == nil {
+		token, expireTimestamp, err = client.renewToken()
+		if err != nil && !client.tokenRejected(err) {
+			return err
+		}
+	}
+	if err != nil {
+		// An empty token makes reconcile authenticate from scratch.
+		token, expireTimestamp = "", 0
+	}
+
+	pool.mu.Lock()
+	pool.token, pool.expireTimestamp = token, expireTimestamp
+	endpoints := pool.endpoints
+	pool.mu.Unlock()
+	if token != "" {
+		// Best effort, as for Client.Renew; reconcile saves the token it authenticates for.
+		pool.saveToken()
+	}
+	return pool.reconcile(endpoints, true)
+}
+
+// reconcile connects to the endpoints the pool is missing (or to all of them, if
+// reopen is set) and closes the connections to endpoints no longer wanted.
+func (pool *Pool) reconcile(endpoints []string, reopen bool) error {
+	pool.mu.RLock()
+	if pool.closed {
+		pool.mu.RUnlock()
+		return ErrPoolClosed
+	}
+	token := pool.token
+	if token != "" && pool.expiring() {
+		// Connections opened with the token would soon be refused: authenticate again,
+		// and move every Client to the new token.
+		token, reopen = "", true
+	}
+	missing := make([]string, 0)
+	for _, endpoint := range endpoints {
+		if _, ok := pool.clients[endpoint]; reopen || !ok {
+			missing = append(missing, endpoint)
+		}
+	}
+	pool.mu.RUnlock()
+
+	// Connect outside the lock: dialing an unreachable endpoint may take a while.
+	opened := make(map[string]*Client)
+	var lastError error
+	for _, endpoint := range missing {
+		var err error
+		if token == "" {
+			token, err = pool.authenticate(endpoint)
+			if err != nil {
+				lastError = err
+				continue
+			}
+		}
+		client, err := openOne(endpoint, pool.certPath, token, pool.cfg)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		client.endpointSpecs = endpoint
+		opened[endpoint] = client
+	}
+
+	pool.mu.Lock()
+	if pool.closed {
+		// Close collected the pool's Clients while these were being opened.
+		pool.mu.Unlock()
+		for _, client := range opened {
+			client.Close()
+		}
+		return ErrPoolClosed
+	}
+	retired := make([]*Client, 0)
+	wanted := make(map[string]bool)
+	for _, endpoint := range endpoints {
+		wanted[endpoint] = true
+		if client, ok := opened[endpoint]; ok {
+			if old, ok := pool.clients[endpoint]; ok {
+				retired = append(retired, old)
+			}
+			client.expireTimestamp = pool.expireTimestamp
+			pool.clients[endpoint] = client
+		}
+	}
+	for endpoint, client := range pool.clients {
+		if !wanted[endpoint] {
+			delete(pool.clients, endpoint)
+			retired = append(retired, client)
+		}
+	}
+	pool.endpoints = endpoints
+	pool.lastError = lastError
+	retired = pool.retire(retired)
+	pool.mu.Unlock()
+
+	CloseMany(retired)
+	return lastError
+}
+
+// expiring reports whether the pool's token has expired, or expires within the clock
+// skew tolerated (see WithClockSkew). It must be called with pool.mu held.
+func (pool *Pool) expiring() bool {
+	skew := DEFAULT_CLOCK_SKEW
+	if pool.cfg.clockSkew > 0 {
+		skew = pool.cfg.clockSkew
+	}
+	return pool.expireTimestamp != 0 && !time.Now().Add(skew).Before(time.Unix(pool.expireTimestamp, 0))
+}
+
+// authenticate fetches a token from endpoint and stores it as the pool's token.
+func (pool *Pool) authenticate(endpoint string) (string, error) {
+	clientID, credential, err := pool.credentials.Credentials(context.Background())
+	if err != nil {
+		return "", fmt.Errorf("CLIENT: Pool(%q): %w", endpoint, err)
+	}
+	client, err := openOne(endpoint, pool.certPath, "", pool.cfg)
+	if err != nil {
+		return "", err
+	}
+	returnBytes, err := client.auth([]byte(clientID), []byte(credential))
+	client.Close()
+	if err != nil {
+		return "", fmt.Errorf("CLIENT: Pool(%q): Failed to auth. %w", endpoint, err)
+	}
+	token, expireTimestamp, err := parseTokenAndExpireTimestamp(string(returnBytes))
+	if err != nil {
+		return "", fmt.Errorf("CLIENT: Pool(%q): Failed to parse Token. %w", endpoint, err)
+	}
+
+	pool.mu.Lock()
+	pool.token, pool.expireTimestamp = token, expireTimestamp
+	pool.mu.Unlock()
+	pool.saveToken()
+	return token, nil
+}
+
+// saveToken saves the pool's token into the TokenStore it was opened with, if any.
+func (pool *Pool) saveToken() error {
+	if pool.cfg.tokenStore == nil {
+		return nil
+	}
+	pool.mu.RLock()
+	token, expireTimestamp := pool.token, pool.expireTimestamp
+	pool.mu.RUnlock()
+	return pool.cfg.tokenStore.Save(pool.cfg.tokenStoreKey, token, expireTimestamp)
+}
+
+// Close stops refreshing the pool and closes all of its Clients, once the calls made
+// with the Pool's methods return. Later calls fail with ErrPoolClosed.
+func (pool *Pool) Close() {
+	pool.closeOnce.Do(func() {
+		close(pool.stop)
+		<-pool.done
+		if pool.onClose != nil {
+			pool.onClose()
+		}
+	})
+
+	pool.mu.Lock()
+	pool.closed = true
+	clients := make([]*Client, 0, len(pool.clients))
+	for _, each := range pool.clients {
+		clients = append(clients, each)
+	}
+	pool.clients = make(map[string]*Client)
+	clients = pool.retire(clients)
+	pool.mu.Unlock()
+
+	CloseMany(clients)
+}
+
+func (pool *Pool) refreshLoop(interval time.Duration) {
+	defer close(pool.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-pool.stop:
+			return
+		case <-ticker.C:
+			pool.Refresh()
+		}
+	}
+}
+
+func (pool *Pool) setLastError(err error) {
+	pool.mu.Lock()
+	pool.lastError = err
+	pool.mu.Unlock()
+}
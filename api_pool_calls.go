@@ -0,0 +1,405 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+// The methods below make a Pool usable wherever a Client is (see API): each one calls
+// the Client method of the same name on one of the pool's Clients, kept in use until
+// the call returns.
+
+// Invoke calls Client.Invoke on one of the pool's Clients.
+func (pool *Pool) Invoke(smartContractSpec string, args []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.Invoke(smartContractSpec, args)
+}
+
+// IdentifiedInvoke calls Client.IdentifiedInvoke on one of the pool's Clients.
+func (pool *Pool) IdentifiedInvoke(smartContractSpec string, args []byte) ([]byte, string, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, "", err
+	}
+	defer release()
+	return client.IdentifiedInvoke(smartContractSpec, args)
+}
+
+// InvokeArgs calls Client.InvokeArgs on one of the pool's Clients.
+func (pool *Pool) InvokeArgs(smartContractSpec string, args ...string) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.InvokeArgs(smartContractSpec, args...)
+}
+
+// InvokeValues calls Client.InvokeValues on one of the pool's Clients.
+func (pool *Pool) InvokeValues(smartContractSpec string, values ...interface{}) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.InvokeValues(smartContractSpec, values...)
+}
+
+// ListInvokableSC calls Client.ListInvokableSC on one of the pool's Clients.
+func (pool *Pool) ListInvokableSC() ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListInvokableSC()
+}
+
+// CreateClient calls Client.CreateClient on one of the pool's Clients.
+func (pool *Pool) CreateClient(clientDataJSON []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.CreateClient(clientDataJSON)
+}
+
+// CreateUser calls Client.CreateUser on one of the pool's Clients.
+func (pool *Pool) CreateUser(userID string, password string, roles []string, domains []string) (string, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return client.CreateUser(userID, password, roles, domains)
+}
+
+// UpdateClient calls Client.UpdateClient on one of the pool's Clients.
+func (pool *Pool) UpdateClient(clientDataJSON []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.UpdateClient(clientDataJSON)
+}
+
+// UpdateUser calls Client.UpdateUser on one of the pool's Clients.
+func (pool *Pool) UpdateUser(userID string, password string, roles []string, domains []string) (string, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return client.UpdateUser(userID, password, roles, domains)
+}
+
+// ListClient calls Client.ListClient on one of the pool's Clients.
+func (pool *Pool) ListClient(clientID []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListClient(clientID)
+}
+
+// GetUserInfo calls Client.GetUserInfo on one of the pool's Clients.
+func (pool *Pool) GetUserInfo(clientID string) (UserFullData, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return UserFullData{}, err
+	}
+	defer release()
+	return client.GetUserInfo(clientID)
+}
+
+// ListClients calls Client.ListClients on one of the pool's Clients.
+func (pool *Pool) ListClients(query []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListClients(query)
+}
+
+// GetUserInfos calls Client.GetUserInfos on one of the pool's Clients.
+func (pool *Pool) GetUserInfos(allDomains bool, domainName string) ([]UserFullDataWrapper, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.GetUserInfos(allDomains, domainName)
+}
+
+// RemoveClient calls Client.RemoveClient on one of the pool's Clients.
+func (pool *Pool) RemoveClient(clientDomainDataJSON []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.RemoveClient(clientDomainDataJSON)
+}
+
+// DeleteUser calls Client.DeleteUser on one of the pool's Clients.
+func (pool *Pool) DeleteUser(userID string, userDomainName string) (string, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return client.DeleteUser(userID, userDomainName)
+}
+
+// CheckApiAccess calls Client.CheckApiAccess on one of the pool's Clients.
+func (pool *Pool) CheckApiAccess(apiAccessControllerJSON []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.CheckApiAccess(apiAccessControllerJSON)
+}
+
+// ManageApiAccess calls Client.ManageApiAccess on one of the pool's Clients.
+func (pool *Pool) ManageApiAccess(in []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ManageApiAccess(in)
+}
+
+// CreateDomain calls Client.CreateDomain on one of the pool's Clients.
+func (pool *Pool) CreateDomain(domainName []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.CreateDomain(domainName)
+}
+
+// ListDomain calls Client.ListDomain on one of the pool's Clients.
+func (pool *Pool) ListDomain(domainName []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListDomain(domainName)
+}
+
+// ListManagedDomains calls Client.ListManagedDomains on one of the pool's Clients.
+func (pool *Pool) ListManagedDomains(userID []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListManagedDomains(userID)
+}
+
+// GrantDomainAdmin calls Client.GrantDomainAdmin on one of the pool's Clients.
+func (pool *Pool) GrantDomainAdmin(in []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.GrantDomainAdmin(in)
+}
+
+// RevokeDomainAdmin calls Client.RevokeDomainAdmin on one of the pool's Clients.
+func (pool *Pool) RevokeDomainAdmin(in []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.RevokeDomainAdmin(in)
+}
+
+// RegisterSmartContract calls Client.RegisterSmartContract on one of the pool's Clients.
+func (pool *Pool) RegisterSmartContract(scRegistration []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.RegisterSmartContract(scRegistration)
+}
+
+// ListSmartContract calls Client.ListSmartContract on one of the pool's Clients.
+func (pool *Pool) ListSmartContract(scName []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListSmartContract(scName)
+}
+
+// ListSmartContracts calls Client.ListSmartContracts on one of the pool's Clients.
+func (pool *Pool) ListSmartContracts(query []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListSmartContracts(query)
+}
+
+// GrantAccess calls Client.GrantAccess on one of the pool's Clients.
+func (pool *Pool) GrantAccess(clientAccessDataJSON []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.GrantAccess(clientAccessDataJSON)
+}
+
+// RevokeAccess calls Client.RevokeAccess on one of the pool's Clients.
+func (pool *Pool) RevokeAccess(clientAccessDataJSON []byte) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.RevokeAccess(clientAccessDataJSON)
+}
+
+// GetBlockchainSummaryJson calls Client.GetBlockchainSummaryJson on one of the pool's Clients.
+func (pool *Pool) GetBlockchainSummaryJson() ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.GetBlockchainSummaryJson()
+}
+
+// GetBlockchainSummary calls Client.GetBlockchainSummary on one of the pool's Clients.
+func (pool *Pool) GetBlockchainSummary() (BlockchainSummary, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return BlockchainSummary{}, err
+	}
+	defer release()
+	return client.GetBlockchainSummary()
+}
+
+// GetBlockDetailsJson calls Client.GetBlockDetailsJson on one of the pool's Clients.
+func (pool *Pool) GetBlockDetailsJson(chainID string, blockID string) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.GetBlockDetailsJson(chainID, blockID)
+}
+
+// CalculateBlockHash calls Client.CalculateBlockHash on one of the pool's Clients.
+func (pool *Pool) CalculateBlockHash(chainID string, blockID string) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.CalculateBlockHash(chainID, blockID)
+}
+
+// GetSmartContractTransactionJson calls Client.GetSmartContractTransactionJson on one of the pool's Clients.
+func (pool *Pool) GetSmartContractTransactionJson(transactionId string) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.GetSmartContractTransactionJson(transactionId)
+}
+
+// GetSmartContractTransactionMetadataJson calls Client.GetSmartContractTransactionMetadataJson on one of the pool's Clients.
+func (pool *Pool) GetSmartContractTransactionMetadataJson(transactionId string) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.GetSmartContractTransactionMetadataJson(transactionId)
+}
+
+// ListLatestTransactions calls Client.ListLatestTransactions on one of the pool's Clients.
+func (pool *Pool) ListLatestTransactions(count int) ([]byte, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListLatestTransactions(count)
+}
+
+// RequestForget calls Client.RequestForget on one of the pool's Clients.
+func (pool *Pool) RequestForget(txIds []string) (string, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return client.RequestForget(txIds)
+}
+
+// ApproveForget calls Client.ApproveForget on one of the pool's Clients.
+func (pool *Pool) ApproveForget(forgetRequestTxID string) (string, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	return client.ApproveForget(forgetRequestTxID)
+}
+
+// CommitForget calls Client.CommitForget on one of the pool's Clients.
+func (pool *Pool) CommitForget(forgetRequestTxID string, forgetApprovalTxID []string) (ForgetReport, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return ForgetReport{}, err
+	}
+	defer release()
+	return client.CommitForget(forgetRequestTxID, forgetApprovalTxID)
+}
+
+// ListForgetGroups calls Client.ListForgetGroups on one of the pool's Clients.
+func (pool *Pool) ListForgetGroups(txIds []string) ([]ForgetGroup, error) {
+	client, release, err := pool.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return client.ListForgetGroups(txIds)
+}
+
+// RegisterEventListener is similar to Client.RegisterEventListener, but listens over a
+// connection of its own to one of the pool's endpoints, since closing the returned
+// ListenerController closes the connection it listens over.
+func (pool *Pool) RegisterEventListener(scName string, eventFilter string) (*ListenerController, <-chan *EventWrapper, error) {
+	endpoint, _, err := pool.pick()
+	if err != nil {
+		return nil, nil, err
+	}
+	client, err := openOne(endpoint, pool.certPath, pool.GetToken(), pool.cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	return client.RegisterEventListener(scName, eventFilter)
+}
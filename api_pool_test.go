@@ -0,0 +1,87 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestPoolReauthenticatesOnceTokenExpires(t *testing.T) {
+	const node0, node1 = "node0.pcoretest.local:5000", "node1.pcoretest.local:5000"
+	_, server := newCounterServer(t)
+	server.SetEndpoints(node0, node1)
+	server.SetTokenTTL(time.Second)
+
+	resolver := &endpointList{}
+	resolver.set(node0)
+	pool, err := sdk.OpenPool(resolver, pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), append(server.Options(), sdk.WithClockSkew(time.Millisecond))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	token := pool.GetToken()
+
+	time.Sleep(1100 * time.Millisecond)
+	if _, err = pool.Invoke("counter-v1", []byte(`{"action":"get","data":"a"}`)); err == nil {
+		t.Fatal("expired token was accepted")
+	}
+
+	// Connecting to a new node re-authenticates, and moves every node to the new token.
+	resolver.set(node0, node1)
+	if err = pool.Refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if pool.GetToken() == token {
+		t.Fatal("Refresh kept the expired token")
+	}
+	clients := pool.Clients()
+	if len(clients) != 2 {
+		t.Fatalf("pool has %d clients, want 2", len(clients))
+	}
+	for i, client := range clients {
+		if _, err = client.Invoke("counter-v1", []byte(`{"action":"get","data":"a"}`)); err != nil {
+			t.Errorf("client %d: %v", i, err)
+		}
+	}
+}
+
+func TestPoolKeepsClientsInUse(t *testing.T) {
+	_, server := newCounterServer(t)
+	injector := sdk.NewFaultInjector(1, sdk.FaultRule{Methods: []string{"Invoke"}, Latency: 200 * time.Millisecond})
+	pool, err := sdk.OpenPool(sdk.StaticResolver(server.EndpointSpecs()), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(),
+		append(server.Options(), sdk.WithFaultInjector(injector))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Renew replaces, and Close closes, the Client carrying the invocation: it completes
+	// all the same.
+	done := make(chan error, 1)
+	go func() {
+		_, err := pool.Invoke("counter-v1", []byte(`{"action":"set","data":"alice"}`))
+		done <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if err = pool.Renew(); err != nil {
+		t.Fatal(err)
+	}
+	pool.Close()
+	if err = <-done; err != nil {
+		t.Fatalf("Invoke during Renew and Close = %v", err)
+	}
+
+	if _, err = pool.Invoke("counter-v1", []byte(`{"action":"get","data":"alice"}`)); !errors.Is(err, sdk.ErrPoolClosed) {
+		t.Errorf("Invoke after Close = %v, want ErrPoolClosed", err)
+	}
+	if err = pool.Refresh(); !errors.Is(err, sdk.ErrPoolClosed) || len(pool.Clients()) != 0 {
+		t.Errorf("Refresh after Close = %v, with %d Clients", err, len(pool.Clients()))
+	}
+}
@@ -11,7 +11,6 @@ import (
 	"fmt"
 	"io"
 	"regexp"
-	"time"
 
 	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
 
@@ -56,8 +55,10 @@ func (client *Client) RegisterEventListener(scName string, eventFilter string) (
 	}
 
 	// Establish stream connection first
-	stream, err := client.grpcClient.RegisterEventListener(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := client.grpcClient.RegisterEventListener(ctx)
 	if err != nil {
+		cancel()
 		client.Close()
 		return nil, nil, fmt.Errorf("CLIENT: %w", err)
 	}
@@ -65,6 +66,7 @@ func (client *Client) RegisterEventListener(scName string, eventFilter string) (
 	// Send the event listener parameters
 	err = stream.Send(&pb.Request{Payload: payloadBytes})
 	if err != nil {
+		cancel()
 		client.Close()
 		return nil, nil, fmt.Errorf("CLIENT: %w", err)
 	}
@@ -72,27 +74,30 @@ func (client *Client) RegisterEventListener(scName string, eventFilter string) (
 	// Receive a success message from server
 	resp, err := stream.Recv()
 	if err != nil {
+		cancel()
 		client.Close()
 		return nil, nil, fmt.Errorf("CLIENT: %w", err)
 	}
 	if resp.Error != nil {
+		cancel()
 		client.Close()
 		return nil, nil, fmt.Errorf("%v", resp.Error)
 	}
 	successMsg := string(resp.Payload)
 	if successMsg != "Successfully registered event listener." {
+		cancel()
 		client.Close()
 		return nil, nil, fmt.Errorf("CLIENT: Did not receive expected success message")
 	}
 
 	eventChannel := make(chan *EventWrapper)
-	streamAlive := true
-	go client.listenEvents(stream, eventChannel, &streamAlive)
+	done := make(chan struct{})
+	go client.listenEvents(stream, eventChannel, done)
 
-	return &ListenerController{eventChannel: eventChannel, streamAlive: &streamAlive, conn: client.conn}, eventChannel, nil
+	return &ListenerController{eventChannel: eventChannel, done: done, conn: client.conn, cancel: cancel}, eventChannel, nil
 }
 
-func (client *Client) listenEvents(stream pb.RequestHandler_RegisterEventListenerClient, eventChannel chan *EventWrapper, streamAlive *bool) {
+func (client *Client) listenEvents(stream pb.RequestHandler_RegisterEventListenerClient, eventChannel chan *EventWrapper, done chan struct{}) {
 	for {
 		resp, err := stream.Recv()
 		if err == io.EOF {
@@ -100,7 +105,7 @@ func (client *Client) listenEvents(stream pb.RequestHandler_RegisterEventListene
 				ScEvent: nil,
 				Error:   fmt.Errorf("CLIENT: Read io.EOF. Stream closed by server."),
 			}
-			closeEventListener(eventChannel, streamAlive)
+			closeEventListener(eventChannel, done)
 			return
 		}
 		if err != nil {
@@ -108,7 +113,7 @@ func (client *Client) listenEvents(stream pb.RequestHandler_RegisterEventListene
 				ScEvent: nil,
 				Error:   fmt.Errorf("CLIENT: %w", err),
 			}
-			closeEventListener(eventChannel, streamAlive)
+			closeEventListener(eventChannel, done)
 			return
 		}
 
@@ -118,7 +123,7 @@ func (client *Client) listenEvents(stream pb.RequestHandler_RegisterEventListene
 				ScEvent: nil,
 				Error:   fmt.Errorf("%v", resp.Error),
 			}
-			closeEventListener(eventChannel, streamAlive)
+			closeEventListener(eventChannel, done)
 			return
 		}
 
@@ -130,7 +135,7 @@ func (client *Client) listenEvents(stream pb.RequestHandler_RegisterEventListene
 				ScEvent: nil,
 				Error:   fmt.Errorf("CLIENT: %v", resp.Error),
 			}
-			closeEventListener(eventChannel, streamAlive)
+			closeEventListener(eventChannel, done)
 			return
 		}
 
@@ -140,30 +145,33 @@ func (client *Client) listenEvents(stream pb.RequestHandler_RegisterEventListene
 			Error:   nil,
 		}
 	}
-
-	closeEventListener(eventChannel, streamAlive)
 }
 
-func closeEventListener(eventChannel chan *EventWrapper, streamAlive *bool) {
+func closeEventListener(eventChannel chan *EventWrapper, done chan struct{}) {
 	close(eventChannel)
-	*streamAlive = false
+	close(done)
 }
 
 type ListenerController struct {
 	eventChannel chan *EventWrapper
 	conn         *grpc.ClientConn
-	streamAlive  *bool
+	cancel       context.CancelFunc
+	// done is closed once the listening goroutine has returned.
+	done chan struct{}
 }
 
 func (cc ListenerController) Close() {
-	cc.conn.Close()
-	for *cc.streamAlive {
+	// Session clients have no connection of their own; cancelling the stream suffices.
+	cc.cancel()
+	if cc.conn != nil {
+		cc.conn.Close()
+	}
+	// Drain the events the listening goroutine may be blocked sending.
+	for {
 		select {
-		case _ = <-cc.eventChannel:
-		default:
-			time.Sleep(time.Millisecond)
+		case <-cc.eventChannel:
+		case <-cc.done:
+			return
 		}
 	}
-
-	fmt.Println("Event Listener Removed.")
 }
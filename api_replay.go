@@ -0,0 +1,333 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Cassette holds the calls recorded by a Recorder, to be replayed by the Clients opened
+// with WithReplay, without a network.
+//
+// Every call made by a replaying Client is served by the first recorded call not yet
+// replayed with the same method and request (Auth calls match on client ID alone).
+// Calls without a match fail with codes.NotFound.
+type Cassette struct {
+	// Redact must be the Redact function of the Recorder that recorded the cassette,
+	// if any, so that requests can be matched against the recorded (redacted) ones.
+	Redact Redactor
+
+	mu       sync.Mutex
+	entries  []CassetteEntry
+	replayed []bool
+}
+
+// ReadCassette reads a cassette written by a Recorder from r.
+func ReadCassette(r io.Reader) (*Cassette, error) {
+	cassette := &Cassette{}
+	dec := json.NewDecoder(r)
+	for {
+		var entry CassetteEntry
+		err := dec.Decode(&entry)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("CLIENT: ReadCassette: entry %d: %w", len(cassette.entries)+1, err)
+		}
+		cassette.entries = append(cassette.entries, entry)
+	}
+	cassette.replayed = make([]bool, len(cassette.entries))
+	return cassette, nil
+}
+
+// LoadCassette reads the cassette stored in the file at path.
+func LoadCassette(path string) (*Cassette, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: LoadCassette: %w", err)
+	}
+	defer f.Close()
+	return ReadCassette(f)
+}
+
+// Unplayed returns the recorded calls that have not been replayed yet, so that tests
+// can check that a scenario made all the calls it was recorded with.
+func (cassette *Cassette) Unplayed() []CassetteEntry {
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+
+	unplayed := make([]CassetteEntry, 0)
+	for i, entry := range cassette.entries {
+		if !cassette.replayed[i] {
+			unplayed = append(unplayed, entry)
+		}
+	}
+	return unplayed
+}
+
+// WithReplay makes the Open* group of functions return Clients that replay cassette
+// instead of connecting to endpoints. The endpoints and certPath passed to Open* are
+// ignored.
+func WithReplay(cassette *Cassette) Option {
+	return func(cfg *openConfig) {
+		cfg.replay = cassette
+	}
+}
+
+func (cassette *Cassette) handlerClient() pb.RequestHandlerClient {
+	return replayHandlerClient{cassette}
+}
+
+// take marks the first recorded call matching probe as replayed, and returns it.
+func (cassette *Cassette) take(probe *CassetteEntry) (*CassetteEntry, error) {
+	redact(probe)
+	if cassette.Redact != nil {
+		cassette.Redact(probe)
+	}
+
+	cassette.mu.Lock()
+	defer cassette.mu.Unlock()
+	for i := range cassette.entries {
+		entry := &cassette.entries[i]
+		if !cassette.replayed[i] && entry.Method == probe.Method && entry.ClientID == probe.ClientID &&
+			bytes.Equal(entry.Request, probe.Request) && bytes.Equal(firstSent(entry), firstSent(probe)) {
+			cassette.replayed[i] = true
+			return entry, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "CLIENT: Cassette: no recorded %s call matches request %q", probe.Method, probe.Request)
+}
+
+func firstSent(entry *CassetteEntry) []byte {
+	for _, message := range entry.Stream {
+		if message.Sent != nil {
+			return message.Sent
+		}
+	}
+	return nil
+}
+
+func (entry *CassetteEntry) err() error {
+	if entry.Status != nil {
+		return status.Error(entry.Status.Code, entry.Status.Message)
+	}
+	return nil
+}
+
+func responseError(message string) []byte {
+	if message == "" {
+		return nil
+	}
+	return []byte(message)
+}
+
+// replayHandlerClient implements pb.RequestHandlerClient by replaying a Cassette.
+type replayHandlerClient struct {
+	cassette *Cassette
+}
+
+func (c replayHandlerClient) unary(method string, in *pb.Request) (*pb.Response, error) {
+	entry, err := c.cassette.take(&CassetteEntry{Method: method, Request: in.Payload})
+	if err != nil {
+		return nil, err
+	}
+	if err = entry.err(); err != nil {
+		return nil, err
+	}
+	return &pb.Response{Payload: entry.Response, Error: responseError(entry.Error)}, nil
+}
+
+func (c replayHandlerClient) Auth(ctx context.Context, in *pb.AuthRequest, opts ...grpc.CallOption) (*pb.Response, error) {
+	entry, err := c.cassette.take(&CassetteEntry{Method: "Auth", ClientID: string(in.ClientId)})
+	if err != nil {
+		return nil, err
+	}
+	if err = entry.err(); err != nil {
+		return nil, err
+	}
+	return &pb.Response{Payload: entry.Response, Error: responseError(entry.Error)}, nil
+}
+
+func (c replayHandlerClient) IdentifiedInvoke(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.IdentifiedResponse, error) {
+	entry, err := c.cassette.take(&CassetteEntry{Method: "IdentifiedInvoke", Request: in.Payload})
+	if err != nil {
+		return nil, err
+	}
+	if err = entry.err(); err != nil {
+		return nil, err
+	}
+	return &pb.IdentifiedResponse{Payload: entry.Response, Error: responseError(entry.Error), CommittedId: []byte(entry.CommitID)}, nil
+}
+
+func (c replayHandlerClient) RegisterEventListener(ctx context.Context, opts ...grpc.CallOption) (pb.RequestHandler_RegisterEventListenerClient, error) {
+	return &replayStream{cassette: c.cassette, ctx: ctx}, nil
+}
+
+func (c replayHandlerClient) Invoke(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("Invoke", in)
+}
+
+func (c replayHandlerClient) UserMan(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("UserMan", in)
+}
+
+func (c replayHandlerClient) SysMan(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("SysMan", in)
+}
+
+func (c replayHandlerClient) Renew(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("Renew", in)
+}
+
+func (c replayHandlerClient) Ping(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("Ping", in)
+}
+
+func (c replayHandlerClient) ManageApiAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("ManageApiAccess", in)
+}
+
+func (c replayHandlerClient) CheckApiAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("CheckApiAccess", in)
+}
+
+func (c replayHandlerClient) RegisterSmartContract(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("RegisterSmartContract", in)
+}
+
+func (c replayHandlerClient) ListSmartContract(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("ListSmartContract", in)
+}
+
+func (c replayHandlerClient) ListSmartContracts(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("ListSmartContracts", in)
+}
+
+func (c replayHandlerClient) GrantAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("GrantAccess", in)
+}
+
+func (c replayHandlerClient) RevokeAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("RevokeAccess", in)
+}
+
+func (c replayHandlerClient) CreateDomain(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("CreateDomain", in)
+}
+
+func (c replayHandlerClient) ListDomain(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("ListDomain", in)
+}
+
+func (c replayHandlerClient) ListManagedDomains(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("ListManagedDomains", in)
+}
+
+func (c replayHandlerClient) GrantDomainAdmin(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("GrantDomainAdmin", in)
+}
+
+func (c replayHandlerClient) RevokeDomainAdmin(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("RevokeDomainAdmin", in)
+}
+
+func (c replayHandlerClient) CreateClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("CreateClient", in)
+}
+
+func (c replayHandlerClient) UpdateClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("UpdateClient", in)
+}
+
+func (c replayHandlerClient) ListClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("ListClient", in)
+}
+
+func (c replayHandlerClient) ListClients(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("ListClients", in)
+}
+
+func (c replayHandlerClient) RemoveClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.unary("RemoveClient", in)
+}
+
+// replayStream replays a recorded RegisterEventListener stream, which is matched on the
+// first message sent over it.
+type replayStream struct {
+	cassette *Cassette
+	ctx      context.Context
+
+	entry *CassetteEntry
+	next  int
+}
+
+func (stream *replayStream) Send(in *pb.Request) error {
+	if stream.entry != nil {
+		return nil
+	}
+	entry, err := stream.cassette.take(&CassetteEntry{Method: "RegisterEventListener", Stream: []CassetteMessage{{Sent: in.Payload}}})
+	if err != nil {
+		return err
+	}
+	stream.entry = entry
+	return nil
+}
+
+func (stream *replayStream) Recv() (*pb.Response, error) {
+	if stream.entry == nil {
+		return nil, status.Error(codes.FailedPrecondition, "CLIENT: Cassette: stream received before sending")
+	}
+	for stream.next < len(stream.entry.Stream) {
+		message := stream.entry.Stream[stream.next]
+		stream.next++
+		if message.Sent == nil {
+			return &pb.Response{Payload: message.Received, Error: responseError(message.Error)}, nil
+		}
+	}
+
+	err := stream.entry.err()
+	if err == nil {
+		return nil, io.EOF
+	}
+	if code := status.Code(err); code == codes.Canceled || code == codes.DeadlineExceeded {
+		// The recording ended because the client gave up: wait for this client to do so too.
+		<-stream.ctx.Done()
+		return nil, status.FromContextError(stream.ctx.Err()).Err()
+	}
+	return nil, err
+}
+
+func (stream *replayStream) Header() (metadata.MD, error) { return metadata.MD{}, nil }
+func (stream *replayStream) Trailer() metadata.MD         { return metadata.MD{} }
+func (stream *replayStream) CloseSend() error             { return nil }
+func (stream *replayStream) Context() context.Context     { return stream.ctx }
+
+func (stream *replayStream) SendMsg(m interface{}) error {
+	return stream.Send(m.(*pb.Request))
+}
+
+func (stream *replayStream) RecvMsg(m interface{}) error {
+	response, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	*m.(*pb.Response) = *response
+	return nil
+}
@@ -0,0 +1,77 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	_, server := newCounterServer(t)
+
+	var cassette bytes.Buffer
+	recorder := sdk.NewRecorder(&cassette)
+	scenario := func(client *sdk.Client) (string, string) {
+		if _, err := client.CreateUser("alice", "secret", []string{"app"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		controller, events, err := client.RegisterEventListener("counter", ".*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, commitID, err := client.IdentifiedInvoke("counter-v1", []byte(`{"action":"set","data":"alice"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		var txID string
+		select {
+		case e := <-events:
+			txID = e.ScEvent.TxId
+		case <-time.After(5 * time.Second):
+			t.Fatal("no event received")
+		}
+		controller.Close()
+		return commitID, txID
+	}
+
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithRecorder(recorder))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordedCommitID, recordedTxID := scenario(client)
+	if err = recorder.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(cassette.String(), pcoretest.RootPassword) || strings.Contains(cassette.String(), "secret") {
+		t.Errorf("cassette is not redacted:\n%s", cassette.String())
+	}
+
+	replay, err := sdk.ReadCassette(&cassette)
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.Close()
+	replayed, err := sdk.OpenAny("unused:5000", pcoretest.RootID, "any", "", sdk.WithReplay(replay))
+	if err != nil {
+		t.Fatal(err)
+	}
+	commitID, txID := scenario(replayed)
+	if commitID != recordedCommitID || txID != recordedTxID {
+		t.Errorf("replayed %q, %q; recorded %q, %q", commitID, txID, recordedCommitID, recordedTxID)
+	}
+	if unplayed := replay.Unplayed(); len(unplayed) != 0 {
+		t.Errorf("Unplayed = %+v", unplayed)
+	}
+	if _, err = replayed.Invoke("counter-v1", nil); err == nil {
+		t.Error("unrecorded call succeeded")
+	}
+}
@@ -0,0 +1,162 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// EndpointResolver discovers the ParallelCore endpoints an application can connect to.
+//
+// Resolve is called every time a Pool refreshes its set of connections, and every time
+// a Client opened with OpenAnyFromResolver renews its connection, so implementations
+// should return the endpoints that are currently in service.
+type EndpointResolver interface {
+	Resolve(ctx context.Context) ([]string, error)
+}
+
+// StaticResolver resolves to a fixed list of endpoints. It takes in the same
+// space-delimited endpointSpecs the Open* group of functions use.
+type StaticResolver string
+
+// Resolve returns the endpoints in r.
+func (r StaticResolver) Resolve(ctx context.Context) ([]string, error) {
+	return splitEndpointSpecs(string(r)), nil
+}
+
+// DNSSRVResolver resolves endpoints from the DNS SRV records of
+// _Service._Proto.Name (for example _pcore._tcp.example.com).
+//
+// Endpoints are ordered by priority (lowest first), then by weight (highest first).
+type DNSSRVResolver struct {
+	Service string
+	Proto   string
+	Name    string
+
+	// Resolver is the DNS resolver to use. If nil, net.DefaultResolver is used.
+	Resolver *net.Resolver
+}
+
+// NewDNSSRVResolver returns a DNSSRVResolver for _service._proto.name.
+func NewDNSSRVResolver(service string, proto string, name string) *DNSSRVResolver {
+	return &DNSSRVResolver{Service: service, Proto: proto, Name: name}
+}
+
+// Resolve looks up the SRV records of r.
+func (r *DNSSRVResolver) Resolve(ctx context.Context) ([]string, error) {
+	resolver := r.Resolver
+	if resolver == nil {
+		resolver = net.DefaultResolver
+	}
+	_, records, err := resolver.LookupSRV(ctx, r.Service, r.Proto, r.Name)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: DNSSRVResolver(%q): %w", r.Name, err)
+	}
+	return srvEndpoints(records), nil
+}
+
+// srvEndpoints orders records by priority (lowest first), then by weight (highest
+// first), and returns their endpoints.
+func srvEndpoints(records []*net.SRV) []string {
+	sort.SliceStable(records, func(i, j int) bool {
+		if records[i].Priority != records[j].Priority {
+			return records[i].Priority < records[j].Priority
+		}
+		return records[i].Weight > records[j].Weight
+	})
+
+	endpoints := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		endpoints = append(endpoints, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+	return endpoints
+}
+
+// FileResolver resolves endpoints from a text file listing one or more endpoints
+// per line. Blank lines and lines starting with '#' are ignored.
+//
+// The file is re-read whenever its modification time or size changes, so endpoints
+// can be added and removed by rewriting the file.
+type FileResolver struct {
+	path string
+
+	mu        sync.Mutex
+	modTime   time.Time
+	size      int64
+	endpoints []string
+}
+
+// NewFileResolver returns a FileResolver reading from path.
+func NewFileResolver(path string) *FileResolver {
+	return &FileResolver{path: path}
+}
+
+// Resolve returns the endpoints listed in r's file.
+func (r *FileResolver) Resolve(ctx context.Context) ([]string, error) {
+	info, err := os.Stat(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: FileResolver(%q): %w", r.path, err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.endpoints != nil && info.ModTime().Equal(r.modTime) && info.Size() == r.size {
+		return append([]string(nil), r.endpoints...), nil
+	}
+
+	content, err := ioutil.ReadFile(r.path)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: FileResolver(%q): %w", r.path, err)
+	}
+	endpoints := make([]string, 0)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		endpoints = append(endpoints, strings.Fields(line)...)
+	}
+	r.modTime, r.size, r.endpoints = info.ModTime(), info.Size(), endpoints
+	return append([]string(nil), endpoints...), nil
+}
+
+// OpenAnyFromResolver is similar to OpenAny, but takes its endpoints from resolver
+// instead of an endpointSpecs string. The returned Client asks resolver again for
+// the current endpoints every time it is renewed (see Client.Renew).
+func OpenAnyFromResolver(resolver EndpointResolver, clientID string, credential string, certPath string, opts ...Option) (*Client, error) {
+	endpoints, err := resolver.Resolve(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	if len(endpoints) == 0 {
+		return nil, fmt.Errorf("CLIENT: OpenAnyFromResolver: Resolver returned no endpoints")
+	}
+	return OpenAny(strings.Join(endpoints, " "), clientID, credential, certPath, append(opts, withResolver(resolver))...)
+}
+
+func withResolver(resolver EndpointResolver) Option {
+	return func(cfg *openConfig) {
+		cfg.resolver = resolver
+	}
+}
+
+// splitEndpointSpecs splits a space-delimited endpointSpecs string, dropping empty entries.
+func splitEndpointSpecs(endpointSpecs string) []string {
+	return strings.Fields(endpointSpecs)
+}
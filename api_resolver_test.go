@@ -0,0 +1,117 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSRVEndpoints(t *testing.T) {
+	endpoints := srvEndpoints([]*net.SRV{
+		{Target: "backup.example.", Port: 5000, Priority: 20, Weight: 100},
+		{Target: "light.example.", Port: 5000, Priority: 10, Weight: 10},
+		{Target: "heavy.example.", Port: 5001, Priority: 10, Weight: 60},
+		{Target: "also-light.example", Port: 5002, Priority: 10, Weight: 10},
+	})
+	want := []string{"heavy.example:5001", "light.example:5000", "also-light.example:5002", "backup.example:5000"}
+	if !reflect.DeepEqual(endpoints, want) {
+		t.Errorf("srvEndpoints = %q, want %q", endpoints, want)
+	}
+	if endpoints := srvEndpoints(nil); len(endpoints) != 0 {
+		t.Errorf("srvEndpoints(nil) = %q", endpoints)
+	}
+}
+
+func TestFileResolver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "resolver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "endpoints")
+	resolver := NewFileResolver(path)
+	ctx := context.Background()
+
+	if _, err = resolver.Resolve(ctx); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Resolve of a missing file = %v", err)
+	}
+
+	write := func(content string, modTime time.Time) {
+		t.Helper()
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resolve := func() []string {
+		t.Helper()
+		endpoints, err := resolver.Resolve(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return endpoints
+	}
+
+	start := time.Now().Add(-time.Hour)
+	write("# nodes\nnode0:5000 node1:5000\n\n   node2:5000  \n#node3:5000\n", start)
+	if got, want := resolve(), []string{"node0:5000", "node1:5000", "node2:5000"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Resolve = %q, want %q", got, want)
+	}
+
+	// A file rewritten with the same size is re-read once its modification time changes.
+	write("node0:5000 node1:5000 node2:5000", start.Add(time.Second))
+	resolve()
+	write("nodeA:5000 nodeB:5000 nodeC:5000", start.Add(time.Second))
+	if got := resolve(); got[0] != "node0:5000" {
+		t.Fatalf("Resolve re-read an unchanged file: %q", got)
+	}
+	write("nodeA:5000 nodeB:5000 nodeC:5000", start.Add(2*time.Second))
+	if got, want := resolve(), []string{"nodeA:5000", "nodeB:5000", "nodeC:5000"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Resolve after touching = %q, want %q", got, want)
+	}
+	write("nodeA:5000", start.Add(2*time.Second))
+	if got, want := resolve(), []string{"nodeA:5000"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("Resolve after shrinking = %q, want %q", got, want)
+	}
+
+	// Callers may modify the endpoints returned.
+	resolve()[0] = "modified"
+	if got := resolve(); got[0] != "nodeA:5000" {
+		t.Fatalf("Resolve returned its own slice: %q", got)
+	}
+	write("", start.Add(3*time.Second))
+	if got := resolve(); len(got) != 0 {
+		t.Fatalf("Resolve of an empty file = %q", got)
+	}
+}
+
+func TestSummaryEndpoints(t *testing.T) {
+	summary := BlockchainSummary{Chains: []ChainSummary{
+		{NetworkAddress: "node0.example:5000"},
+		{NetworkAddress: " node1.example "},
+		{NetworkAddress: ""},
+		{NetworkAddress: "node0.example:5000"},
+		{NetworkAddress: "[::1]:5001"},
+	}}
+	want := []string{"node0.example:5000", "node1.example:6000", "[::1]:5001"}
+	if got := summaryEndpoints(summary, "seed.example:6000"); !reflect.DeepEqual(got, want) {
+		t.Errorf("summaryEndpoints = %q, want %q", got, want)
+	}
+	// Without a seed port, addresses are left alone.
+	if got := summaryEndpoints(summary, "seed.example"); got[1] != "node1.example" {
+		t.Errorf("summaryEndpoints without a seed port = %q", got)
+	}
+}
@@ -0,0 +1,467 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrSagaNotFound = errors.New("CLIENT: Saga not found")
+
+// SagaInvocation is a smart contract invocation made by a saga.
+type SagaInvocation struct {
+	SmartContractSpec string `json:"spec"`
+	Args              []byte `json:"args"`
+}
+
+// SagaStep is one step of a saga: an invocation, and the invocation undoing it.
+type SagaStep struct {
+	Name   string         `json:"name"`
+	Action SagaInvocation `json:"action"`
+	// Compensation undoes Action. Steps without one are left alone when the saga is
+	// compensated.
+	Compensation *SagaInvocation `json:"compensation,omitempty"`
+}
+
+// Saga is a business process made of steps carried out in order, each undone by its
+// compensation if a later step fails (see SagaRunner).
+type Saga struct {
+	ID    string     `json:"id"`
+	Steps []SagaStep `json:"steps"`
+}
+
+// SagaStatus is the status of a saga, or of one of its steps.
+type SagaStatus string
+
+const (
+	// Statuses of sagas and steps.
+	SagaPending      SagaStatus = ""
+	SagaRunning      SagaStatus = "running"
+	SagaCompleted    SagaStatus = "completed"
+	SagaCompensating SagaStatus = "compensating"
+	SagaCompensated  SagaStatus = "compensated"
+	// SagaFailed is the status of a saga that could not be compensated, and of its
+	// failed steps.
+	SagaFailed SagaStatus = "failed"
+)
+
+// SagaStepState is the progress of one step of a saga.
+type SagaStepState struct {
+	Status SagaStatus `json:"status"`
+	// CommitID and CompensationCommitID are the commit IDs of the step's action and
+	// compensation: the evidence that they were carried out.
+	CommitID             string `json:"commitId,omitempty"`
+	CompensationCommitID string `json:"compensationCommitId,omitempty"`
+	Error                string `json:"error,omitempty"`
+}
+
+// SagaState is the progress of a saga, as persisted in a SagaStore.
+type SagaState struct {
+	Saga   Saga            `json:"saga"`
+	Status SagaStatus      `json:"status"`
+	Steps  []SagaStepState `json:"steps"`
+	// Error is the error of the step that made the saga compensate.
+	Error   string    `json:"error,omitempty"`
+	Updated time.Time `json:"updated"`
+}
+
+// SagaError is the error of a saga that did not complete.
+type SagaError struct {
+	SagaID string
+	// Step is the name of the step whose action, or compensation, failed.
+	Step string
+	// Compensated is set if the saga was compensated, and is false if its compensation
+	// failed too.
+	Compensated bool
+	Err         error
+}
+
+func (e *SagaError) Error() string {
+	if e.Compensated {
+		return fmt.Sprintf("CLIENT: Saga(%q): step %q failed, saga compensated: %v", e.SagaID, e.Step, e.Err)
+	}
+	return fmt.Sprintf("CLIENT: Saga(%q): step %q failed, saga not compensated: %v", e.SagaID, e.Step, e.Err)
+}
+
+func (e *SagaError) Unwrap() error {
+	return e.Err
+}
+
+// SagaStore persists the state of sagas, so that they can be resumed after a crash.
+type SagaStore interface {
+	// Load returns the state of saga id, or an error wrapping ErrSagaNotFound.
+	Load(id string) (SagaState, error)
+	Save(state SagaState) error
+	// List returns the IDs of the stored sagas.
+	List() ([]string, error)
+}
+
+// SagaRunner carries out sagas through Invoker, persisting their progress in Store
+// before and after every invocation.
+//
+// Steps are carried out in order with IdentifiedInvoke. If one fails, the compensations
+// of the steps carried out so far are invoked in reverse order, including that of the
+// failed step if it failed with a transport error (it may have been carried out).
+// Transport errors are retried Retries times before the step counts as failed;
+// compensations are retried the same way, and a saga whose compensation fails is left
+// SagaFailed, to be compensated again by a later Run.
+//
+// A saga interrupted by a crash, or by its context, is resumed by Run (or ResumeAll)
+// where it stopped. The invocation that was in progress is made again, so actions and
+// compensations must tolerate being carried out twice (see IdempotentCaller).
+type SagaRunner struct {
+	Invoker Invoker
+	Store   SagaStore
+	// Retries bounds the number of retries after transport errors.
+	// DEFAULT_SAGA_RETRIES if zero; negative for none.
+	Retries int
+	// Backoff is the delay before the first retry, doubled before every other.
+	// DEFAULT_SAGA_BACKOFF if zero.
+	Backoff time.Duration
+}
+
+// Run carries out saga, or resumes it if Store already has its state (saga's steps
+// are then ignored in favour of the stored ones), and returns its final state. The
+// error is a *SagaError if the saga did not complete.
+func (runner SagaRunner) Run(ctx context.Context, saga Saga) (SagaState, error) {
+	state, err := runner.Store.Load(saga.ID)
+	if errors.Is(err, ErrSagaNotFound) {
+		state, err = SagaState{Saga: saga, Steps: make([]SagaStepState, len(saga.Steps))}, nil
+	}
+	if err != nil {
+		return state, fmt.Errorf("CLIENT: Saga(%q): %w", saga.ID, err)
+	}
+	return runner.run(ctx, state)
+}
+
+// Resume resumes the saga id stored in Store (see Run).
+func (runner SagaRunner) Resume(ctx context.Context, id string) (SagaState, error) {
+	state, err := runner.Store.Load(id)
+	if err != nil {
+		return state, fmt.Errorf("CLIENT: Saga(%q): %w", id, err)
+	}
+	return runner.run(ctx, state)
+}
+
+// ResumeAll resumes every saga of Store that is neither completed nor compensated, and
+// returns the first error encountered.
+func (runner SagaRunner) ResumeAll(ctx context.Context) error {
+	ids, err := runner.Store.List()
+	if err != nil {
+		return fmt.Errorf("CLIENT: SagaRunner: %w", err)
+	}
+	var first error
+	for _, id := range ids {
+		state, err := runner.Store.Load(id)
+		if err == nil && (state.Status == SagaCompleted || state.Status == SagaCompensated) {
+			continue
+		}
+		if err == nil {
+			_, err = runner.run(ctx, state)
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+func (runner SagaRunner) run(ctx context.Context, state SagaState) (SagaState, error) {
+	switch state.Status {
+	case SagaCompleted:
+		return state, nil
+	case SagaCompensated:
+		return state, runner.sagaError(state, true)
+	case SagaPending, SagaRunning:
+		if err := runner.forward(ctx, &state); err != nil {
+			return state, err
+		}
+		if state.Status == SagaCompleted {
+			return state, nil
+		}
+	}
+	return state, runner.compensate(ctx, &state)
+}
+
+// forward carries out the steps not carried out yet, until one fails.
+func (runner SagaRunner) forward(ctx context.Context, state *SagaState) error {
+	state.Status = SagaRunning
+	for i, step := range state.Saga.Steps {
+		if state.Steps[i].Status == SagaCompleted {
+			continue
+		}
+		state.Steps[i].Status = SagaRunning
+		if err := runner.save(state); err != nil {
+			return err
+		}
+
+		commitID, err := runner.invoke(ctx, step.Action)
+		if err != nil && ctx.Err() != nil {
+			// Left running, to be resumed.
+			return fmt.Errorf("CLIENT: Saga(%q): %w", state.Saga.ID, ctx.Err())
+		}
+		if err != nil {
+			state.Steps[i].Error = err.Error()
+			if transportError(err) {
+				// The action may have been carried out: compensate it too.
+				state.Steps[i].Status = SagaCompensating
+			} else {
+				state.Steps[i].Status = SagaFailed
+			}
+			state.Status, state.Error = SagaCompensating, fmt.Sprintf("step %q: %v", step.Name, err)
+			return runner.save(state)
+		}
+		state.Steps[i].Status, state.Steps[i].CommitID = SagaCompleted, commitID
+		if err = runner.save(state); err != nil {
+			return err
+		}
+	}
+	state.Status = SagaCompleted
+	return runner.save(state)
+}
+
+// compensate invokes the compensations of the steps carried out, in reverse order.
+func (runner SagaRunner) compensate(ctx context.Context, state *SagaState) error {
+	state.Status = SagaCompensating
+	for i := len(state.Saga.Steps) - 1; i >= 0; i-- {
+		step := state.Saga.Steps[i]
+		switch state.Steps[i].Status {
+		case SagaCompleted, SagaCompensating:
+		default:
+			continue
+		}
+		if step.Compensation == nil {
+			state.Steps[i].Status = SagaCompensated
+			continue
+		}
+		state.Steps[i].Status = SagaCompensating
+		if err := runner.save(state); err != nil {
+			return err
+		}
+
+		commitID, err := runner.invoke(ctx, *step.Compensation)
+		if err != nil && ctx.Err() != nil {
+			// Left compensating, to be resumed.
+			return fmt.Errorf("CLIENT: Saga(%q): %w", state.Saga.ID, ctx.Err())
+		}
+		if err != nil {
+			state.Status = SagaFailed
+			if saveErr := runner.save(state); saveErr != nil {
+				return saveErr
+			}
+			return &SagaError{SagaID: state.Saga.ID, Step: step.Name, Err: fmt.Errorf("compensation: %w", err)}
+		}
+		state.Steps[i].Status, state.Steps[i].CompensationCommitID = SagaCompensated, commitID
+	}
+	state.Status = SagaCompensated
+	if err := runner.save(state); err != nil {
+		return err
+	}
+	return runner.sagaError(*state, true)
+}
+
+// sagaError returns the error of the compensated saga of state.
+func (runner SagaRunner) sagaError(state SagaState, compensated bool) error {
+	step := ""
+	for i, each := range state.Steps {
+		if each.Error != "" {
+			step = state.Saga.Steps[i].Name
+		}
+	}
+	return &SagaError{SagaID: state.Saga.ID, Step: step, Compensated: compensated, Err: errors.New(state.Error)}
+}
+
+// invoke makes invocation, retrying it after transport errors, until ctx is done.
+func (runner SagaRunner) invoke(ctx context.Context, invocation SagaInvocation) (string, error) {
+	retries, backoff := runner.Retries, runner.Backoff
+	if retries == 0 {
+		retries = DEFAULT_SAGA_RETRIES
+	}
+	if backoff <= 0 {
+		backoff = DEFAULT_SAGA_BACKOFF
+	}
+	for retry := 0; ; retry++ {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		_, commitID, err := identifiedInvokeContext(ctx, runner.Invoker, invocation.SmartContractSpec, invocation.Args)
+		if err == nil || !transportError(err) || retry >= retries {
+			return commitID, err
+		}
+		select {
+		case <-time.After(backoff):
+			backoff *= 2
+		case <-ctx.Done():
+			return "", err
+		}
+	}
+}
+
+func (runner SagaRunner) save(state *SagaState) error {
+	state.Updated = time.Now()
+	if err := runner.Store.Save(*state); err != nil {
+		return fmt.Errorf("CLIENT: Saga(%q): %w", state.Saga.ID, err)
+	}
+	return nil
+}
+
+// MemorySagaStore is a SagaStore keeping sagas in memory.
+type MemorySagaStore struct {
+	mu     sync.Mutex
+	states map[string][]byte
+}
+
+// NewMemorySagaStore returns an empty MemorySagaStore.
+func NewMemorySagaStore() *MemorySagaStore {
+	return &MemorySagaStore{states: make(map[string][]byte)}
+}
+
+// Load implements SagaStore.
+func (store *MemorySagaStore) Load(id string) (SagaState, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var state SagaState
+	content, ok := store.states[id]
+	if !ok {
+		return state, ErrSagaNotFound
+	}
+	// States are stored encoded, so that callers cannot alter them.
+	return state, json.Unmarshal(content, &state)
+}
+
+// Save implements SagaStore.
+func (store *MemorySagaStore) Save(state SagaState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.states[state.Saga.ID] = content
+	return nil
+}
+
+// List implements SagaStore.
+func (store *MemorySagaStore) List() ([]string, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	ids := make([]string, 0, len(store.states))
+	for id := range store.states {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// FileSagaStore is a SagaStore keeping each saga in its own file in a directory.
+// Files are only readable by the current user (mode 0600).
+type FileSagaStore struct {
+	dir string
+}
+
+// NewFileSagaStore returns a FileSagaStore saving sagas in dir, creating dir (with
+// mode 0700) if needed.
+func NewFileSagaStore(dir string) (*FileSagaStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("CLIENT: FileSagaStore(%q): %w", dir, err)
+	}
+	return &FileSagaStore{dir: dir}, nil
+}
+
+// Load implements SagaStore.
+func (store *FileSagaStore) Load(id string) (SagaState, error) {
+	return store.load(store.path(id))
+}
+
+func (store *FileSagaStore) load(path string) (SagaState, error) {
+	var state SagaState
+	content, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, ErrSagaNotFound
+	}
+	if err == nil {
+		err = json.Unmarshal(content, &state)
+	}
+	if err != nil {
+		return state, fmt.Errorf("CLIENT: FileSagaStore(%q): %w", store.dir, err)
+	}
+	return state, nil
+}
+
+// Save implements SagaStore. The saga file is replaced atomically, and the directory
+// synced, so that a crash leaves either its previous or its new state.
+func (store *FileSagaStore) Save(state SagaState) error {
+	content, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("CLIENT: FileSagaStore(%q): %w", store.dir, err)
+	}
+
+	// ioutil.TempFile creates files with mode 0600.
+	file, err := ioutil.TempFile(store.dir, ".saga-*")
+	if err != nil {
+		return fmt.Errorf("CLIENT: FileSagaStore(%q): %w", store.dir, err)
+	}
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), store.path(state.Saga.ID))
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("CLIENT: FileSagaStore(%q): %w", store.dir, err)
+	}
+	if err = syncDir(store.dir); err != nil {
+		return fmt.Errorf("CLIENT: FileSagaStore(%q): %w", store.dir, err)
+	}
+	return nil
+}
+
+// List implements SagaStore.
+func (store *FileSagaStore) List() ([]string, error) {
+	infos, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: FileSagaStore(%q): %w", store.dir, err)
+	}
+	ids := make([]string, 0, len(infos))
+	for _, info := range infos {
+		if !strings.HasSuffix(info.Name(), ".saga") {
+			continue
+		}
+		state, err := store.load(filepath.Join(store.dir, info.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, state.Saga.ID)
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+// path names saga files after a hash of their ID, which may contain any character.
+func (store *FileSagaStore) path(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return filepath.Join(store.dir, hex.EncodeToString(sum[:])+".saga")
+}
@@ -0,0 +1,148 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+// ledger puts and deletes keys, and refuses the "fail" action.
+func ledger(sc *pcoretest.Context) ([]byte, error) {
+	switch sc.Task.Action {
+	case "put":
+		sc.Put(sc.Task.Data, []byte("1"))
+	case "del":
+		sc.Delete(sc.Task.Data)
+	default:
+		return nil, errors.New("refused")
+	}
+	return []byte("ok"), nil
+}
+
+func ledgerStep(name string, action string) sdk.SagaStep {
+	return sdk.SagaStep{
+		Name:         name,
+		Action:       sdk.SagaInvocation{SmartContractSpec: "ledger-v1", Args: []byte(fmt.Sprintf(`{"action":%q,"data":%q}`, action, name))},
+		Compensation: &sdk.SagaInvocation{SmartContractSpec: "ledger-v1", Args: []byte(fmt.Sprintf(`{"action":"del","data":%q}`, name))},
+	}
+}
+
+func TestSagaRunner(t *testing.T) {
+	client, server := pcoretest.NewClient(t)
+	server.AddSmartContract("ledger", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("ledger-v*", ledger)
+
+	dir := tempDir(t)
+	store, err := sdk.NewFileSagaStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner := sdk.SagaRunner{Invoker: client, Store: store}
+	ctx := context.Background()
+	has := func(key string) bool {
+		_, ok := server.Value("ledger", key)
+		return ok
+	}
+
+	state, err := runner.Run(ctx, sdk.Saga{ID: "order-1", Steps: []sdk.SagaStep{ledgerStep("reserve-1", "put"), ledgerStep("charge-1", "put"), ledgerStep("ship-1", "put")}})
+	if err != nil || state.Status != sdk.SagaCompleted {
+		t.Fatalf("Run() = %s, %v", state.Status, err)
+	}
+	for i, step := range state.Steps {
+		if step.Status != sdk.SagaCompleted || step.CommitID == "" {
+			t.Errorf("step %d = %+v", i, step)
+		}
+	}
+	if !has("reserve-1") || !has("charge-1") || !has("ship-1") {
+		t.Fatal("completed saga left keys unset")
+	}
+
+	// A failed step compensates the steps before it.
+	state, err = runner.Run(ctx, sdk.Saga{ID: "order-2", Steps: []sdk.SagaStep{ledgerStep("reserve-2", "put"), ledgerStep("charge-2", "put"), ledgerStep("ship-2", "fail")}})
+	var sagaErr *sdk.SagaError
+	if !errors.As(err, &sagaErr) || !sagaErr.Compensated || sagaErr.Step != "ship-2" || state.Status != sdk.SagaCompensated {
+		t.Fatalf("Run() = %s, %v, want ship-2 to fail and the saga compensated", state.Status, err)
+	}
+	if has("reserve-2") || has("charge-2") {
+		t.Fatal("compensated saga left keys set")
+	}
+	if state.Steps[0].CompensationCommitID == "" || state.Steps[2].Status != sdk.SagaFailed {
+		t.Fatalf("steps = %+v", state.Steps)
+	}
+
+	// A saga interrupted by a crash is resumed by another process.
+	_, commitID, err := client.IdentifiedInvoke("ledger-v1", []byte(`{"action":"put","data":"reserve-3"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store.Save(sdk.SagaState{
+		Saga:   sdk.Saga{ID: "order-3", Steps: []sdk.SagaStep{ledgerStep("reserve-3", "put"), ledgerStep("charge-3", "put"), ledgerStep("ship-3", "put")}},
+		Status: sdk.SagaRunning,
+		Steps:  []sdk.SagaStepState{{Status: sdk.SagaCompleted, CommitID: commitID}, {Status: sdk.SagaRunning}, {}},
+	})
+	store, err = sdk.NewFileSagaStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runner.Store = store
+	if ids, err := store.List(); err != nil || len(ids) != 3 {
+		t.Fatalf("List() = %q, %v", ids, err)
+	}
+	if err = runner.ResumeAll(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if state, err = store.Load("order-3"); err != nil || state.Status != sdk.SagaCompleted || state.Steps[0].CommitID != commitID {
+		t.Fatalf("resumed saga = %+v, %v", state, err)
+	}
+	if !has("charge-3") || !has("ship-3") {
+		t.Fatal("resumed saga left keys unset")
+	}
+
+	// A failed compensation leaves the saga failed, to be compensated again.
+	broken := ledgerStep("reserve-4", "put")
+	broken.Compensation.SmartContractSpec = "refunds-v1"
+	state, err = runner.Run(ctx, sdk.Saga{ID: "order-4", Steps: []sdk.SagaStep{broken, ledgerStep("ship-4", "fail")}})
+	if !errors.As(err, &sagaErr) || sagaErr.Compensated || sagaErr.Step != "reserve-4" || state.Status != sdk.SagaFailed {
+		t.Fatalf("Run() = %s, %v, want the compensation of reserve-4 to fail", state.Status, err)
+	}
+	server.AddSmartContract("refunds", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("refunds-v*", ledger)
+	if state, err = runner.Resume(ctx, "order-4"); !errors.As(err, &sagaErr) || !sagaErr.Compensated || state.Status != sdk.SagaCompensated {
+		t.Fatalf("Resume() = %s, %v, want the saga compensated", state.Status, err)
+	}
+	if _, err = runner.Resume(ctx, "order-5"); !errors.Is(err, sdk.ErrSagaNotFound) {
+		t.Fatalf("Resume() of an unknown saga = %v, want ErrSagaNotFound", err)
+	}
+}
+
+func TestSagaRunnerCancelsBlockedSteps(t *testing.T) {
+	client, server := pcoretest.NewClient(t)
+	release := make(chan struct{})
+	defer close(release)
+	server.AddSmartContract("ledger", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("ledger-v*", func(sc *pcoretest.Context) ([]byte, error) {
+		<-release
+		return ledger(sc)
+	})
+	store := sdk.NewMemorySagaStore()
+	runner := sdk.SagaRunner{Invoker: client, Store: store}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := runner.Run(ctx, sdk.Saga{ID: "blocked", Steps: []sdk.SagaStep{ledgerStep("reserve", "put")}}); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Run() of a blocked step = %v, want context.DeadlineExceeded", err)
+	}
+	if state, err := store.Load("blocked"); err != nil || state.Status != sdk.SagaRunning {
+		t.Fatalf("blocked saga = %+v, %v, want it left running", state, err)
+	}
+}
@@ -0,0 +1,356 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidSmartContractSpec = errors.New("CLIENT: Invalid smart contract spec")
+	ErrNoSmartContractVersion   = errors.New("CLIENT: No invokable smart contract version")
+)
+
+// SmartContractSpec identifies a smart contract and the versions of it to invoke.
+//
+// Specs are parsed by ParseSmartContractSpec from strings of the forms:
+//  - <name>-v<version>, e.g. payments-v2: that exact version.
+//  - <name>-v* or <name>: the latest version.
+//  - <name>@<constraint>, e.g. payments@^2: the latest version matching the constraint.
+//
+// Constraints are comparisons of dot-separated versions: "=1.2" (or "1.2"), ">1.2",
+// ">=1.2", "<2", "<=2", "^1.2" (>=1.2 <2; ^0.2 is >=0.2 <0.3), "~1.2" (>=1.2 <1.3) and
+// "*". Comparisons separated by spaces or commas must all hold; alternatives are
+// separated by "||".
+type SmartContractSpec struct {
+	Name string
+	// Version is the exact version to invoke, or empty if Constraint is set.
+	Version string
+	// Constraint is the constraint the version to invoke must satisfy; "*" for the latest
+	// version.
+	Constraint string
+
+	alternatives [][]comparison
+}
+
+type comparison struct {
+	op      string
+	version string
+}
+
+// ParseSmartContractSpec parses a spec (see SmartContractSpec).
+func ParseSmartContractSpec(spec string) (SmartContractSpec, error) {
+	spec = strings.TrimSpace(spec)
+	var parsed SmartContractSpec
+	if i := strings.Index(spec, "@"); i >= 0 {
+		parsed.Name, parsed.Constraint = spec[:i], strings.TrimSpace(spec[i+1:])
+	} else if i := strings.LastIndex(spec, "-v"); i > 0 && i+2 < len(spec) && strings.IndexByte("0123456789*", spec[i+2]) >= 0 {
+		parsed.Name, parsed.Constraint = spec[:i], spec[i+2:]
+	} else {
+		parsed.Name, parsed.Constraint = spec, "*"
+	}
+	if parsed.Name == "" || parsed.Name[0] == '-' || strings.ContainsAny(parsed.Name, " \t@*^~<>=|,") {
+		return SmartContractSpec{}, fmt.Errorf("%w: %q", ErrInvalidSmartContractSpec, spec)
+	}
+
+	alternatives, err := parseConstraint(parsed.Constraint)
+	if err != nil {
+		return SmartContractSpec{}, fmt.Errorf("%w: %q: %v", ErrInvalidSmartContractSpec, spec, err)
+	}
+	if len(alternatives) == 1 && len(alternatives[0]) == 1 && alternatives[0][0].op == "=" {
+		parsed.Version, parsed.Constraint = alternatives[0][0].version, ""
+		return parsed, nil
+	}
+	parsed.alternatives = alternatives
+	return parsed, nil
+}
+
+// MustParseSmartContractSpec is like ParseSmartContractSpec but panics if spec cannot
+// be parsed.
+func MustParseSmartContractSpec(spec string) SmartContractSpec {
+	parsed, err := ParseSmartContractSpec(spec)
+	if err != nil {
+		panic(err)
+	}
+	return parsed
+}
+
+// IsExact reports whether spec names an exact version.
+func (spec SmartContractSpec) IsExact() bool {
+	return spec.Version != ""
+}
+
+// String returns spec in the form it is parsed from: <name>-v<version> for exact
+// versions and the latest version, <name>@<constraint> otherwise. Exact specs can be
+// passed to Invoke.
+func (spec SmartContractSpec) String() string {
+	switch {
+	case spec.Version != "":
+		return spec.Name + "-v" + spec.Version
+	case spec.Constraint == "*" || spec.Constraint == "":
+		return spec.Name + "-v*"
+	}
+	return spec.Name + "@" + spec.Constraint
+}
+
+// Matches reports whether version satisfies spec.
+func (spec SmartContractSpec) Matches(version string) bool {
+	if spec.Version != "" {
+		return CompareVersions(version, spec.Version) == 0
+	}
+	if spec.alternatives == nil {
+		// A SmartContractSpec built as a literal.
+		alternatives, err := parseConstraint(spec.Constraint)
+		if err != nil {
+			return false
+		}
+		spec.alternatives = alternatives
+	}
+	for _, comparisons := range spec.alternatives {
+		matches := true
+		for _, c := range comparisons {
+			matches = matches && c.matches(version)
+		}
+		if matches {
+			return true
+		}
+	}
+	return false
+}
+
+func parseConstraint(constraint string) ([][]comparison, error) {
+	var alternatives [][]comparison
+	for _, alternative := range strings.Split(constraint, "||") {
+		fields := strings.FieldsFunc(alternative, func(r rune) bool { return r == ' ' || r == ',' || r == '\t' })
+		if len(fields) == 0 {
+			if strings.TrimSpace(constraint) == "" {
+				fields = []string{"*"}
+			} else {
+				return nil, errors.New("empty alternative")
+			}
+		}
+
+		var comparisons []comparison
+		for _, field := range fields {
+			op := ""
+			for _, prefix := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+				if strings.HasPrefix(field, prefix) {
+					op = prefix
+					break
+				}
+			}
+			version := strings.TrimSpace(field[len(op):])
+			if version == "*" && op == "" {
+				comparisons = append(comparisons, comparison{op: "*"})
+				continue
+			}
+			if op == "" {
+				op = "="
+			}
+			if !validVersion(version, op == "^" || op == "~") {
+				return nil, fmt.Errorf("invalid version %q", field)
+			}
+			comparisons = append(comparisons, expand(op, version)...)
+		}
+		alternatives = append(alternatives, comparisons)
+	}
+	return alternatives, nil
+}
+
+func validVersion(version string, numeric bool) bool {
+	if version == "" {
+		return false
+	}
+	for _, part := range strings.Split(version, ".") {
+		if part == "" || strings.ContainsAny(part, " *-@") {
+			return false
+		}
+		if _, err := strconv.Atoi(part); numeric && err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// expand turns the ^ and ~ operators into a pair of bounds.
+func expand(op string, version string) []comparison {
+	if op != "^" && op != "~" {
+		return []comparison{{op, version}}
+	}
+	parts := strings.Split(version, ".")
+	bump := 0
+	if op == "^" {
+		for bump < len(parts)-1 && parts[bump] == "0" {
+			bump++
+		}
+	} else if len(parts) > 1 {
+		bump = 1
+	}
+	n, _ := strconv.Atoi(parts[bump])
+	upper := append(append([]string(nil), parts[:bump]...), strconv.Itoa(n+1))
+	return []comparison{{">=", version}, {"<", strings.Join(upper, ".")}}
+}
+
+func (c comparison) matches(version string) bool {
+	switch c.op {
+	case "*":
+		return true
+	case "=":
+		return CompareVersions(version, c.version) == 0
+	case ">":
+		return CompareVersions(version, c.version) > 0
+	case ">=":
+		return CompareVersions(version, c.version) >= 0
+	case "<":
+		return CompareVersions(version, c.version) < 0
+	case "<=":
+		return CompareVersions(version, c.version) <= 0
+	}
+	return false
+}
+
+// CompareVersions compares dot-separated versions, numerically where both parts are
+// numbers, and returns -1, 0 or 1 if a is lower than, equal to or higher than b.
+// Missing parts count as 0.
+func CompareVersions(a string, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) || i < len(bs); i++ {
+		x, y := "0", "0"
+		if i < len(as) {
+			x = as[i]
+		}
+		if i < len(bs) {
+			y = bs[i]
+		}
+		xn, xerr := strconv.Atoi(x)
+		yn, yerr := strconv.Atoi(y)
+		switch {
+		case xerr == nil && yerr == nil:
+			if xn != yn {
+				if xn < yn {
+					return -1
+				}
+				return 1
+			}
+		case x != y:
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// SmartContractResolver resolves SmartContractSpecs to the exact versions of the smart
+// contracts the client may invoke, as listed by ListInvokableSC.
+//
+// The list is cached for the resolver's TTL. A spec no listed version satisfies
+// refreshes the list before failing, so that newly registered versions are found.
+type SmartContractResolver struct {
+	invoker Invoker
+	ttl     time.Duration
+	now     func() time.Time
+
+	mu       sync.Mutex
+	versions map[string][]string // sorted, highest first
+	fetched  time.Time
+}
+
+// NewSmartContractResolver returns a resolver listing the smart contracts invokable
+// through invoker (e.g. a Client or a Pool), caching the list for ttl.
+func NewSmartContractResolver(invoker Invoker, ttl time.Duration) *SmartContractResolver {
+	return &SmartContractResolver{invoker: invoker, ttl: ttl, now: time.Now}
+}
+
+// Resolve parses spec and returns the exact spec of the latest invokable version
+// satisfying it. The error wraps ErrInvalidSmartContractSpec or
+// ErrNoSmartContractVersion if spec is invalid or no version satisfies it.
+func (resolver *SmartContractResolver) Resolve(spec string) (SmartContractSpec, error) {
+	parsed, err := ParseSmartContractSpec(spec)
+	if err != nil {
+		return SmartContractSpec{}, err
+	}
+	return resolver.ResolveSpec(parsed)
+}
+
+// ResolveSpec returns the exact spec of the latest invokable version satisfying spec.
+func (resolver *SmartContractResolver) ResolveSpec(spec SmartContractSpec) (SmartContractSpec, error) {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+
+	refreshed := false
+	if resolver.versions == nil || resolver.now().Sub(resolver.fetched) >= resolver.ttl {
+		if err := resolver.refresh(); err != nil {
+			return SmartContractSpec{}, err
+		}
+		refreshed = true
+	}
+	for {
+		for _, version := range resolver.versions[spec.Name] {
+			if spec.Matches(version) {
+				return SmartContractSpec{Name: spec.Name, Version: version}, nil
+			}
+		}
+		if refreshed {
+			return SmartContractSpec{}, fmt.Errorf("%w: %s", ErrNoSmartContractVersion, spec)
+		}
+		if err := resolver.refresh(); err != nil {
+			return SmartContractSpec{}, err
+		}
+		refreshed = true
+	}
+}
+
+// Versions returns the invokable versions of the smart contract name, highest first.
+func (resolver *SmartContractResolver) Versions(name string) ([]string, error) {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	if resolver.versions == nil || resolver.now().Sub(resolver.fetched) >= resolver.ttl {
+		if err := resolver.refresh(); err != nil {
+			return nil, err
+		}
+	}
+	return append([]string(nil), resolver.versions[name]...), nil
+}
+
+// Refresh reloads the list of invokable smart contracts.
+func (resolver *SmartContractResolver) Refresh() error {
+	resolver.mu.Lock()
+	defer resolver.mu.Unlock()
+	return resolver.refresh()
+}
+
+func (resolver *SmartContractResolver) refresh() error {
+	raw, err := resolver.invoker.ListInvokableSC()
+	if err != nil {
+		return fmt.Errorf("CLIENT: SmartContractResolver: %w", err)
+	}
+	var invokable []struct {
+		Name    string `json:"name"`
+		Version string `json:"ver"`
+	}
+	if err = json.Unmarshal(raw, &invokable); err != nil {
+		return fmt.Errorf("CLIENT: SmartContractResolver: %w", err)
+	}
+
+	versions := make(map[string][]string)
+	for _, each := range invokable {
+		versions[each.Name] = append(versions[each.Name], each.Version)
+	}
+	for _, list := range versions {
+		sort.SliceStable(list, func(i, j int) bool { return CompareVersions(list[i], list[j]) > 0 })
+	}
+	resolver.versions, resolver.fetched = versions, resolver.now()
+	return nil
+}
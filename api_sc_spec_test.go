@@ -0,0 +1,141 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseSmartContractSpec(t *testing.T) {
+	for spec, want := range map[string]string{
+		"payments-v2":      "payments-v2",
+		"payments-v2.1":    "payments-v2.1",
+		"payments-v*":      "payments-v*",
+		"payments":         "payments-v*",
+		"my-validator":     "my-validator-v*",
+		"my-validator-v3":  "my-validator-v3",
+		"payments@2":       "payments-v2",
+		"payments@^2":      "payments@^2",
+		"payments@>=1 <3":  "payments@>=1 <3",
+		"payments@1 || ^3": "payments@1 || ^3",
+	} {
+		parsed, err := ParseSmartContractSpec(spec)
+		if err != nil || parsed.String() != want {
+			t.Errorf("ParseSmartContractSpec(%q) = %v, %v; want %s", spec, parsed, err, want)
+		}
+	}
+	for _, spec := range []string{"", "-v2", "@^2", "payments@^x", "payments@>=", "payments@1 ||", "payments-v^2"} {
+		if _, err := ParseSmartContractSpec(spec); !errors.Is(err, ErrInvalidSmartContractSpec) {
+			t.Errorf("ParseSmartContractSpec(%q) error = %v", spec, err)
+		}
+	}
+}
+
+func TestSmartContractSpecMatches(t *testing.T) {
+	for _, test := range []struct {
+		spec    string
+		version string
+		want    bool
+	}{
+		{"payments-v2", "2", true},
+		{"payments-v2", "2.0", true},
+		{"payments-v2", "2.1", false},
+		{"payments@^2", "2.9", true},
+		{"payments@^2", "3", false},
+		{"payments@^0.2", "0.2.5", true},
+		{"payments@^0.2", "0.3", false},
+		{"payments@~1.2", "1.2.9", true},
+		{"payments@~1.2", "1.3", false},
+		{"payments@>1,<=3", "3", true},
+		{"payments@>1,<=3", "1", false},
+		{"payments@1 || ^3", "3.1", true},
+		{"payments@1 || ^3", "2", false},
+		{"payments-v*", "10", true},
+	} {
+		if got := MustParseSmartContractSpec(test.spec).Matches(test.version); got != test.want {
+			t.Errorf("%s matches %s = %v", test.spec, test.version, got)
+		}
+	}
+	if !(SmartContractSpec{Name: "payments", Constraint: "^2"}).Matches("2.5") {
+		t.Error("a literal SmartContractSpec does not match")
+	}
+}
+
+func TestCompareVersions(t *testing.T) {
+	for _, test := range []struct {
+		a, b string
+		want int
+	}{
+		{"2", "2", 0},
+		{"2", "2.0.0", 0},
+		{"2", "10", -1},
+		{"2.10", "2.9", 1},
+		{"1.2.3", "1.3", -1},
+		{"1.beta", "1.alpha", 1},
+		{"1.beta", "1.beta", 0},
+	} {
+		if got := CompareVersions(test.a, test.b); got != test.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.a, test.b, got, test.want)
+		}
+		if got := CompareVersions(test.b, test.a); got != -test.want {
+			t.Errorf("CompareVersions(%q, %q) = %d, want %d", test.b, test.a, got, -test.want)
+		}
+	}
+}
+
+// invokable is an Invoker listing a mutable set of smart contracts.
+type invokable struct {
+	Invoker
+	list  string
+	calls int
+}
+
+func (i *invokable) ListInvokableSC() ([]byte, error) {
+	i.calls++
+	return []byte(i.list), nil
+}
+
+func TestSmartContractResolver(t *testing.T) {
+	invoker := &invokable{list: `[{"name":"payments","ver":"1"},{"name":"payments","ver":"2.10"},{"name":"payments","ver":"2.9"}]`}
+	now := time.Unix(0, 0)
+	resolver := NewSmartContractResolver(invoker, time.Minute)
+	resolver.now = func() time.Time { return now }
+
+	for spec, want := range map[string]string{
+		"payments":       "payments-v2.10",
+		"payments@^2":    "payments-v2.10",
+		"payments@<2.10": "payments-v2.9",
+		"payments-v1":    "payments-v1",
+	} {
+		if resolved, err := resolver.Resolve(spec); err != nil || resolved.String() != want {
+			t.Errorf("Resolve(%q) = %v, %v; want %s", spec, resolved, err, want)
+		}
+	}
+	if invoker.calls != 1 {
+		t.Errorf("ListInvokableSC called %d times, want 1", invoker.calls)
+	}
+
+	// A miss refreshes the list once.
+	if _, err := resolver.Resolve("payments@^3"); !errors.Is(err, ErrNoSmartContractVersion) || invoker.calls != 2 {
+		t.Errorf("Resolve(payments@^3) error = %v after %d calls", err, invoker.calls)
+	}
+	invoker.list = `[{"name":"payments","ver":"3"}]`
+	if resolved, err := resolver.Resolve("payments@^3"); err != nil || resolved.Version != "3" {
+		t.Errorf("Resolve(payments@^3) = %v, %v", resolved, err)
+	}
+
+	// The list expires after the TTL.
+	invoker.list = `[{"name":"payments","ver":"4"}]`
+	if resolved, _ := resolver.Resolve("payments"); resolved.Version != "3" {
+		t.Errorf("Resolve(payments) = %v before the TTL", resolved)
+	}
+	now = now.Add(time.Minute)
+	if resolved, _ := resolver.Resolve("payments"); resolved.Version != "4" {
+		t.Errorf("Resolve(payments) = %v after the TTL", resolved)
+	}
+}
@@ -0,0 +1,217 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"google.golang.org/grpc"
+)
+
+// SharedConnection is a set of connections to ParallelCore endpoints that are not tied
+// to any identity. Any number of users can make calls over a SharedConnection through
+// session Clients (see NewSession), each attaching their own token to every call.
+//
+// This lets a gateway serving many users multiplex all of their sessions over a few
+// connections, instead of opening one connection per user with OpenAnyByToken.
+type SharedConnection struct {
+	endpointSpecs string
+	certPath      string
+	cfg           *openConfig
+	clients       []*Client
+	next          uint64
+	closeOnce     sync.Once
+}
+
+// OpenShared connects to every endpoint in endpointSpecs without authenticating.
+// Like OpenMany, it skips endpoints it cannot connect to, and only fails if it cannot
+// connect to any.
+func OpenShared(endpointSpecs string, certPath string, opts ...Option) (*SharedConnection, error) {
+	cfg := newOpenConfig(opts)
+	if cfg.tokenStore != nil {
+		return nil, fmt.Errorf("CLIENT: OpenShared(%q): WithTokenStore is not supported by shared connections", endpointSpecs)
+	}
+	shared := &SharedConnection{endpointSpecs: endpointSpecs, certPath: certPath, cfg: cfg}
+
+	var lastError error
+	for _, endpoint := range strings.Split(endpointSpecs, " ") {
+		client, err := openOne(endpoint, certPath, "", cfg)
+		if err != nil {
+			lastError = err
+			continue
+		}
+		shared.clients = append(shared.clients, client)
+	}
+	if len(shared.clients) == 0 {
+		return nil, fmt.Errorf("CLIENT: OpenShared(%q): Failed to open any connection. Last error: %w", endpointSpecs, lastError)
+	}
+	return shared, nil
+}
+
+// NewSession returns a Client making its calls over one of shared's connections, with
+// token attached to each call. Creating a session is cheap: it neither dials nor
+// contacts ParallelCore.
+//
+// Closing a session Client does not close shared's connections, and renewing it only
+// replaces its token. Sessions become unusable once shared is closed.
+func (shared *SharedConnection) NewSession(token string, expireTimestamp int64) *Client {
+	i := atomic.AddUint64(&shared.next, 1)
+	base := shared.clients[i%uint64(len(shared.clients))]
+
+	return &Client{
+		grpcClient:      newSessionHandlerClient(base.conn, token),
+		endpointSpecs:   shared.endpointSpecs,
+		certPath:        shared.certPath,
+		token:           token,
+		expireTimestamp: expireTimestamp,
+		cfg:             shared.cfg,
+		shared:          base.conn,
+	}
+}
+
+// Authenticate authenticates clientID over one of shared's connections, and returns
+// a session Client for it (see NewSession).
+func (shared *SharedConnection) Authenticate(clientID string, credential string) (*Client, error) {
+	i := atomic.AddUint64(&shared.next, 1)
+	base := shared.clients[i%uint64(len(shared.clients))]
+
+	returnBytes, err := base.auth([]byte(clientID), []byte(credential))
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: SharedConnection.Authenticate: Failed to auth. %w", err)
+	}
+	token, expireTimestamp, err := parseTokenAndExpireTimestamp(string(returnBytes))
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: SharedConnection.Authenticate: Failed to parse Token. %w", err)
+	}
+	return shared.NewSession(token, expireTimestamp), nil
+}
+
+// Close closes all of shared's connections.
+func (shared *SharedConnection) Close() {
+	shared.closeOnce.Do(func() {
+		CloseMany(shared.clients)
+	})
+}
+
+// sessionHandlerClient attaches a session's token to every call made over a
+// shared connection.
+type sessionHandlerClient struct {
+	inner pb.RequestHandlerClient
+	creds grpc.CallOption
+}
+
+func newSessionHandlerClient(conn *grpc.ClientConn, token string) *sessionHandlerClient {
+	return &sessionHandlerClient{
+		inner: pb.NewRequestHandlerClient(conn),
+		creds: grpc.PerRPCCredentials(customCredential{token: token}),
+	}
+}
+
+func (c *sessionHandlerClient) Invoke(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.Invoke(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) IdentifiedInvoke(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.IdentifiedResponse, error) {
+	return c.inner.IdentifiedInvoke(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) UserMan(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.UserMan(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) SysMan(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.SysMan(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) Renew(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.Renew(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) Auth(ctx context.Context, in *pb.AuthRequest, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.Auth(ctx, in, opts...)
+}
+
+func (c *sessionHandlerClient) Ping(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.Ping(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) RegisterEventListener(ctx context.Context, opts ...grpc.CallOption) (pb.RequestHandler_RegisterEventListenerClient, error) {
+	return c.inner.RegisterEventListener(ctx, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) ManageApiAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.ManageApiAccess(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) CheckApiAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.CheckApiAccess(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) RegisterSmartContract(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.RegisterSmartContract(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) ListSmartContract(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.ListSmartContract(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) ListSmartContracts(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.ListSmartContracts(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) GrantAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.GrantAccess(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) RevokeAccess(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.RevokeAccess(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) CreateDomain(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.CreateDomain(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) ListDomain(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.ListDomain(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) ListManagedDomains(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.ListManagedDomains(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) GrantDomainAdmin(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.GrantDomainAdmin(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) RevokeDomainAdmin(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.RevokeDomainAdmin(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) CreateClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.CreateClient(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) UpdateClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.UpdateClient(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) ListClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.ListClient(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) ListClients(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.ListClients(ctx, in, append(opts, c.creds)...)
+}
+
+func (c *sessionHandlerClient) RemoveClient(ctx context.Context, in *pb.Request, opts ...grpc.CallOption) (*pb.Response, error) {
+	return c.inner.RemoveClient(ctx, in, append(opts, c.creds)...)
+}
@@ -0,0 +1,260 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// TokenStore keeps authentication tokens (see Client.GetToken) between runs of an
+// application, so that it can resume its session with OpenAnyWithTokenStore instead of
+// authenticating with a password every time it starts.
+//
+// key identifies the session; see TokenStoreKey.
+type TokenStore interface {
+	// Load returns the token saved under key, or an error wrapping ErrTokenNotFound.
+	Load(key string) (token string, expireTimestamp int64, err error)
+	Save(key string, token string, expireTimestamp int64) error
+	Delete(key string) error
+}
+
+var ErrTokenNotFound = errors.New("CLIENT: Token not found")
+
+// TokenStoreKey returns the key under which OpenAnyWithTokenStore saves the token of
+// clientID for the network reachable through endpointSpecs.
+func TokenStoreKey(clientID string, endpointSpecs string) string {
+	return clientID + "@" + endpointSpecs
+}
+
+// WithTokenStore makes the returned Client(s) or Pool save their token into store under
+// key whenever they obtain one, either when first opened or when renewed (see
+// Client.Renew and Pool.Renew). It is not supported by OpenShared, whose sessions each
+// have their own token.
+//
+// The Open* functions fail if the token cannot be saved, so that a misconfigured store
+// is noticed right away. Later saves are best effort: a renewal succeeds even if its
+// token cannot be saved, the saved token then simply being older than the Client's.
+func WithTokenStore(store TokenStore, key string) Option {
+	return func(cfg *openConfig) {
+		cfg.tokenStore = store
+		cfg.tokenStoreKey = key
+	}
+}
+
+// OpenAnyWithTokenStore is similar to OpenAny, but first tries to resume the session
+// saved in store using OpenAnyByToken. It falls back to authenticating with clientID and
+// credential if there is no saved token, if the saved token has expired, or if
+// ParallelCore rejects it.
+//
+// The returned Client saves its token into store whenever it obtains a new one,
+// including when renewed.
+func OpenAnyWithTokenStore(endpointSpecs string, clientID string, credential string, certPath string, store TokenStore, opts ...Option) (*Client, error) {
+	key := TokenStoreKey(clientID, endpointSpecs)
+	opts = append(opts, WithTokenStore(store, key))
+
+	token, expireTimestamp, err := store.Load(key)
+	if err == nil && time.Until(time.Unix(expireTimestamp, 0)) > DEFAULT_CLOCK_SKEW {
+		client, err := OpenAnyByToken(endpointSpecs, token, expireTimestamp, certPath, opts...)
+		if err == nil {
+			// Make sure the token has not been revoked, with a call any user may make.
+			if _, err = client.ListInvokableSC(); err == nil {
+				return client, nil
+			}
+			client.Close()
+		}
+		store.Delete(key)
+	}
+
+	return OpenAny(endpointSpecs, clientID, credential, certPath, opts...)
+}
+
+// saveToken saves the client's token into the TokenStore it was opened with, if any.
+func (client *Client) saveToken() error {
+	if client.cfg == nil || client.cfg.tokenStore == nil {
+		return nil
+	}
+	return client.cfg.tokenStore.Save(client.cfg.tokenStoreKey, client.token, client.expireTimestamp)
+}
+
+// MemoryTokenStore is a TokenStore keeping tokens in memory. It lets processes that
+// open many short-lived Clients share one session.
+type MemoryTokenStore struct {
+	mu     sync.Mutex
+	tokens map[string]storedToken
+}
+
+type storedToken struct {
+	Token           string `json:"token"`
+	ExpireTimestamp int64  `json:"expireTimestamp"`
+}
+
+// NewMemoryTokenStore returns an empty MemoryTokenStore.
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{tokens: make(map[string]storedToken)}
+}
+
+// Load implements TokenStore.
+func (store *MemoryTokenStore) Load(key string) (string, int64, error) {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	stored, ok := store.tokens[key]
+	if !ok {
+		return "", 0, ErrTokenNotFound
+	}
+	return stored.Token, stored.ExpireTimestamp, nil
+}
+
+// Save implements TokenStore.
+func (store *MemoryTokenStore) Save(key string, token string, expireTimestamp int64) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.tokens[key] = storedToken{Token: token, ExpireTimestamp: expireTimestamp}
+	return nil
+}
+
+// Delete implements TokenStore.
+func (store *MemoryTokenStore) Delete(key string) error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	delete(store.tokens, key)
+	return nil
+}
+
+// FileTokenStore is a TokenStore keeping each token in its own file in a directory.
+// Files are only readable by the current user (mode 0600), and are optionally
+// encrypted with AES-GCM (see NewEncryptedFileTokenStore).
+type FileTokenStore struct {
+	dir  string
+	aead cipher.AEAD
+}
+
+// NewFileTokenStore returns a FileTokenStore saving tokens in dir, creating dir
+// (with mode 0700) if needed.
+func NewFileTokenStore(dir string) (*FileTokenStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("CLIENT: FileTokenStore(%q): %w", dir, err)
+	}
+	return &FileTokenStore{dir: dir}, nil
+}
+
+// NewEncryptedFileTokenStore is similar to NewFileTokenStore, but encrypts tokens
+// with encryptionKey, which must be 16, 24 or 32 bytes long (AES-128, AES-192 or AES-256).
+func NewEncryptedFileTokenStore(dir string, encryptionKey []byte) (*FileTokenStore, error) {
+	block, err := aes.NewCipher(encryptionKey)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: FileTokenStore(%q): %w", dir, err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: FileTokenStore(%q): %w", dir, err)
+	}
+	store, err := NewFileTokenStore(dir)
+	if err != nil {
+		return nil, err
+	}
+	store.aead = aead
+	return store, nil
+}
+
+// Load implements TokenStore.
+func (store *FileTokenStore) Load(key string) (string, int64, error) {
+	content, err := ioutil.ReadFile(store.path(key))
+	if os.IsNotExist(err) {
+		return "", 0, ErrTokenNotFound
+	}
+	if err != nil {
+		return "", 0, fmt.Errorf("CLIENT: FileTokenStore(%q): %w", store.dir, err)
+	}
+
+	if store.aead != nil {
+		nonceSize := store.aead.NonceSize()
+		if len(content) < nonceSize {
+			return "", 0, fmt.Errorf("CLIENT: FileTokenStore(%q): Token file is truncated", store.dir)
+		}
+		// The key is authenticated too, so a file cannot be copied over another session's.
+		content, err = store.aead.Open(nil, content[:nonceSize], content[nonceSize:], []byte(key))
+		if err != nil {
+			return "", 0, fmt.Errorf("CLIENT: FileTokenStore(%q): Failed to decrypt token. %w", store.dir, err)
+		}
+	}
+
+	var stored storedToken
+	if err = json.Unmarshal(content, &stored); err != nil {
+		return "", 0, fmt.Errorf("CLIENT: FileTokenStore(%q): %w", store.dir, err)
+	}
+	return stored.Token, stored.ExpireTimestamp, nil
+}
+
+// Save implements TokenStore. The token file is replaced atomically, so concurrent
+// readers never see a partially written token, and synced to disk before Save returns.
+func (store *FileTokenStore) Save(key string, token string, expireTimestamp int64) error {
+	content, err := json.Marshal(storedToken{Token: token, ExpireTimestamp: expireTimestamp})
+	if err != nil {
+		return fmt.Errorf("CLIENT: FileTokenStore(%q): %w", store.dir, err)
+	}
+	if store.aead != nil {
+		nonce := make([]byte, store.aead.NonceSize())
+		if _, err = io.ReadFull(rand.Reader, nonce); err != nil {
+			return fmt.Errorf("CLIENT: FileTokenStore(%q): %w", store.dir, err)
+		}
+		content = store.aead.Seal(nonce, nonce, content, []byte(key))
+	}
+
+	// ioutil.TempFile creates files with mode 0600.
+	file, err := ioutil.TempFile(store.dir, ".token-*")
+	if err != nil {
+		return fmt.Errorf("CLIENT: FileTokenStore(%q): %w", store.dir, err)
+	}
+	_, err = file.Write(content)
+	if err == nil {
+		err = file.Sync()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), store.path(key))
+	}
+	if err == nil {
+		err = syncDir(store.dir)
+	}
+	if err != nil {
+		os.Remove(file.Name())
+		return fmt.Errorf("CLIENT: FileTokenStore(%q): %w", store.dir, err)
+	}
+	return nil
+}
+
+// Delete implements TokenStore.
+func (store *FileTokenStore) Delete(key string) error {
+	err := os.Remove(store.path(key))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("CLIENT: FileTokenStore(%q): %w", store.dir, err)
+	}
+	return nil
+}
+
+// path names token files after a hash of their key, since keys contain endpoint lists.
+func (store *FileTokenStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(store.dir, hex.EncodeToString(sum[:])+".token")
+}
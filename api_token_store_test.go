@@ -0,0 +1,309 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func tempDir(t *testing.T) string {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "pcore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+// testTokenStore checks the behaviour every TokenStore shares.
+func testTokenStore(t *testing.T, store sdk.TokenStore) {
+	t.Helper()
+	if _, _, err := store.Load("alice@node0"); !errors.Is(err, sdk.ErrTokenNotFound) {
+		t.Fatalf("Load of a missing token = %v, want ErrTokenNotFound", err)
+	}
+	if err := store.Save("alice@node0", "token-a", 1600000000); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save("bob@node0", "token-b", 1700000000); err != nil {
+		t.Fatal(err)
+	}
+	if token, expireTimestamp, err := store.Load("alice@node0"); err != nil || token != "token-a" || expireTimestamp != 1600000000 {
+		t.Fatalf("Load = %q, %d, %v", token, expireTimestamp, err)
+	}
+	if err := store.Save("alice@node0", "token-a2", 1600000100); err != nil {
+		t.Fatal(err)
+	}
+	if token, _, err := store.Load("alice@node0"); err != nil || token != "token-a2" {
+		t.Fatalf("Load after overwrite = %q, %v", token, err)
+	}
+	if err := store.Delete("alice@node0"); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := store.Load("alice@node0"); !errors.Is(err, sdk.ErrTokenNotFound) {
+		t.Fatalf("Load of a deleted token = %v", err)
+	}
+	if err := store.Delete("alice@node0"); err != nil {
+		t.Fatalf("Delete of a missing token = %v", err)
+	}
+	if token, _, err := store.Load("bob@node0"); err != nil || token != "token-b" {
+		t.Fatalf("Load of another key = %q, %v", token, err)
+	}
+}
+
+func TestMemoryTokenStore(t *testing.T) {
+	testTokenStore(t, sdk.NewMemoryTokenStore())
+}
+
+func TestFileTokenStore(t *testing.T) {
+	dir := filepath.Join(tempDir(t), "tokens")
+	store, err := sdk.NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTokenStore(t, store)
+
+	info, err := os.Stat(dir)
+	if err != nil || info.Mode().Perm() != 0700 {
+		t.Fatalf("directory mode = %v, %v, want 0700", info.Mode(), err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Fatalf("directory holds %q, want bob's token only", files)
+	}
+	if info, err = os.Stat(files[0]); err != nil || info.Mode().Perm() != 0600 {
+		t.Fatalf("token file mode = %v, %v, want 0600", info.Mode(), err)
+	}
+	if content, _ := ioutil.ReadFile(files[0]); !strings.Contains(string(content), "token-b") {
+		t.Fatalf("unencrypted token file = %q", content)
+	}
+
+	// Another store over the same directory sees the same tokens.
+	other, err := sdk.NewFileTokenStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token, _, err := other.Load("bob@node0"); err != nil || token != "token-b" {
+		t.Fatalf("Load from another store = %q, %v", token, err)
+	}
+}
+
+func TestEncryptedFileTokenStore(t *testing.T) {
+	dir := tempDir(t)
+	key := []byte("0123456789abcdef0123456789abcdef")
+	if _, err := sdk.NewEncryptedFileTokenStore(dir, key[:10]); err == nil {
+		t.Fatal("NewEncryptedFileTokenStore accepted a 10-byte key")
+	}
+	store, err := sdk.NewEncryptedFileTokenStore(dir, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	testTokenStore(t, store)
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Fatalf("directory holds %q", files)
+	}
+	content, err := ioutil.ReadFile(files[0])
+	if err != nil || strings.Contains(string(content), "token-b") {
+		t.Fatalf("encrypted token file = %q, %v", content, err)
+	}
+
+	// Tokens cannot be read with another encryption key, tampered with, or moved to
+	// another session's file.
+	wrongKey, _ := sdk.NewEncryptedFileTokenStore(dir, []byte("fedcba9876543210fedcba9876543210"))
+	if _, _, err = wrongKey.Load("bob@node0"); err == nil {
+		t.Error("Load with the wrong encryption key succeeded")
+	}
+	if err = store.Save("alice@node0", "token-a", 1600000000); err != nil {
+		t.Fatal(err)
+	}
+	aliceFiles, _ := filepath.Glob(filepath.Join(dir, "*"))
+	var aliceFile string
+	for _, each := range aliceFiles {
+		if each != files[0] {
+			aliceFile = each
+		}
+	}
+	if err = ioutil.WriteFile(aliceFile, content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = store.Load("alice@node0"); err == nil {
+		t.Error("Load of bob's token copied over alice's succeeded")
+	}
+	content[len(content)-1] ^= 1
+	if err = ioutil.WriteFile(files[0], content, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = store.Load("bob@node0"); err == nil {
+		t.Error("Load of a tampered token succeeded")
+	}
+	if err = ioutil.WriteFile(files[0], content[:4], 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = store.Load("bob@node0"); err == nil {
+		t.Error("Load of a truncated token succeeded")
+	}
+}
+
+func TestOpenAnyWithTokenStore(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	user, _ := json.Marshal(sdk.UserData{ID: "alice", Credential: "secret", Roles: "app"})
+	if _, err := root.CreateClient(user); err != nil {
+		t.Fatal(err)
+	}
+	store := sdk.NewMemoryTokenStore()
+	open := func(credential string) (*sdk.Client, error) {
+		return sdk.OpenAnyWithTokenStore(server.EndpointSpecs(), "alice", credential, server.CertPath(), store, server.Options()...)
+	}
+
+	client, err := open("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.Close()
+	key := sdk.TokenStoreKey("alice", server.EndpointSpecs())
+	token, _, err := store.Load(key)
+	if err != nil || token != client.GetToken() {
+		t.Fatalf("saved token = %q, %v", token, err)
+	}
+
+	// The saved session is resumed, even by a user who may not list clients.
+	resumed, err := open("not needed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resumed.Close()
+	if resumed.GetToken() != token {
+		t.Fatal("OpenAnyWithTokenStore did not resume the saved session")
+	}
+
+	// A revoked token is replaced by a new session.
+	server.RevokeToken(token)
+	if _, err = open("wrong"); err == nil {
+		t.Fatal("OpenAnyWithTokenStore accepted a revoked token")
+	}
+	fresh, err := open("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fresh.Close()
+	if saved, _, _ := store.Load(key); fresh.GetToken() == token || saved != fresh.GetToken() {
+		t.Fatal("OpenAnyWithTokenStore did not replace the revoked token")
+	}
+}
+
+func TestWithTokenStore(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	store := sdk.NewMemoryTokenStore()
+	opts := append(server.Options(), sdk.WithTokenStore(store, "session"))
+	saved := func() string {
+		t.Helper()
+		token, _, err := store.Load("session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.Delete("session")
+		return token
+	}
+
+	byToken, err := sdk.OpenAnyByToken(server.EndpointSpecs(), root.GetToken(), root.GetTokenExpTime(), server.CertPath(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer byToken.Close()
+	if saved() != root.GetToken() {
+		t.Error("OpenAnyByToken did not save its token")
+	}
+
+	many, err := sdk.OpenMany(server.EndpointSpecs(), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.CloseMany(many)
+	if saved() != many[0].GetToken() || many[0].GetTokenExpTime() == 0 {
+		t.Error("OpenMany did not save its token")
+	}
+
+	manyByToken, err := sdk.OpenManyByToken(server.EndpointSpecs(), root.GetToken(), root.GetTokenExpTime(), server.CertPath(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sdk.CloseMany(manyByToken)
+	if saved() != root.GetToken() {
+		t.Error("OpenManyByToken did not save its token")
+	}
+
+	pool, err := sdk.OpenPool(sdk.StaticResolver(server.EndpointSpecs()), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+	if saved() != pool.GetToken() {
+		t.Error("OpenPool did not save its token")
+	}
+	if err = pool.Renew(); err != nil {
+		t.Fatal(err)
+	}
+	if saved() != pool.GetToken() {
+		t.Error("Pool.Renew did not save its token")
+	}
+
+	if _, err = sdk.OpenShared(server.EndpointSpecs(), server.CertPath(), opts...); err == nil {
+		t.Error("OpenShared accepted WithTokenStore")
+	}
+}
+
+// flakyStore is a TokenStore failing to save while broken is set.
+type flakyStore struct {
+	*sdk.MemoryTokenStore
+	broken bool
+}
+
+func (store *flakyStore) Save(key string, token string, expireTimestamp int64) error {
+	if store.broken {
+		return errors.New("disk full")
+	}
+	return store.MemoryTokenStore.Save(key, token, expireTimestamp)
+}
+
+func TestRenewWithBrokenTokenStore(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+	store := &flakyStore{MemoryTokenStore: sdk.NewMemoryTokenStore(), broken: true}
+	if _, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithTokenStore(store, "session")); err == nil {
+		t.Fatal("OpenAny ignored a failing TokenStore")
+	}
+
+	// Once opened, renewal succeeds whether or not its token can be saved.
+	store.broken = false
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithTokenStore(store, "session"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	pool, err := sdk.OpenPool(sdk.StaticResolver(server.EndpointSpecs()), pcoretest.RootID, pcoretest.RootPassword, server.CertPath(), append(server.Options(), sdk.WithTokenStore(store, "pool"))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Close()
+
+	store.broken = true
+	if err = client.Renew(); err != nil {
+		t.Errorf("Client.Renew = %v", err)
+	}
+	if err = pool.Renew(); err != nil {
+		t.Errorf("Pool.Renew = %v", err)
+	}
+}
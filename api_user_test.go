@@ -2,7 +2,9 @@
 // Copyright 2021 Digital Transaction Limited.
 // All Rights Reserved.
 //
-// For internal testing only.
+// For internal testing only: these tests run against the network at PCORE_TEST_ENDPOINT,
+// as PCORE_TEST_CLIENT_ID (default root) with PCORE_TEST_CREDENTIAL, and are skipped
+// when it is unset. See package conformance for the full suite.
 
 package parallelcore_client_sdk_go
 
@@ -13,36 +15,59 @@ import (
 	"testing"
 )
 
-var endpoint string = "local.digital-transaction.net:5000"
-var userID string = "root"
-var password string = "parallelcore"
+var endpoint string = os.Getenv("PCORE_TEST_ENDPOINT")
+var userID string = envOr("PCORE_TEST_CLIENT_ID", "root")
+var password string = os.Getenv("PCORE_TEST_CREDENTIAL")
 
 var client *Client
 
+func envOr(name string, fallback string) string {
+	if value := os.Getenv(name); value != "" {
+		return value
+	}
+	return fallback
+}
+
 func TestMain(m *testing.M) {
-	tempClient, err := OpenAny(endpoint, userID, password, "")
+	if endpoint != "" {
+		tempClient, err := OpenAny(endpoint, userID, password, "")
 
-	if err != nil {
-		fmt.Println(err)
-		os.Exit(-1)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(-1)
+		}
+
+		client = tempClient
 	}
+	os.Exit(m.Run())
+}
 
-	client = tempClient
-	m.Run()
+func needClient(t *testing.T) {
+	if client == nil {
+		t.Skip("PCORE_TEST_ENDPOINT is not set")
+	}
 }
 
 func TestCreateUser(t *testing.T) {
+	needClient(t)
 	res, err := client.CreateUser("alimin", "NoodleK!ng", []string{"app", "admin"}, []string{"default"})
 
 	if err != nil {
-		t.Error(err)
+		t.Fatal(err)
 	}
+	defer client.DeleteUser("alimin", "default")
 
 	fmt.Println(string(res))
+
+	info, err := client.GetUserInfo("alimin")
+	if err != nil || info.ID != "alimin" {
+		t.Errorf("GetUserInfo(alimin) = %+v, %v", info, err)
+	}
 }
 
 func TestListClient(t *testing.T) {
-	res, err := client.ListClient([]byte("adrio"))
+	needClient(t)
+	res, err := client.ListClient([]byte(userID))
 
 	if err != nil {
 		t.Error(err)
@@ -52,10 +77,14 @@ func TestListClient(t *testing.T) {
 }
 
 func TestGetUserInfo(t *testing.T) {
-	res, err := client.GetUserInfo("adrio")
+	needClient(t)
+	res, err := client.GetUserInfo(userID)
 	if err != nil {
 		t.Error(err)
 	}
+	if res.ID != userID {
+		t.Errorf("GetUserInfo(%s).ID = %q", userID, res.ID)
+	}
 
 	resJSON, err := json.MarshalIndent(res, "", "  ")
 	if err != nil {
@@ -66,6 +95,7 @@ func TestGetUserInfo(t *testing.T) {
 }
 
 func TestListClients(t *testing.T) {
+	needClient(t)
 	query, _ := json.Marshal(InfoListData{
 		AllDomains: false,
 		DomainName: "",
@@ -80,6 +110,7 @@ func TestListClients(t *testing.T) {
 }
 
 func TestGetUserInfos(t *testing.T) {
+	needClient(t)
 	res, err := client.GetUserInfos(true, "")
 	if err != nil {
 		t.Error(err)
@@ -0,0 +1,471 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+// Command pcore-scgen generates typed clients for smart contracts, built on the SDK's
+// ContractCaller.
+//
+// The actions of a smart contract are described either by a Go interface, annotated
+// with comments:
+//
+//	// Payments is the payments smart contract.
+//	//
+//	// pcore:contract payments 2
+//	// pcore:error ErrInsufficientFunds "insufficient funds"
+//	type Payments interface {
+//		Transfer(ctx context.Context, req TransferReq) (TransferResp, error)
+//		// pcore:action balance-of
+//		Balance(ctx context.Context, account string) (int64, error)
+//		Reset(ctx context.Context) error
+//	}
+//
+// or by a JSON file:
+//
+//	{
+//	  "package": "payments",
+//	  "contract": "payments",
+//	  "version": "2",
+//	  "errors": [{"name": "ErrInsufficientFunds", "match": "insufficient funds"}],
+//	  "actions": [
+//	    {"method": "Transfer", "name": "transfer", "param": "req", "request": "TransferReq", "response": "TransferResp"},
+//	    {"method": "Balance", "name": "balance-of", "param": "account", "request": "string", "response": "int64"},
+//	    {"method": "Reset", "name": "reset"}
+//	  ]
+//	}
+//
+// or by the same description in YAML, in a file named *.yaml or *.yml:
+//
+//	package: payments
+//	contract: payments
+//	version: "2"
+//	errors:
+//	  - {name: ErrInsufficientFunds, match: insufficient funds}
+//	actions:
+//	  - {method: Transfer, name: transfer, param: req, request: TransferReq, response: TransferResp}
+//	  - {method: Balance, name: balance-of, param: account, request: string, response: int64}
+//	  - {method: Reset, name: reset}
+//
+// Descriptions list the packages their types refer to in "imports". Actions are named
+// after their method, with a lower-case first letter, unless named otherwise.
+//
+// The contract version is optional: clients call the latest version unless pinned to
+// one. It may also be a constraint such as "^2" (see sdk.SmartContractSpec), resolved
+// by the client's Resolver. Smart contract errors containing the match of a
+// pcore:error are returned as errors wrapping the declared sentinel error.
+//
+// Usage, typically from a go:generate directive in the package of the description:
+//
+//	pcore-scgen -source payments.go [-interface Payments] [-type Client] [-output payments_client.go]
+//	pcore-scgen -spec payments.json|payments.yaml [-type Client] [-output payments_client.go]
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+
+	"gopkg.in/yaml.v2"
+)
+
+const sdkImport = "github.com/digital-transaction/parallelcore-client-sdk-go"
+
+// Contract describes a smart contract, as read from a JSON or YAML description.
+type Contract struct {
+	Package  string      `json:"package" yaml:"package"`
+	Name     string      `json:"contract" yaml:"contract"`
+	Version  string      `json:"version,omitempty" yaml:"version,omitempty"`
+	Imports  []string    `json:"imports,omitempty" yaml:"imports,omitempty"`
+	Errors   []ErrorSpec `json:"errors,omitempty" yaml:"errors,omitempty"`
+	Actions  []Action    `json:"actions" yaml:"actions"`
+	Source   string      `json:"-" yaml:"-"`
+	Asserted string      `json:"-" yaml:"-"` // interface the client must satisfy, if any
+}
+
+// ErrorSpec declares a sentinel error, returned for the smart contract errors
+// containing Match.
+type ErrorSpec struct {
+	Name  string `json:"name" yaml:"name"`
+	Match string `json:"match" yaml:"match"`
+}
+
+// Action is a smart contract action, called by the client method Method. Request and
+// Response are Go types, empty if the action takes no argument or returns no result;
+// Param names the argument ("args" by default).
+type Action struct {
+	Method   string `json:"method" yaml:"method"`
+	Name     string `json:"name,omitempty" yaml:"name,omitempty"`
+	Request  string `json:"request,omitempty" yaml:"request,omitempty"`
+	Response string `json:"response,omitempty" yaml:"response,omitempty"`
+	Param    string `json:"param,omitempty" yaml:"param,omitempty"`
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("pcore-scgen: ")
+	source := flag.String("source", "", "Go file declaring the annotated interface")
+	spec := flag.String("spec", "", "JSON or YAML (*.yaml, *.yml) description of the smart contract")
+	iface := flag.String("interface", "", "interface to generate a client for (default: the only annotated one)")
+	typeName := flag.String("type", "Client", "name of the generated client type")
+	output := flag.String("output", "", "file to write the client to (default: <contract>_client.go)")
+	flag.Parse()
+
+	var contract *Contract
+	var err error
+	switch {
+	case *source != "" && *spec == "":
+		contract, err = ParseSource(*source, *iface)
+	case *spec != "" && *source == "":
+		contract, err = ParseSpec(*spec)
+	default:
+		flag.Usage()
+		log.Fatal("exactly one of -source and -spec is required")
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	code, err := Generate(contract, *typeName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *output == "" {
+		*output = strings.ReplaceAll(contract.Name, "-", "_") + "_client.go"
+	}
+	if err = ioutil.WriteFile(*output, code, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// ParseSpec reads a description of a smart contract, in YAML if path ends with .yaml or
+// .yml, and in JSON otherwise.
+func ParseSpec(path string) (*Contract, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	contract := &Contract{}
+	switch filepath.Ext(path) {
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(raw, contract)
+	default:
+		err = json.Unmarshal(raw, contract)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	contract.Source = filepath.Base(path)
+	return contract, contract.check()
+}
+
+// ParseSource reads the description of a smart contract from the interface named iface
+// in a Go file, or from its only interface annotated with pcore:contract.
+func ParseSource(path string, iface string) (*Contract, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, nil, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+
+	var found *ast.TypeSpec
+	var doc *ast.CommentGroup
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if _, ok := typeSpec.Type.(*ast.InterfaceType); !ok {
+				continue
+			}
+			typeDoc := typeSpec.Doc
+			if typeDoc == nil {
+				typeDoc = gen.Doc
+			}
+			if iface != "" && typeSpec.Name.Name != iface || iface == "" && len(directives(typeDoc, "contract")) == 0 {
+				continue
+			}
+			if found != nil {
+				return nil, fmt.Errorf("%s: several annotated interfaces, choose one with -interface", path)
+			}
+			found, doc = typeSpec, typeDoc
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%s: no interface annotated with pcore:contract", path)
+	}
+
+	contract := &Contract{Package: file.Name.Name, Source: filepath.Base(path), Asserted: found.Name.Name}
+	contractArgs := directives(doc, "contract")
+	if len(contractArgs) == 0 || len(contractArgs[0]) == 0 || len(contractArgs[0]) > 2 {
+		return nil, fmt.Errorf("%s: %s needs a pcore:contract <name> [<version>] comment", path, found.Name.Name)
+	}
+	contract.Name = contractArgs[0][0]
+	if len(contractArgs[0]) == 2 {
+		contract.Version = contractArgs[0][1]
+	}
+	for _, args := range directives(doc, "error") {
+		if len(args) != 2 {
+			return nil, fmt.Errorf("%s: pcore:error needs a name and a quoted message", path)
+		}
+		contract.Errors = append(contract.Errors, ErrorSpec{Name: args[0], Match: args[1]})
+	}
+
+	used := make(map[string]bool)
+	for _, field := range found.Type.(*ast.InterfaceType).Methods.List {
+		fn, ok := field.Type.(*ast.FuncType)
+		if !ok || len(field.Names) == 0 {
+			return nil, fmt.Errorf("%s: %s: embedded interfaces are not supported", path, found.Name.Name)
+		}
+		action, err := parseMethod(fset, field.Names[0].Name, fn, used)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", fset.Position(field.Pos()), err)
+		}
+		if names := directives(field.Doc, "action"); len(names) != 0 && len(names[0]) == 1 {
+			action.Name = names[0][0]
+		}
+		contract.Actions = append(contract.Actions, action)
+	}
+
+	for _, spec := range file.Imports {
+		importPath, _ := strconv.Unquote(spec.Path.Value)
+		name := filepath.Base(importPath)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		if used[name] && importPath != "context" {
+			if spec.Name != nil {
+				contract.Imports = append(contract.Imports, name+" "+spec.Path.Value)
+			} else {
+				contract.Imports = append(contract.Imports, importPath)
+			}
+		}
+	}
+	return contract, contract.check()
+}
+
+// parseMethod reads an action from a method with the signature
+// Method(ctx context.Context[, arg T]) ([R, ]error), recording in used the packages its
+// types refer to.
+func parseMethod(fset *token.FileSet, name string, fn *ast.FuncType, used map[string]bool) (Action, error) {
+	action := Action{Method: name, Param: "args"}
+	var params []*ast.Field
+	for _, field := range fn.Params.List {
+		names := field.Names
+		if len(names) == 0 {
+			names = []*ast.Ident{nil}
+		}
+		for _, ident := range names {
+			params = append(params, &ast.Field{Names: []*ast.Ident{ident}, Type: field.Type})
+		}
+	}
+	var results []ast.Expr
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			for n := 0; n < len(field.Names) || n == 0; n++ {
+				results = append(results, field.Type)
+			}
+		}
+	}
+
+	wrong := fmt.Errorf("%s must be %s(ctx context.Context[, args T]) ([R, ]error)", name, name)
+	if len(params) == 0 || len(params) > 2 || expr(fset, params[0].Type) != "context.Context" {
+		return action, wrong
+	}
+	if len(results) == 0 || len(results) > 2 || expr(fset, results[len(results)-1]) != "error" {
+		return action, wrong
+	}
+	if len(params) == 2 {
+		action.Request = expr(fset, params[1].Type)
+		markUsed(params[1].Type, used)
+		if ident := params[1].Names[0]; ident != nil && !reserved[ident.Name] {
+			action.Param = ident.Name
+		}
+	}
+	if len(results) == 2 {
+		action.Response = expr(fset, results[0])
+		markUsed(results[0], used)
+	}
+	return action, nil
+}
+
+// reserved are the names generated methods use, which parameters cannot take.
+var reserved = map[string]bool{"_": true, "c": true, "ctx": true, "err": true, "result": true, "version": true}
+
+func markUsed(node ast.Node, used map[string]bool) {
+	ast.Inspect(node, func(n ast.Node) bool {
+		if selector, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := selector.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+}
+
+func expr(fset *token.FileSet, node ast.Expr) string {
+	var out bytes.Buffer
+	format.Node(&out, fset, node)
+	return out.String()
+}
+
+// directives returns the arguments of the "pcore:<name>" lines of doc. Arguments are
+// written as sdk.EncodeArgs writes them: quoted arguments may contain spaces.
+func directives(doc *ast.CommentGroup, name string) [][]string {
+	if doc == nil {
+		return nil
+	}
+	var found [][]string
+	for _, line := range strings.Split(doc.Text(), "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "pcore:"+name+" ") && line != "pcore:"+name {
+			continue
+		}
+		found = append(found, splitArgs(strings.TrimPrefix(line, "pcore:"+name)))
+	}
+	return found
+}
+
+// splitArgs splits the arguments of a directive the way sdk.DecodeArgs does, falling
+// back to splitting on spaces if their quoting is malformed.
+func splitArgs(line string) []string {
+	args, err := sdk.DecodeArgs([]byte(line))
+	if err != nil {
+		return strings.Fields(line)
+	}
+	return args
+}
+
+func (contract *Contract) check() error {
+	if contract.Package == "" || contract.Name == "" {
+		return fmt.Errorf("%s: the package and contract names are required", contract.Source)
+	}
+	if _, err := sdk.ParseSmartContractSpec(contract.Name + "@" + contract.Version); err != nil {
+		return fmt.Errorf("%s: invalid contract name or version %q %q", contract.Source, contract.Name, contract.Version)
+	}
+	methods := make(map[string]bool)
+	for i := range contract.Actions {
+		action := &contract.Actions[i]
+		if !token.IsIdentifier(action.Method) || !token.IsExported(action.Method) {
+			return fmt.Errorf("%s: invalid method name %q", contract.Source, action.Method)
+		}
+		if methods[action.Method] {
+			return fmt.Errorf("%s: duplicate method %s", contract.Source, action.Method)
+		}
+		methods[action.Method] = true
+		if action.Param == "" {
+			action.Param = "args"
+		}
+		if reserved[action.Param] || !token.IsIdentifier(action.Param) {
+			return fmt.Errorf("%s: invalid parameter name %q of %s", contract.Source, action.Param, action.Method)
+		}
+		if action.Name == "" {
+			first, size := utf8.DecodeRuneInString(action.Method)
+			action.Name = string(unicode.ToLower(first)) + action.Method[size:]
+		}
+	}
+	for _, e := range contract.Errors {
+		if !token.IsIdentifier(e.Name) || e.Match == "" {
+			return fmt.Errorf("%s: invalid error %q %q", contract.Source, e.Name, e.Match)
+		}
+	}
+	return nil
+}
+
+// Generate returns the source of the client typeName of contract.
+func Generate(contract *Contract, typeName string) ([]byte, error) {
+	var out bytes.Buffer
+	p := func(format string, args ...interface{}) { fmt.Fprintf(&out, format, args...) }
+
+	imports := []string{strconv.Quote("context")}
+	if len(contract.Errors) != 0 {
+		imports = append(imports, strconv.Quote("errors"))
+	}
+	for _, each := range contract.Imports {
+		if !strings.Contains(each, `"`) {
+			each = strconv.Quote(each)
+		}
+		imports = append(imports, each)
+	}
+	sort.Strings(imports[1:])
+
+	p("// Code generated by pcore-scgen from %s; DO NOT EDIT.\n\n", contract.Source)
+	p("package %s\n\nimport (\n", contract.Package)
+	for _, each := range imports {
+		p("\t%s\n", each)
+	}
+	p("\n\tsdk %q\n)\n\n", sdkImport)
+
+	p("// ContractName is the name of the smart contract called by %s, and ContractVersion\n", typeName)
+	p("// the version it is pinned to (the latest version if empty).\n")
+	p("const (\n\tContractName    = %q\n\tContractVersion = %q\n)\n\n", contract.Name, contract.Version)
+
+	if len(contract.Errors) != 0 {
+		p("// Errors reported by the %s smart contract.\nvar (\n", contract.Name)
+		for _, e := range contract.Errors {
+			p("\t%s = errors.New(%q)\n", e.Name, e.Match)
+		}
+		p(")\n\n")
+	}
+	p("var contractErrors = map[string]error{\n")
+	for _, e := range contract.Errors {
+		p("\t%q: %s,\n", e.Match, e.Name)
+	}
+	p("}\n\n")
+
+	if contract.Asserted != "" {
+		p("var _ %s = %s{}\n\n", contract.Asserted, typeName)
+	}
+
+	p("// %s calls the actions of the %s smart contract. Errors reported by the smart\n", typeName, contract.Name)
+	p("// contract are returned as *sdk.ContractError values.\n")
+	p("type %s struct {\n", typeName)
+	p("\t// Invoker makes the calls, e.g. an *sdk.Client or an *sdk.Pool.\n\tInvoker sdk.Invoker\n")
+	p("\t// Version, if set, overrides ContractVersion. \"*\" calls the latest version.\n\tVersion string\n")
+	p("\t// Resolver, if set, resolves the version to call, which may then be a constraint\n")
+	p("\t// such as \"^2\".\n\tResolver *sdk.SmartContractResolver\n")
+	p("\t// Codec, if set, encodes arguments and decodes results (see sdk.ContractCaller).\n\tCodec sdk.Codec\n}\n")
+
+	for _, action := range contract.Actions {
+		args, param := "nil", ""
+		if action.Request != "" {
+			args, param = action.Param, fmt.Sprintf(", %s %s", action.Param, action.Request)
+		}
+		p("\n// %s calls the %s action.\n", action.Method, action.Name)
+		if action.Response == "" {
+			p("func (c %s) %s(ctx context.Context%s) error {\n", typeName, action.Method, param)
+			p("\treturn c.caller().Call(ctx, %q, %s, nil)\n}\n", action.Name, args)
+			continue
+		}
+		p("func (c %s) %s(ctx context.Context%s) (%s, error) {\n", typeName, action.Method, param, action.Response)
+		p("\tvar result %s\n", action.Response)
+		p("\terr := c.caller().Call(ctx, %q, %s, &result)\n", action.Name, args)
+		p("\treturn result, err\n}\n")
+	}
+
+	p("\nfunc (c %s) caller() sdk.ContractCaller {\n", typeName)
+	p("\tversion := c.Version\n\tif version == \"\" {\n\t\tversion = ContractVersion\n\t}\n")
+	p("\treturn sdk.ContractCaller{Invoker: c.Invoker, Name: ContractName, Version: version, Resolver: c.Resolver, Codec: c.Codec, Errors: contractErrors}\n}\n")
+
+	code, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code (check the types of %s): %w", contract.Source, err)
+	}
+	return code, nil
+}
@@ -0,0 +1,63 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+const example = "../../examples/payments/"
+
+func TestGenerateFromSource(t *testing.T) {
+	contract, err := ParseSource(example+"payments.go", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := Generate(contract, "Client")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, err := ioutil.ReadFile(example + "payments_client.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(code, want) {
+		t.Errorf("generated code differs from %spayments_client.go (run go generate there):\n%s", example, code)
+	}
+}
+
+func TestGenerateFromSpec(t *testing.T) {
+	want, err := ioutil.ReadFile(example + "payments_client.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"payments.json", "payments.yaml"} {
+		contract, err := ParseSpec("testdata/" + name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, err := Generate(contract, "Client")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Descriptions do not name an interface to assert.
+		expected := strings.Replace(string(want), "from payments.go", "from "+name, 1)
+		expected = strings.Replace(expected, "var _ Payments = Client{}\n\n", "", 1)
+		if string(code) != expected {
+			t.Errorf("code generated from %s:\n%s", name, code)
+		}
+	}
+}
+
+func TestDirectives(t *testing.T) {
+	args := splitArgs(` ErrQuoted "a \"quoted\" message" plain`)
+	if len(args) != 3 || args[0] != "ErrQuoted" || args[1] != `a "quoted" message` || args[2] != "plain" {
+		t.Errorf("splitArgs = %q", args)
+	}
+}
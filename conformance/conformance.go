@@ -0,0 +1,567 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+// Package conformance is a test suite exercising every public method of the
+// ParallelCore Go Client SDK's Client end to end, against any ParallelCore network (or
+// the in-memory fake of package pcoretest).
+//
+// The suite asserts the shape of responses, and reports the server features it found
+// missing (requests rejected as unknown or unimplemented) instead of failing on them.
+// It removes the users it creates and revokes the rights it grants. Domains and smart
+// contracts cannot be removed: the suite reuses one domain, named "conformance", across
+// runs, and registers a smart contract only if given a registration to make.
+//
+// To run it against a live network, set:
+//  - PCORE_CONFORMANCE_ENDPOINTS: space-delimited endpoints.
+//  - PCORE_CONFORMANCE_CLIENT_ID and PCORE_CONFORMANCE_CREDENTIAL: a super-admin that is
+//    also domain-admin of the 'default' domain.
+//  - PCORE_CERT_PATH (optional): the certificate of the endpoints.
+//  - PCORE_CONFORMANCE_SC and PCORE_CONFORMANCE_SC_ARGS (optional): the spec of a smart
+//    contract the super-admin may invoke, and arguments it accepts.
+//  - PCORE_CONFORMANCE_SC_EVENTS (optional): "1" if the smart contract emits an event
+//    when invoked with those arguments.
+//  - PCORE_CONFORMANCE_REGISTRATION (optional): a JSON-encoded smart contract
+//    registration (see Client.RegisterSmartContract) to make.
+//  - PCORE_CONFORMANCE_FORGET (optional): "1" to run the right-to-forget workflow.
+//
+// then run go test ./conformance/...
+package conformance
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Target describes the network the suite runs against.
+type Target struct {
+	// Open returns a Client connected to the network as clientID.
+	Open func(clientID string, credential string) (*sdk.Client, error)
+	// AdminID and AdminCredential identify a super-admin who is also domain-admin of
+	// the 'default' domain.
+	AdminID         string
+	AdminCredential string
+	// SmartContract is the spec of a smart contract the admin may invoke with Args.
+	// Invocations are skipped if it is empty.
+	SmartContract string
+	Args          []byte
+	// Events tells that invoking SmartContract with Args emits an event, which the
+	// suite then expects to receive.
+	Events bool
+	// Registration is a smart contract registration (see Client.RegisterSmartContract)
+	// the admin may make. Registered smart contracts cannot be removed, so registering
+	// is skipped if Registration is empty.
+	Registration []byte
+	// Forget enables the right-to-forget workflow, which records transactions.
+	Forget bool
+}
+
+// TargetFromEnv returns the Target described by the PCORE_CONFORMANCE_* environment
+// variables, and whether PCORE_CONFORMANCE_ENDPOINTS is set.
+func TargetFromEnv() (Target, bool) {
+	endpoints := os.Getenv("PCORE_CONFORMANCE_ENDPOINTS")
+	certPath := os.Getenv("PCORE_CERT_PATH")
+	return Target{
+		Open: func(clientID string, credential string) (*sdk.Client, error) {
+			return sdk.OpenAny(endpoints, clientID, credential, certPath)
+		},
+		AdminID:         os.Getenv("PCORE_CONFORMANCE_CLIENT_ID"),
+		AdminCredential: os.Getenv("PCORE_CONFORMANCE_CREDENTIAL"),
+		SmartContract:   os.Getenv("PCORE_CONFORMANCE_SC"),
+		Args:            []byte(os.Getenv("PCORE_CONFORMANCE_SC_ARGS")),
+		Events:          os.Getenv("PCORE_CONFORMANCE_SC_EVENTS") == "1",
+		Registration:    []byte(os.Getenv("PCORE_CONFORMANCE_REGISTRATION")),
+		Forget:          os.Getenv("PCORE_CONFORMANCE_FORGET") == "1",
+	}, endpoints != ""
+}
+
+// Report lists the server features the suite found missing.
+type Report struct {
+	mu      sync.Mutex
+	missing []string
+}
+
+// Missing returns the missing features, sorted.
+func (report *Report) Missing() []string {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	missing := append([]string(nil), report.missing...)
+	sort.Strings(missing)
+	return missing
+}
+
+func (report *Report) add(feature string) {
+	report.mu.Lock()
+	defer report.mu.Unlock()
+	report.missing = append(report.missing, feature)
+}
+
+// domain is the domain the suite creates on its first run, and reuses afterwards.
+const domain = "conformance"
+
+type suite struct {
+	target Target
+	admin  *sdk.Client
+	report *Report
+	prefix string
+}
+
+// Run runs the suite against target as subtests of t, and returns the features found
+// missing, which it also logs.
+func Run(t *testing.T, target Target) *Report {
+	admin, err := target.Open(target.AdminID, target.AdminCredential)
+	if err != nil {
+		t.Fatalf("conformance: cannot connect as %s: %v", target.AdminID, err)
+	}
+	defer admin.Close()
+
+	s := &suite{
+		target: target,
+		admin:  admin,
+		report: &Report{},
+		prefix: fmt.Sprintf("conformance-%x", time.Now().UnixNano()),
+	}
+	t.Run("Auth", s.testAuth)
+	t.Run("Users", s.testUsers)
+	t.Run("Domains", s.testDomains)
+	t.Run("ApiAccess", s.testApiAccess)
+	t.Run("SmartContracts", s.testSmartContracts)
+	t.Run("Blockchain", s.testBlockchain)
+	t.Run("Events", s.testEvents)
+	t.Run("SysMan", s.testSysMan)
+	t.Run("Forget", s.testForget)
+
+	if missing := s.report.Missing(); len(missing) != 0 {
+		t.Logf("conformance: missing server features: %s", strings.Join(missing, ", "))
+	}
+	return s.report
+}
+
+// supported fails t on unexpected errors. Errors telling that the server does not
+// implement a request are recorded as a missing feature, and skip t.
+func (s *suite) supported(t *testing.T, feature string, err error) {
+	t.Helper()
+	if err == nil {
+		return
+	}
+	if isMissing(err) {
+		s.report.add(feature)
+		t.Skipf("missing server feature %s: %v", feature, err)
+	}
+	t.Fatalf("%s: %v", feature, err)
+}
+
+func isMissing(err error) bool {
+	for e := err; e != nil; {
+		if s, ok := status.FromError(e); ok && s.Code() == codes.Unimplemented {
+			return true
+		}
+		unwrapper, ok := e.(interface{ Unwrap() error })
+		if !ok {
+			break
+		}
+		e = unwrapper.Unwrap()
+	}
+	message := strings.ToLower(err.Error())
+	for _, hint := range []string{"unknown action", "not implemented", "unimplemented", "not supported"} {
+		if strings.Contains(message, hint) {
+			return true
+		}
+	}
+	return false
+}
+
+// newUser creates a user in domains, removed when t finishes.
+func (s *suite) newUser(t *testing.T, name string, domains ...string) (string, string) {
+	t.Helper()
+	userID, password := s.prefix+"-"+name, "Conformance!"+s.prefix
+	_, err := s.admin.CreateUser(userID, password, []string{"app"}, domains)
+	s.supported(t, "CreateUser", err)
+
+	domain := sdk.DOMAIN_DEFAULT
+	if len(domains) != 0 {
+		domain = domains[0]
+	}
+	t.Cleanup(func() {
+		if _, err := s.admin.DeleteUser(userID, domain); err != nil {
+			t.Errorf("cleanup: DeleteUser(%s): %v", userID, err)
+		}
+	})
+	return userID, password
+}
+
+func (s *suite) testAuth(t *testing.T) {
+	if s.admin.GetToken() == "" {
+		t.Error("GetToken is empty")
+	}
+	if exp := s.admin.GetTokenExpTime(); exp <= time.Now().Unix() {
+		t.Errorf("GetTokenExpTime = %d, in the past", exp)
+	}
+
+	userID, password := s.newUser(t, "auth")
+	client, err := s.target.Open(userID, password)
+	if err != nil {
+		t.Fatalf("Open as new user: %v", err)
+	}
+	defer client.Close()
+
+	if claims, err := client.TokenClaims(); err != nil {
+		s.report.add("JWT tokens")
+		t.Logf("TokenClaims: %v", err)
+	} else if claims.ClientID != userID && claims.Subject != userID {
+		t.Errorf("TokenClaims = %+v, want client %s", claims, userID)
+	}
+
+	s.supported(t, "Renew", client.Renew())
+
+	_, err = client.UpdateSelfCredential(userID, password+"2")
+	s.supported(t, "UpdateSelfCredential", err)
+	if again, err := s.target.Open(userID, password+"2"); err != nil {
+		t.Errorf("Open with the updated credential: %v", err)
+	} else {
+		again.Close()
+	}
+}
+
+func (s *suite) testUsers(t *testing.T) {
+	userID, _ := s.newUser(t, "user")
+
+	info, err := s.admin.GetUserInfo(userID)
+	s.supported(t, "GetUserInfo", err)
+	if info.ID != userID || !contains(info.Domains, sdk.DOMAIN_DEFAULT) || !contains(info.Roles, "app") {
+		t.Errorf("GetUserInfo = %+v", info)
+	}
+
+	raw, err := s.admin.ListClient([]byte(userID))
+	s.supported(t, "ListClient", err)
+	var listed sdk.UserFullData
+	if err = json.Unmarshal(raw, &listed); err != nil || listed.ID != userID {
+		t.Errorf("ListClient = %s, %v", raw, err)
+	}
+
+	self, err := s.admin.GetUserInfo("")
+	s.supported(t, "GetUserInfo", err)
+	if self.ID != s.target.AdminID {
+		t.Errorf("GetUserInfo(\"\") = %+v, want %s", self, s.target.AdminID)
+	}
+
+	infos, err := s.admin.GetUserInfos(false, sdk.DOMAIN_DEFAULT)
+	s.supported(t, "GetUserInfos", err)
+	found := false
+	for _, each := range infos {
+		found = found || (each.ID == userID && each.Data.ID == userID)
+	}
+	if !found {
+		t.Errorf("GetUserInfos(false, default) does not list %s", userID)
+	}
+
+	raw, err = s.admin.ListClients([]byte(`{"allDomains":true,"domainName":""}`))
+	s.supported(t, "ListClients", err)
+	var wrappers []sdk.UserFullDataWrapper
+	if err = json.Unmarshal(raw, &wrappers); err != nil || len(wrappers) == 0 {
+		t.Errorf("ListClients = %s, %v", raw, err)
+	}
+
+	_, err = s.admin.UpdateUser(userID, "", []string{"app", "auditor"}, nil)
+	s.supported(t, "UpdateUser", err)
+	if info, err = s.admin.GetUserInfo(userID); err != nil || !contains(info.Roles, "auditor") {
+		t.Errorf("GetUserInfo after UpdateUser = %+v, %v", info, err)
+	}
+
+	rawID := s.prefix + "-raw"
+	_, err = s.admin.CreateClient([]byte(fmt.Sprintf(`{"clientId":%q,"clientCredential":"Raw!pw","clientRoles":"app","clientDomainName":""}`, rawID)))
+	s.supported(t, "CreateClient", err)
+	removed := false
+	t.Cleanup(func() {
+		if !removed {
+			s.admin.DeleteUser(rawID, sdk.DOMAIN_DEFAULT)
+		}
+	})
+	_, err = s.admin.UpdateClient([]byte(fmt.Sprintf(`{"clientId":%q,"clientCredential":"","clientRoles":"app,auditor","clientDomainName":""}`, rawID)))
+	s.supported(t, "UpdateClient", err)
+	_, err = s.admin.RemoveClient([]byte(fmt.Sprintf(`{"clientId":%q,"clientDomainName":""}`, rawID)))
+	s.supported(t, "RemoveClient", err)
+	removed = true
+	if _, err = s.admin.GetUserInfo(rawID); err == nil {
+		t.Errorf("%s still exists after RemoveClient", rawID)
+	}
+}
+
+func (s *suite) testDomains(t *testing.T) {
+	// The API has no call to remove a domain: create it on the first run only.
+	if _, err := s.admin.ListDomain([]byte(domain)); err != nil {
+		_, err = s.admin.CreateDomain([]byte(domain))
+		s.supported(t, "CreateDomain", err)
+	}
+
+	userID, _ := s.newUser(t, "domain", domain)
+	grant := []byte(fmt.Sprintf(`{"clientId":%q,"clientDomainName":%q}`, userID, domain))
+	_, err := s.admin.GrantDomainAdmin(grant)
+	s.supported(t, "GrantDomainAdmin", err)
+	revoked := false
+	t.Cleanup(func() {
+		if !revoked {
+			s.admin.RevokeDomainAdmin(grant)
+		}
+	})
+
+	raw, err := s.admin.ListManagedDomains([]byte(userID))
+	s.supported(t, "ListManagedDomains", err)
+	var managed []string
+	if err = json.Unmarshal(raw, &managed); err != nil || !contains(managed, domain) {
+		t.Errorf("ListManagedDomains = %s, %v", raw, err)
+	}
+
+	raw, err = s.admin.ListDomain([]byte(domain))
+	s.supported(t, "ListDomain", err)
+	if !json.Valid(raw) {
+		t.Errorf("ListDomain = %s, not JSON", raw)
+	}
+
+	_, err = s.admin.RevokeDomainAdmin(grant)
+	s.supported(t, "RevokeDomainAdmin", err)
+	revoked = true
+	if raw, err = s.admin.ListManagedDomains([]byte(userID)); err != nil || json.Unmarshal(raw, &managed) != nil || contains(managed, domain) {
+		t.Errorf("ListManagedDomains after RevokeDomainAdmin = %s, %v", raw, err)
+	}
+}
+
+func (s *suite) testApiAccess(t *testing.T) {
+	raw, err := s.admin.CheckApiAccess([]byte(`{"operation":"check","api":"GetSmartContractTransactionJson"}`))
+	s.supported(t, "CheckApiAccess", err)
+	if text := string(raw); text != "true" && text != "false" {
+		t.Errorf("CheckApiAccess = %q, want true or false", text)
+	}
+	// ManageApiAccess changes who may use an API network-wide, so it is not exercised.
+}
+
+func (s *suite) testSmartContracts(t *testing.T) {
+	raw, err := s.admin.ListSmartContracts([]byte(`{"allDomains":true,"domainName":""}`))
+	s.supported(t, "ListSmartContracts", err)
+	var contracts []map[string]interface{}
+	if err = json.Unmarshal(raw, &contracts); err != nil {
+		t.Errorf("ListSmartContracts = %s, %v", raw, err)
+	}
+
+	raw, err = s.admin.ListInvokableSC()
+	s.supported(t, "ListInvokableSC", err)
+	var invokable []struct {
+		Name    string `json:"name"`
+		Version string `json:"ver"`
+	}
+	if err = json.Unmarshal(raw, &invokable); err != nil {
+		t.Errorf("ListInvokableSC = %s, %v", raw, err)
+	}
+
+	if len(s.target.Registration) != 0 {
+		var registration sdk.SmartContractData
+		if err = json.Unmarshal(s.target.Registration, &registration); err != nil {
+			t.Fatalf("Registration: %v", err)
+		}
+		_, err = s.admin.RegisterSmartContract(s.target.Registration)
+		s.supported(t, "RegisterSmartContract", err)
+		raw, err = s.admin.ListSmartContract([]byte(registration.Name))
+		s.supported(t, "ListSmartContract", err)
+		if !json.Valid(raw) {
+			t.Errorf("ListSmartContract(%s) after RegisterSmartContract = %s", registration.Name, raw)
+		}
+	}
+
+	if s.target.SmartContract == "" {
+		t.Skip("no smart contract to invoke (set PCORE_CONFORMANCE_SC)")
+	}
+	name := s.target.SmartContract
+	if i := strings.LastIndex(name, "-v"); i > 0 {
+		name = name[:i]
+	}
+
+	raw, err = s.admin.ListSmartContract([]byte(name))
+	s.supported(t, "ListSmartContract", err)
+	var info map[string]interface{}
+	if err = json.Unmarshal(raw, &info); err != nil || info["scName"] != name {
+		t.Errorf("ListSmartContract = %s, %v", raw, err)
+	}
+
+	if _, err = s.admin.Invoke(s.target.SmartContract, s.target.Args); err != nil {
+		t.Errorf("Invoke: %v", err)
+	}
+	if _, _, err = s.admin.IdentifiedInvoke(s.target.SmartContract, s.target.Args); err != nil {
+		t.Errorf("IdentifiedInvoke: %v", err)
+	}
+
+	userID, _ := s.newUser(t, "access")
+	access := fmt.Sprintf(`{"clientId":%q,"scName":%q,"domainName":""}`, userID, name)
+	_, err = s.admin.GrantAccess([]byte(access))
+	s.supported(t, "GrantAccess", err)
+	revoked := false
+	t.Cleanup(func() {
+		if !revoked {
+			s.admin.RevokeAccess([]byte(access))
+		}
+	})
+	if user, err := s.admin.GetUserInfo(userID); err != nil || !contains(user.AccessList, name) {
+		t.Errorf("GetUserInfo after GrantAccess = %+v, %v", user, err)
+	}
+	_, err = s.admin.RevokeAccess([]byte(access))
+	s.supported(t, "RevokeAccess", err)
+	revoked = true
+}
+
+func (s *suite) testBlockchain(t *testing.T) {
+	raw, err := s.admin.GetBlockchainSummaryJson()
+	s.supported(t, "GetBlockchainSummaryJson", err)
+	var summary sdk.BlockchainSummary
+	if err = json.Unmarshal(raw, &summary); err != nil {
+		t.Fatalf("GetBlockchainSummaryJson = %s, %v", raw, err)
+	}
+	if parsed, err := s.admin.GetBlockchainSummary(); err != nil || len(parsed.Chains) != len(summary.Chains) {
+		t.Errorf("GetBlockchainSummary = %+v, %v; GetBlockchainSummaryJson has %d chains", parsed, err, len(summary.Chains))
+	}
+	if len(summary.Chains) == 0 {
+		t.Fatal("GetBlockchainSummary returned no chain")
+	}
+	chain := summary.Chains[0]
+	if chain.ChainId == "" || chain.NetworkAddress == "" {
+		t.Errorf("GetBlockchainSummary = %+v", summary)
+	}
+
+	if chain.SealedBlockCount != 0 {
+		blockID := fmt.Sprint(chain.LastBlock.BlockNumber)
+		raw, err := s.admin.GetBlockDetailsJson(chain.ChainId, blockID)
+		s.supported(t, "GetBlockDetailsJson", err)
+		var details struct {
+			BlockNumber int64  `json:"block_number"`
+			Hash        string `json:"hash"`
+		}
+		if err = json.Unmarshal(raw, &details); err != nil || details.BlockNumber != chain.LastBlock.BlockNumber {
+			t.Errorf("GetBlockDetailsJson = %s, %v", raw, err)
+		}
+
+		hash, err := s.admin.CalculateBlockHash(chain.ChainId, blockID)
+		s.supported(t, "CalculateBlockHash", err)
+		if len(hash) == 0 {
+			t.Error("CalculateBlockHash is empty")
+		}
+	}
+
+	raw, err = s.admin.ListLatestTransactions(1)
+	s.supported(t, "ListLatestTransactions", err)
+	var latest struct {
+		TxIds []string `json:"tx_ids"`
+	}
+	if err = json.Unmarshal(raw, &latest); err != nil {
+		t.Fatalf("ListLatestTransactions = %s, %v", raw, err)
+	}
+	if len(latest.TxIds) == 0 {
+		t.Skip("no transaction to inspect")
+	}
+
+	raw, err = s.admin.GetSmartContractTransactionJson(latest.TxIds[0])
+	s.supported(t, "GetSmartContractTransactionJson", err)
+	if !json.Valid(raw) {
+		t.Errorf("GetSmartContractTransactionJson = %s, not JSON", raw)
+	}
+	raw, err = s.admin.GetSmartContractTransactionMetadataJson(latest.TxIds[0])
+	s.supported(t, "GetSmartContractTransactionMetadataJson", err)
+	var metadata map[string]interface{}
+	if err = json.Unmarshal(raw, &metadata); err != nil || metadata["chain_id"] == nil {
+		t.Errorf("GetSmartContractTransactionMetadataJson = %s, %v", raw, err)
+	}
+}
+
+func (s *suite) testEvents(t *testing.T) {
+	name := s.target.SmartContract
+	if i := strings.LastIndex(name, "-v"); i > 0 {
+		name = name[:i]
+	}
+	if name == "" {
+		name = s.prefix
+	}
+
+	// RegisterEventListener closes the client it is called on when done.
+	client, err := s.target.Open(s.target.AdminID, s.target.AdminCredential)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, events, err := client.RegisterEventListener(name, ".*")
+	s.supported(t, "RegisterEventListener", err)
+	if events == nil {
+		t.Fatal("RegisterEventListener returned no event channel")
+	}
+	defer controller.Close()
+
+	if !s.target.Events {
+		t.Skip("no smart contract emitting events (set PCORE_CONFORMANCE_SC_EVENTS)")
+	}
+	_, commitID, err := s.admin.IdentifiedInvoke(s.target.SmartContract, s.target.Args)
+	if err != nil {
+		t.Fatalf("IdentifiedInvoke: %v", err)
+	}
+	select {
+	case e := <-events:
+		if e.Error != nil {
+			t.Fatalf("event error: %v", e.Error)
+		}
+		if e.ScEvent == nil || e.ScEvent.ScName != name || e.ScEvent.TxId == "" {
+			t.Errorf("event = %+v, after transaction %s", e.ScEvent, commitID)
+		}
+	case <-time.After(30 * time.Second):
+		t.Errorf("no event received after transaction %s", commitID)
+	}
+}
+
+func (s *suite) testSysMan(t *testing.T) {
+	// SysMan is deprecated: exercise it with a query only.
+	data, _ := json.Marshal([]string{s.prefix + "-missing-tx"})
+	task, _ := json.Marshal(sdk.SysManData{Action: sdk.API_LIST_FORGET_GROUPS, Data: data})
+	raw, err := s.admin.SysMan(task)
+	s.supported(t, "SysMan", err)
+	var groups []sdk.ForgetGroup
+	if err = json.Unmarshal(raw, &groups); err != nil {
+		t.Errorf("SysMan(%s) = %s, %v", sdk.API_LIST_FORGET_GROUPS, raw, err)
+	}
+}
+
+func (s *suite) testForget(t *testing.T) {
+	if !s.target.Forget {
+		t.Skip("right-to-forget workflow disabled (set PCORE_CONFORMANCE_FORGET=1)")
+	}
+	missing := s.prefix + "-missing-tx"
+
+	requestID, err := s.admin.RequestForget([]string{missing})
+	s.supported(t, "RequestForget", err)
+	approvalID, err := s.admin.ApproveForget(requestID)
+	s.supported(t, "ApproveForget", err)
+	report, err := s.admin.CommitForget(requestID, []string{approvalID})
+	s.supported(t, "CommitForget", err)
+	if !contains(report.NotFound, missing) || report.CommitTxId == "" {
+		t.Errorf("CommitForget = %+v", report)
+	}
+
+	groups, err := s.admin.ListForgetGroups([]string{missing})
+	s.supported(t, "ListForgetGroups", err)
+	if len(groups) == 0 || !contains(groups[0].TxIds, missing) {
+		t.Errorf("ListForgetGroups = %+v", groups)
+	}
+}
+
+func contains(list []string, value string) bool {
+	for _, each := range list {
+		if each == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,66 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package conformance_test
+
+import (
+	"testing"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/conformance"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestFake(t *testing.T) {
+	server, err := pcoretest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddSmartContract("echo", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("echo-v*", func(sc *pcoretest.Context) ([]byte, error) {
+		sc.Put(sc.Task.Action, []byte(sc.Task.Data))
+		sc.Emit(sc.Task.Action, sc.Task.Data)
+		return []byte(sc.Task.Data), nil
+	})
+
+	target := conformance.Target{
+		Open: func(clientID string, credential string) (*sdk.Client, error) {
+			return server.Open(clientID, credential)
+		},
+		AdminID:         pcoretest.RootID,
+		AdminCredential: pcoretest.RootPassword,
+		SmartContract:   "echo-v1",
+		Args:            []byte(`{"action":"set","data":"conformance"}`),
+		Events:          true,
+		Registration:    []byte(`{"scName":"conformance-v1","domainName":"default"}`),
+		Forget:          true,
+	}
+	report := conformance.Run(t, target)
+	if missing := report.Missing(); len(missing) != 0 {
+		t.Errorf("the fake server misses %v", missing)
+	}
+
+	// The suite cleans up after itself, and can run again against the same network.
+	root, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer root.Close()
+	users, err := root.GetUserInfos(true, "")
+	if err != nil || len(users) != 1 {
+		t.Errorf("users left behind: %+v, %v", users, err)
+	}
+	target.Registration = []byte(`{"scName":"conformance-v2","domainName":"default"}`)
+	conformance.Run(t, target)
+}
+
+func TestEndpoint(t *testing.T) {
+	target, ok := conformance.TargetFromEnv()
+	if !ok {
+		t.Skip("PCORE_CONFORMANCE_ENDPOINTS is not set")
+	}
+	conformance.Run(t, target)
+}
@@ -5,9 +5,46 @@
 
 package parallelcore_client_sdk_go
 
+import "time"
+
 const (
 	DOMAIN_DEFAULT = "default"
 
+	// WithProxy value disabling proxies, including those of the environment
+	PROXY_DIRECT = "direct"
+
+	DEFAULT_POOL_REFRESH_INTERVAL     = 30 * time.Second
+	DEFAULT_CLOCK_SKEW                = 30 * time.Second
+	DEFAULT_TRANSACTION_POLL_INTERVAL = time.Second
+	DEFAULT_COMMIT_WAIT_TIMEOUT       = 10 * time.Second
+	DEFAULT_COMMIT_POLL_INTERVAL      = 100 * time.Millisecond
+	DEFAULT_IDEMPOTENCY_WINDOW        = 100
+	DEFAULT_IDEMPOTENCY_RETRIES       = 3
+	DEFAULT_IDEMPOTENCY_BACKOFF       = 100 * time.Millisecond
+	DEFAULT_OUTBOX_BACKOFF            = time.Second
+	DEFAULT_OUTBOX_MAX_BACKOFF        = time.Minute
+	DEFAULT_OUTBOX_JOURNAL_SIZE       = 1000
+	DEFAULT_SAGA_RETRIES              = 3
+	DEFAULT_SAGA_BACKOFF              = 100 * time.Millisecond
+	DEFAULT_INVOKE_CACHE_TTL          = time.Minute
+	DEFAULT_INVOKE_CACHE_MAX_ENTRIES  = 1024
+
+	DEFAULT_INVOKE_CACHE_SUBSCRIBE_BACKOFF     = time.Second
+	DEFAULT_INVOKE_CACHE_SUBSCRIBE_MAX_BACKOFF = time.Minute
+
+	// Smart contracts honouring idempotency keys record them under this prefix
+	IDEMPOTENCY_KEY_MUTATION_PREFIX = "idempotency-key/"
+
+	// Block status, as reported by GetBlockDetailsJson. These are the values observed
+	// on ParallelCore nodes, which do not document them: WithSealedBlockStatus
+	// overrides the one WaitForTransaction expects.
+	BLOCK_STATUS_OPEN   = 0
+	BLOCK_STATUS_SEALED = 1
+
+	// Error message of ParallelCore for a transaction it does not know (yet), as
+	// returned by GetSmartContractTransactionMetadataJson
+	FMT_TRANSACTION_X_DOES_NOT_EXIST = "Transaction %s does not exist"
+
 	E_FUNC_X_OUTPUT_DECODE_ERROR_X     = "CLIENT: %s: Output decoding Error (%w)"
 	E_FUNC_X_ERROR_X                   = "CLIENT: %s: %w"
 	FMT_FUNC_X_INPUT_ENCODE_ERROR_X    = "CLIENT: %s: Input encoding Error (%w)"
@@ -16,8 +16,10 @@ type SysManData struct {
 }
 
 type ScTask struct {
-	Action string `json:"action"`
-	Data   string `json:"data"`
+	Action         string `json:"action"`
+	Data           string `json:"data"`
+	Codec          string `json:"codec,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
 }
 
 type ClientData struct {
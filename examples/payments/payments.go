@@ -0,0 +1,41 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+// Package payments is an example of a typed smart contract client, generated by
+// cmd/pcore-scgen from the Payments interface.
+package payments
+
+import (
+	"context"
+	"encoding/json"
+)
+
+//go:generate go run ../../cmd/pcore-scgen -source payments.go -output payments_client.go
+
+// TransferReq is the argument of the transfer action.
+type TransferReq struct {
+	From   string `json:"from"`
+	To     string `json:"to"`
+	Amount int64  `json:"amount"`
+}
+
+// TransferResp is the result of the transfer action.
+type TransferResp struct {
+	From int64 `json:"from"`
+	To   int64 `json:"to"`
+}
+
+// Payments lists the actions of the payments smart contract.
+//
+// pcore:contract payments 1
+// pcore:error ErrInsufficientFunds "insufficient funds"
+// pcore:error ErrUnknownAccount "unknown account"
+type Payments interface {
+	Transfer(ctx context.Context, req TransferReq) (TransferResp, error)
+	// pcore:action balance-of
+	Balance(ctx context.Context, account string) (int64, error)
+	History(ctx context.Context, account string) (json.RawMessage, error)
+	Reset(ctx context.Context) error
+}
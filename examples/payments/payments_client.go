@@ -0,0 +1,79 @@
+// Code generated by pcore-scgen from payments.go; DO NOT EDIT.
+
+package payments
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+)
+
+// ContractName is the name of the smart contract called by Client, and ContractVersion
+// the version it is pinned to (the latest version if empty).
+const (
+	ContractName    = "payments"
+	ContractVersion = "1"
+)
+
+// Errors reported by the payments smart contract.
+var (
+	ErrInsufficientFunds = errors.New("insufficient funds")
+	ErrUnknownAccount    = errors.New("unknown account")
+)
+
+var contractErrors = map[string]error{
+	"insufficient funds": ErrInsufficientFunds,
+	"unknown account":    ErrUnknownAccount,
+}
+
+var _ Payments = Client{}
+
+// Client calls the actions of the payments smart contract. Errors reported by the smart
+// contract are returned as *sdk.ContractError values.
+type Client struct {
+	// Invoker makes the calls, e.g. an *sdk.Client or an *sdk.Pool.
+	Invoker sdk.Invoker
+	// Version, if set, overrides ContractVersion. "*" calls the latest version.
+	Version string
+	// Resolver, if set, resolves the version to call, which may then be a constraint
+	// such as "^2".
+	Resolver *sdk.SmartContractResolver
+	// Codec, if set, encodes arguments and decodes results (see sdk.ContractCaller).
+	Codec sdk.Codec
+}
+
+// Transfer calls the transfer action.
+func (c Client) Transfer(ctx context.Context, req TransferReq) (TransferResp, error) {
+	var result TransferResp
+	err := c.caller().Call(ctx, "transfer", req, &result)
+	return result, err
+}
+
+// Balance calls the balance-of action.
+func (c Client) Balance(ctx context.Context, account string) (int64, error) {
+	var result int64
+	err := c.caller().Call(ctx, "balance-of", account, &result)
+	return result, err
+}
+
+// History calls the history action.
+func (c Client) History(ctx context.Context, account string) (json.RawMessage, error) {
+	var result json.RawMessage
+	err := c.caller().Call(ctx, "history", account, &result)
+	return result, err
+}
+
+// Reset calls the reset action.
+func (c Client) Reset(ctx context.Context) error {
+	return c.caller().Call(ctx, "reset", nil, nil)
+}
+
+func (c Client) caller() sdk.ContractCaller {
+	version := c.Version
+	if version == "" {
+		version = ContractVersion
+	}
+	return sdk.ContractCaller{Invoker: c.Invoker, Name: ContractName, Version: version, Resolver: c.Resolver, Codec: c.Codec, Errors: contractErrors}
+}
@@ -0,0 +1,123 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package payments_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/examples/payments"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+// contract implements the payments smart contract on the fake server.
+func contract(sc *pcoretest.Context) ([]byte, error) {
+	balance := func(account string) (int64, error) {
+		raw, ok := sc.Get(account)
+		if !ok {
+			return 0, fmt.Errorf("unknown account %s", account)
+		}
+		return strconv.ParseInt(string(raw), 10, 64)
+	}
+
+	switch sc.Task.Action {
+	case "open":
+		sc.Put(sc.Task.Data, []byte("100"))
+		return nil, nil
+	case "balance-of":
+		amount, err := balance(sc.Task.Data)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(strconv.FormatInt(amount, 10)), nil
+	case "transfer":
+		var req payments.TransferReq
+		if err := json.Unmarshal([]byte(sc.Task.Data), &req); err != nil {
+			return nil, err
+		}
+		from, err := balance(req.From)
+		if err != nil {
+			return nil, err
+		}
+		to, err := balance(req.To)
+		if err != nil {
+			return nil, err
+		}
+		if from < req.Amount {
+			return nil, errors.New("insufficient funds")
+		}
+		from, to = from-req.Amount, to+req.Amount
+		sc.Put(req.From, []byte(strconv.FormatInt(from, 10)))
+		sc.Put(req.To, []byte(strconv.FormatInt(to, 10)))
+		return json.Marshal(payments.TransferResp{From: from, To: to})
+	}
+	return nil, fmt.Errorf("unknown action %s", sc.Task.Action)
+}
+
+func TestClient(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	server.AddSmartContract("payments", "1", sdk.DOMAIN_DEFAULT)
+	server.AddSmartContract("payments", "2", sdk.DOMAIN_DEFAULT)
+	server.Handle("payments-v1", contract)
+	server.Handle("payments-v2", func(*pcoretest.Context) ([]byte, error) { return nil, errors.New("v2 called") })
+
+	for _, account := range []string{"alice", "bob"} {
+		if _, err := sdk.CallSmartContract(root, "payments", "open", account); err == nil {
+			t.Fatal("payments-v* did not call the latest version")
+		}
+		if _, err := root.Invoke("payments-v1", []byte(`{"action":"open","data":"`+account+`"}`)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	ctx := context.Background()
+	client := payments.Client{Invoker: root}
+	resp, err := client.Transfer(ctx, payments.TransferReq{From: "alice", To: "bob", Amount: 30})
+	if err != nil || resp != (payments.TransferResp{From: 70, To: 130}) {
+		t.Errorf("Transfer = %+v, %v", resp, err)
+	}
+	if balance, err := client.Balance(ctx, "bob"); err != nil || balance != 130 {
+		t.Errorf("Balance = %d, %v", balance, err)
+	}
+
+	_, err = client.Transfer(ctx, payments.TransferReq{From: "alice", To: "bob", Amount: 1000})
+	var contractErr *sdk.ContractError
+	if !errors.Is(err, payments.ErrInsufficientFunds) || !errors.As(err, &contractErr) || contractErr.Action != "transfer" {
+		t.Errorf("Transfer error = %v", err)
+	}
+	if _, err = client.Balance(ctx, "carol"); !errors.Is(err, payments.ErrUnknownAccount) {
+		t.Errorf("Balance error = %v", err)
+	}
+	if err = client.Reset(ctx); err == nil || errors.As(err, &contractErr) && contractErr.Err != nil {
+		t.Errorf("Reset error = %v", err)
+	}
+
+	latest := payments.Client{Invoker: root, Version: "*"}
+	if _, err = latest.Balance(ctx, "bob"); err == nil || err.Error() != "CLIENT: payments.balance-of: v2 called" {
+		t.Errorf("Balance of the latest version error = %v", err)
+	}
+
+	resolver := sdk.NewSmartContractResolver(root, time.Minute)
+	constrained := payments.Client{Invoker: root, Version: "<2", Resolver: resolver}
+	if balance, err := constrained.Balance(ctx, "bob"); err != nil || balance != 130 {
+		t.Errorf("Balance of version <2 = %d, %v", balance, err)
+	}
+	if _, err = (payments.Client{Invoker: root, Version: "^3", Resolver: resolver}).Balance(ctx, "bob"); !errors.Is(err, sdk.ErrNoSmartContractVersion) {
+		t.Errorf("Balance of version ^3 error = %v", err)
+	}
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if _, err = client.Balance(cancelled, "bob"); err != context.Canceled {
+		t.Errorf("Balance with a cancelled context error = %v", err)
+	}
+}
@@ -3,8 +3,11 @@ module github.com/digital-transaction/parallelcore-client-sdk-go
 go 1.15
 
 require (
+	github.com/fxamacker/cbor/v2 v2.2.0
 	github.com/golang/protobuf v1.4.3
+	github.com/vmihailenco/msgpack/v5 v5.3.5
 	golang.org/x/net v0.0.0-20201110031124-69a78807bb2b
 	google.golang.org/grpc v1.23.1
 	google.golang.org/protobuf v1.23.0
+	gopkg.in/yaml.v2 v2.4.0
 )
@@ -0,0 +1,59 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+// counter sets keys to "1" on "set", emitting a "set" event, and reads them back on
+// "get".
+func counter(sc *pcoretest.Context) ([]byte, error) {
+	switch sc.Task.Action {
+	case "get":
+		value, _ := sc.Get(sc.Task.Data)
+		return value, nil
+	case "set":
+		sc.Put(sc.Task.Data, []byte("1"))
+		sc.Emit("set", sc.Task.Data)
+		return []byte("ok"), nil
+	}
+	return nil, errors.New("unknown action")
+}
+
+// newCounterServer starts a fake server serving counter as "counter-v1", for the
+// duration of test t, and returns a Client connected to it as root.
+func newCounterServer(t *testing.T) (*sdk.Client, *pcoretest.Server) {
+	t.Helper()
+	client, server := pcoretest.NewClient(t)
+	server.AddSmartContract("counter", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("counter-v*", counter)
+	return client, server
+}
+
+// endpointList is an EndpointResolver tests can change.
+type endpointList struct {
+	mu        sync.Mutex
+	endpoints []string
+}
+
+func (l *endpointList) set(endpoints ...string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.endpoints = endpoints
+}
+
+func (l *endpointList) Resolve(ctx context.Context) ([]string, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]string(nil), l.endpoints...), nil
+}
@@ -0,0 +1,56 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+// Package mocks provides mock implementations of the capability interfaces of the
+// ParallelCore Go Client SDK (Invoker, UserAdmin, ..., and API for all of them), so
+// that code depending on them can be unit-tested.
+//
+// Every mock has one function field per method, which the method calls:
+//
+//	invoker := &mocks.InvokerMock{
+//		InvokeFunc: func(spec string, args []byte) ([]byte, error) {
+//			return []byte("ok"), nil
+//		},
+//	}
+//	...
+//	calls := invoker.Calls("Invoke")
+//
+// The mocks are generated from api_interfaces.go by go generate.
+package mocks
+
+import "sync"
+
+// Call is one recorded call of a mock's method.
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// calls records the calls made to a mock. It is safe for concurrent use.
+type calls struct {
+	mu    sync.Mutex
+	calls []Call
+}
+
+func (c *calls) record(method string, args ...interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, Call{Method: method, Args: args})
+}
+
+// Calls returns the calls made to method so far, oldest first, or the calls made to
+// every method if method is empty.
+func (c *calls) Calls(method string) []Call {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	result := make([]Call, 0, len(c.calls))
+	for _, each := range c.calls {
+		if method == "" || each.Method == method {
+			result = append(result, each)
+		}
+	}
+	return result
+}
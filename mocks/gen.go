@@ -0,0 +1,172 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+//go:build ignore
+// +build ignore
+
+// gen generates package mocks from the interfaces declared in a source file of the
+// SDK. Run it with go generate from the SDK's root directory.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"log"
+	"strings"
+)
+
+const sdkImport = "github.com/digital-transaction/parallelcore-client-sdk-go"
+
+type method struct {
+	name    string
+	params  []string // "name type"
+	names   []string
+	results []string
+}
+
+func main() {
+	source := flag.String("source", "api_interfaces.go", "file declaring the interfaces")
+	output := flag.String("output", "mocks/mocks.go", "file to write the mocks to")
+	flag.Parse()
+
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, *source, nil, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	interfaces := make(map[string]*ast.InterfaceType)
+	var order []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.TYPE {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			typeSpec := spec.(*ast.TypeSpec)
+			if iface, ok := typeSpec.Type.(*ast.InterfaceType); ok {
+				interfaces[typeSpec.Name.Name] = iface
+				order = append(order, typeSpec.Name.Name)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by go run mocks/gen.go -source %s; DO NOT EDIT.\n\n", *source)
+	fmt.Fprintf(&out, "package mocks\n\nimport sdk %q\n\n", sdkImport)
+	fmt.Fprintf(&out, "var (\n")
+	for _, name := range order {
+		fmt.Fprintf(&out, "\t_ sdk.%s = &%sMock{}\n", name, name)
+	}
+	fmt.Fprintf(&out, ")\n")
+
+	for _, name := range order {
+		methods := collect(fset, interfaces, name)
+		mock := name + "Mock"
+
+		fmt.Fprintf(&out, "\n// %s is a mock implementation of sdk.%s. Each method records its call\n", mock, name)
+		fmt.Fprintf(&out, "// and calls the field of the same name suffixed with Func, which must be set.\n")
+		fmt.Fprintf(&out, "type %s struct {\n", mock)
+		for _, m := range methods {
+			fmt.Fprintf(&out, "\t%sFunc func(%s) %s\n", m.name, strings.Join(m.params, ", "), resultList(m.results))
+		}
+		fmt.Fprintf(&out, "\n\tcalls\n}\n")
+
+		for _, m := range methods {
+			fmt.Fprintf(&out, "\nfunc (mock *%s) %s(%s) %s {\n", mock, m.name, strings.Join(m.params, ", "), resultList(m.results))
+			fmt.Fprintf(&out, "\tif mock.%sFunc == nil {\n", m.name)
+			fmt.Fprintf(&out, "\t\tpanic(\"%s.%sFunc: method is nil but %s.%s was just called\")\n\t}\n", mock, m.name, name, m.name)
+			fmt.Fprintf(&out, "\tmock.record(%q%s)\n", m.name, prefixed(m.names))
+			fmt.Fprintf(&out, "\treturn mock.%sFunc(%s)\n}\n", m.name, strings.Join(m.names, ", "))
+		}
+	}
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		log.Fatalf("%v\n%s", err, out.Bytes())
+	}
+	if err = ioutil.WriteFile(*output, src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// collect returns the methods of interface name, including those of the interfaces
+// it embeds.
+func collect(fset *token.FileSet, interfaces map[string]*ast.InterfaceType, name string) []method {
+	iface, ok := interfaces[name]
+	if !ok {
+		log.Fatalf("interface %s is not declared in the source file", name)
+	}
+	var methods []method
+	for _, field := range iface.Methods.List {
+		switch t := field.Type.(type) {
+		case *ast.Ident:
+			methods = append(methods, collect(fset, interfaces, t.Name)...)
+		case *ast.FuncType:
+			m := method{name: field.Names[0].Name}
+			for _, param := range t.Params.List {
+				for _, paramName := range param.Names {
+					m.params = append(m.params, paramName.Name+" "+typeString(fset, param.Type))
+					m.names = append(m.names, paramName.Name)
+				}
+			}
+			if t.Results != nil {
+				for _, result := range t.Results.List {
+					m.results = append(m.results, typeString(fset, result.Type))
+				}
+			}
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// typeString prints expr, qualifying the SDK's own types with the sdk package name.
+func typeString(fset *token.FileSet, expr ast.Expr) string {
+	var buf bytes.Buffer
+	format.Node(&buf, fset, qualify(expr))
+	return buf.String()
+}
+
+func qualify(expr ast.Expr) ast.Expr {
+	switch t := expr.(type) {
+	case *ast.Ident:
+		if ast.IsExported(t.Name) {
+			return &ast.SelectorExpr{X: ast.NewIdent("sdk"), Sel: ast.NewIdent(t.Name)}
+		}
+		return t
+	case *ast.StarExpr:
+		return &ast.StarExpr{X: qualify(t.X)}
+	case *ast.ArrayType:
+		return &ast.ArrayType{Len: t.Len, Elt: qualify(t.Elt)}
+	case *ast.MapType:
+		return &ast.MapType{Key: qualify(t.Key), Value: qualify(t.Value)}
+	case *ast.ChanType:
+		return &ast.ChanType{Dir: t.Dir, Value: qualify(t.Value)}
+	case *ast.Ellipsis:
+		return &ast.Ellipsis{Elt: qualify(t.Elt)}
+	}
+	return expr
+}
+
+func resultList(results []string) string {
+	if len(results) == 1 {
+		return results[0]
+	}
+	return "(" + strings.Join(results, ", ") + ")"
+}
+
+func prefixed(names []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	return ", " + strings.Join(names, ", ")
+}
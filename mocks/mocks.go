@@ -0,0 +1,738 @@
+// Code generated by go run mocks/gen.go -source api_interfaces.go; DO NOT EDIT.
+
+package mocks
+
+import sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+
+var (
+	_ sdk.Invoker               = &InvokerMock{}
+	_ sdk.UserAdmin             = &UserAdminMock{}
+	_ sdk.DomainAdmin           = &DomainAdminMock{}
+	_ sdk.SmartContractRegistry = &SmartContractRegistryMock{}
+	_ sdk.BlockchainReader      = &BlockchainReaderMock{}
+	_ sdk.ForgetAdmin           = &ForgetAdminMock{}
+	_ sdk.EventSubscriber       = &EventSubscriberMock{}
+	_ sdk.API                   = &APIMock{}
+)
+
+// InvokerMock is a mock implementation of sdk.Invoker. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type InvokerMock struct {
+	InvokeFunc           func(smartContractSpec string, args []byte) ([]byte, error)
+	IdentifiedInvokeFunc func(smartContractSpec string, args []byte) ([]byte, string, error)
+	ListInvokableSCFunc  func() ([]byte, error)
+
+	calls
+}
+
+func (mock *InvokerMock) Invoke(smartContractSpec string, args []byte) ([]byte, error) {
+	if mock.InvokeFunc == nil {
+		panic("InvokerMock.InvokeFunc: method is nil but Invoker.Invoke was just called")
+	}
+	mock.record("Invoke", smartContractSpec, args)
+	return mock.InvokeFunc(smartContractSpec, args)
+}
+
+func (mock *InvokerMock) IdentifiedInvoke(smartContractSpec string, args []byte) ([]byte, string, error) {
+	if mock.IdentifiedInvokeFunc == nil {
+		panic("InvokerMock.IdentifiedInvokeFunc: method is nil but Invoker.IdentifiedInvoke was just called")
+	}
+	mock.record("IdentifiedInvoke", smartContractSpec, args)
+	return mock.IdentifiedInvokeFunc(smartContractSpec, args)
+}
+
+func (mock *InvokerMock) ListInvokableSC() ([]byte, error) {
+	if mock.ListInvokableSCFunc == nil {
+		panic("InvokerMock.ListInvokableSCFunc: method is nil but Invoker.ListInvokableSC was just called")
+	}
+	mock.record("ListInvokableSC")
+	return mock.ListInvokableSCFunc()
+}
+
+// UserAdminMock is a mock implementation of sdk.UserAdmin. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type UserAdminMock struct {
+	CreateClientFunc    func(clientDataJSON []byte) ([]byte, error)
+	CreateUserFunc      func(userID string, password string, roles []string, domains []string) (string, error)
+	UpdateClientFunc    func(clientDataJSON []byte) ([]byte, error)
+	UpdateUserFunc      func(userID string, password string, roles []string, domains []string) (string, error)
+	ListClientFunc      func(clientID []byte) ([]byte, error)
+	GetUserInfoFunc     func(clientID string) (sdk.UserFullData, error)
+	ListClientsFunc     func(query []byte) ([]byte, error)
+	GetUserInfosFunc    func(allDomains bool, domainName string) ([]sdk.UserFullDataWrapper, error)
+	RemoveClientFunc    func(clientDomainDataJSON []byte) ([]byte, error)
+	DeleteUserFunc      func(userID string, userDomainName string) (string, error)
+	CheckApiAccessFunc  func(apiAccessControllerJSON []byte) ([]byte, error)
+	ManageApiAccessFunc func(in []byte) ([]byte, error)
+
+	calls
+}
+
+func (mock *UserAdminMock) CreateClient(clientDataJSON []byte) ([]byte, error) {
+	if mock.CreateClientFunc == nil {
+		panic("UserAdminMock.CreateClientFunc: method is nil but UserAdmin.CreateClient was just called")
+	}
+	mock.record("CreateClient", clientDataJSON)
+	return mock.CreateClientFunc(clientDataJSON)
+}
+
+func (mock *UserAdminMock) CreateUser(userID string, password string, roles []string, domains []string) (string, error) {
+	if mock.CreateUserFunc == nil {
+		panic("UserAdminMock.CreateUserFunc: method is nil but UserAdmin.CreateUser was just called")
+	}
+	mock.record("CreateUser", userID, password, roles, domains)
+	return mock.CreateUserFunc(userID, password, roles, domains)
+}
+
+func (mock *UserAdminMock) UpdateClient(clientDataJSON []byte) ([]byte, error) {
+	if mock.UpdateClientFunc == nil {
+		panic("UserAdminMock.UpdateClientFunc: method is nil but UserAdmin.UpdateClient was just called")
+	}
+	mock.record("UpdateClient", clientDataJSON)
+	return mock.UpdateClientFunc(clientDataJSON)
+}
+
+func (mock *UserAdminMock) UpdateUser(userID string, password string, roles []string, domains []string) (string, error) {
+	if mock.UpdateUserFunc == nil {
+		panic("UserAdminMock.UpdateUserFunc: method is nil but UserAdmin.UpdateUser was just called")
+	}
+	mock.record("UpdateUser", userID, password, roles, domains)
+	return mock.UpdateUserFunc(userID, password, roles, domains)
+}
+
+func (mock *UserAdminMock) ListClient(clientID []byte) ([]byte, error) {
+	if mock.ListClientFunc == nil {
+		panic("UserAdminMock.ListClientFunc: method is nil but UserAdmin.ListClient was just called")
+	}
+	mock.record("ListClient", clientID)
+	return mock.ListClientFunc(clientID)
+}
+
+func (mock *UserAdminMock) GetUserInfo(clientID string) (sdk.UserFullData, error) {
+	if mock.GetUserInfoFunc == nil {
+		panic("UserAdminMock.GetUserInfoFunc: method is nil but UserAdmin.GetUserInfo was just called")
+	}
+	mock.record("GetUserInfo", clientID)
+	return mock.GetUserInfoFunc(clientID)
+}
+
+func (mock *UserAdminMock) ListClients(query []byte) ([]byte, error) {
+	if mock.ListClientsFunc == nil {
+		panic("UserAdminMock.ListClientsFunc: method is nil but UserAdmin.ListClients was just called")
+	}
+	mock.record("ListClients", query)
+	return mock.ListClientsFunc(query)
+}
+
+func (mock *UserAdminMock) GetUserInfos(allDomains bool, domainName string) ([]sdk.UserFullDataWrapper, error) {
+	if mock.GetUserInfosFunc == nil {
+		panic("UserAdminMock.GetUserInfosFunc: method is nil but UserAdmin.GetUserInfos was just called")
+	}
+	mock.record("GetUserInfos", allDomains, domainName)
+	return mock.GetUserInfosFunc(allDomains, domainName)
+}
+
+func (mock *UserAdminMock) RemoveClient(clientDomainDataJSON []byte) ([]byte, error) {
+	if mock.RemoveClientFunc == nil {
+		panic("UserAdminMock.RemoveClientFunc: method is nil but UserAdmin.RemoveClient was just called")
+	}
+	mock.record("RemoveClient", clientDomainDataJSON)
+	return mock.RemoveClientFunc(clientDomainDataJSON)
+}
+
+func (mock *UserAdminMock) DeleteUser(userID string, userDomainName string) (string, error) {
+	if mock.DeleteUserFunc == nil {
+		panic("UserAdminMock.DeleteUserFunc: method is nil but UserAdmin.DeleteUser was just called")
+	}
+	mock.record("DeleteUser", userID, userDomainName)
+	return mock.DeleteUserFunc(userID, userDomainName)
+}
+
+func (mock *UserAdminMock) CheckApiAccess(apiAccessControllerJSON []byte) ([]byte, error) {
+	if mock.CheckApiAccessFunc == nil {
+		panic("UserAdminMock.CheckApiAccessFunc: method is nil but UserAdmin.CheckApiAccess was just called")
+	}
+	mock.record("CheckApiAccess", apiAccessControllerJSON)
+	return mock.CheckApiAccessFunc(apiAccessControllerJSON)
+}
+
+func (mock *UserAdminMock) ManageApiAccess(in []byte) ([]byte, error) {
+	if mock.ManageApiAccessFunc == nil {
+		panic("UserAdminMock.ManageApiAccessFunc: method is nil but UserAdmin.ManageApiAccess was just called")
+	}
+	mock.record("ManageApiAccess", in)
+	return mock.ManageApiAccessFunc(in)
+}
+
+// DomainAdminMock is a mock implementation of sdk.DomainAdmin. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type DomainAdminMock struct {
+	CreateDomainFunc       func(domainName []byte) ([]byte, error)
+	ListDomainFunc         func(domainName []byte) ([]byte, error)
+	ListManagedDomainsFunc func(userID []byte) ([]byte, error)
+	GrantDomainAdminFunc   func(in []byte) ([]byte, error)
+	RevokeDomainAdminFunc  func(in []byte) ([]byte, error)
+
+	calls
+}
+
+func (mock *DomainAdminMock) CreateDomain(domainName []byte) ([]byte, error) {
+	if mock.CreateDomainFunc == nil {
+		panic("DomainAdminMock.CreateDomainFunc: method is nil but DomainAdmin.CreateDomain was just called")
+	}
+	mock.record("CreateDomain", domainName)
+	return mock.CreateDomainFunc(domainName)
+}
+
+func (mock *DomainAdminMock) ListDomain(domainName []byte) ([]byte, error) {
+	if mock.ListDomainFunc == nil {
+		panic("DomainAdminMock.ListDomainFunc: method is nil but DomainAdmin.ListDomain was just called")
+	}
+	mock.record("ListDomain", domainName)
+	return mock.ListDomainFunc(domainName)
+}
+
+func (mock *DomainAdminMock) ListManagedDomains(userID []byte) ([]byte, error) {
+	if mock.ListManagedDomainsFunc == nil {
+		panic("DomainAdminMock.ListManagedDomainsFunc: method is nil but DomainAdmin.ListManagedDomains was just called")
+	}
+	mock.record("ListManagedDomains", userID)
+	return mock.ListManagedDomainsFunc(userID)
+}
+
+func (mock *DomainAdminMock) GrantDomainAdmin(in []byte) ([]byte, error) {
+	if mock.GrantDomainAdminFunc == nil {
+		panic("DomainAdminMock.GrantDomainAdminFunc: method is nil but DomainAdmin.GrantDomainAdmin was just called")
+	}
+	mock.record("GrantDomainAdmin", in)
+	return mock.GrantDomainAdminFunc(in)
+}
+
+func (mock *DomainAdminMock) RevokeDomainAdmin(in []byte) ([]byte, error) {
+	if mock.RevokeDomainAdminFunc == nil {
+		panic("DomainAdminMock.RevokeDomainAdminFunc: method is nil but DomainAdmin.RevokeDomainAdmin was just called")
+	}
+	mock.record("RevokeDomainAdmin", in)
+	return mock.RevokeDomainAdminFunc(in)
+}
+
+// SmartContractRegistryMock is a mock implementation of sdk.SmartContractRegistry. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type SmartContractRegistryMock struct {
+	RegisterSmartContractFunc func(scRegistration []byte) ([]byte, error)
+	ListSmartContractFunc     func(scName []byte) ([]byte, error)
+	ListSmartContractsFunc    func(query []byte) ([]byte, error)
+	GrantAccessFunc           func(clientAccessDataJSON []byte) ([]byte, error)
+	RevokeAccessFunc          func(clientAccessDataJSON []byte) ([]byte, error)
+
+	calls
+}
+
+func (mock *SmartContractRegistryMock) RegisterSmartContract(scRegistration []byte) ([]byte, error) {
+	if mock.RegisterSmartContractFunc == nil {
+		panic("SmartContractRegistryMock.RegisterSmartContractFunc: method is nil but SmartContractRegistry.RegisterSmartContract was just called")
+	}
+	mock.record("RegisterSmartContract", scRegistration)
+	return mock.RegisterSmartContractFunc(scRegistration)
+}
+
+func (mock *SmartContractRegistryMock) ListSmartContract(scName []byte) ([]byte, error) {
+	if mock.ListSmartContractFunc == nil {
+		panic("SmartContractRegistryMock.ListSmartContractFunc: method is nil but SmartContractRegistry.ListSmartContract was just called")
+	}
+	mock.record("ListSmartContract", scName)
+	return mock.ListSmartContractFunc(scName)
+}
+
+func (mock *SmartContractRegistryMock) ListSmartContracts(query []byte) ([]byte, error) {
+	if mock.ListSmartContractsFunc == nil {
+		panic("SmartContractRegistryMock.ListSmartContractsFunc: method is nil but SmartContractRegistry.ListSmartContracts was just called")
+	}
+	mock.record("ListSmartContracts", query)
+	return mock.ListSmartContractsFunc(query)
+}
+
+func (mock *SmartContractRegistryMock) GrantAccess(clientAccessDataJSON []byte) ([]byte, error) {
+	if mock.GrantAccessFunc == nil {
+		panic("SmartContractRegistryMock.GrantAccessFunc: method is nil but SmartContractRegistry.GrantAccess was just called")
+	}
+	mock.record("GrantAccess", clientAccessDataJSON)
+	return mock.GrantAccessFunc(clientAccessDataJSON)
+}
+
+func (mock *SmartContractRegistryMock) RevokeAccess(clientAccessDataJSON []byte) ([]byte, error) {
+	if mock.RevokeAccessFunc == nil {
+		panic("SmartContractRegistryMock.RevokeAccessFunc: method is nil but SmartContractRegistry.RevokeAccess was just called")
+	}
+	mock.record("RevokeAccess", clientAccessDataJSON)
+	return mock.RevokeAccessFunc(clientAccessDataJSON)
+}
+
+// BlockchainReaderMock is a mock implementation of sdk.BlockchainReader. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type BlockchainReaderMock struct {
+	GetBlockchainSummaryJsonFunc                func() ([]byte, error)
+	GetBlockchainSummaryFunc                    func() (sdk.BlockchainSummary, error)
+	GetBlockDetailsJsonFunc                     func(chainID string, blockID string) ([]byte, error)
+	CalculateBlockHashFunc                      func(chainID string, blockID string) ([]byte, error)
+	GetSmartContractTransactionJsonFunc         func(transactionId string) ([]byte, error)
+	GetSmartContractTransactionMetadataJsonFunc func(transactionId string) ([]byte, error)
+	ListLatestTransactionsFunc                  func(count int) ([]byte, error)
+
+	calls
+}
+
+func (mock *BlockchainReaderMock) GetBlockchainSummaryJson() ([]byte, error) {
+	if mock.GetBlockchainSummaryJsonFunc == nil {
+		panic("BlockchainReaderMock.GetBlockchainSummaryJsonFunc: method is nil but BlockchainReader.GetBlockchainSummaryJson was just called")
+	}
+	mock.record("GetBlockchainSummaryJson")
+	return mock.GetBlockchainSummaryJsonFunc()
+}
+
+func (mock *BlockchainReaderMock) GetBlockchainSummary() (sdk.BlockchainSummary, error) {
+	if mock.GetBlockchainSummaryFunc == nil {
+		panic("BlockchainReaderMock.GetBlockchainSummaryFunc: method is nil but BlockchainReader.GetBlockchainSummary was just called")
+	}
+	mock.record("GetBlockchainSummary")
+	return mock.GetBlockchainSummaryFunc()
+}
+
+func (mock *BlockchainReaderMock) GetBlockDetailsJson(chainID string, blockID string) ([]byte, error) {
+	if mock.GetBlockDetailsJsonFunc == nil {
+		panic("BlockchainReaderMock.GetBlockDetailsJsonFunc: method is nil but BlockchainReader.GetBlockDetailsJson was just called")
+	}
+	mock.record("GetBlockDetailsJson", chainID, blockID)
+	return mock.GetBlockDetailsJsonFunc(chainID, blockID)
+}
+
+func (mock *BlockchainReaderMock) CalculateBlockHash(chainID string, blockID string) ([]byte, error) {
+	if mock.CalculateBlockHashFunc == nil {
+		panic("BlockchainReaderMock.CalculateBlockHashFunc: method is nil but BlockchainReader.CalculateBlockHash was just called")
+	}
+	mock.record("CalculateBlockHash", chainID, blockID)
+	return mock.CalculateBlockHashFunc(chainID, blockID)
+}
+
+func (mock *BlockchainReaderMock) GetSmartContractTransactionJson(transactionId string) ([]byte, error) {
+	if mock.GetSmartContractTransactionJsonFunc == nil {
+		panic("BlockchainReaderMock.GetSmartContractTransactionJsonFunc: method is nil but BlockchainReader.GetSmartContractTransactionJson was just called")
+	}
+	mock.record("GetSmartContractTransactionJson", transactionId)
+	return mock.GetSmartContractTransactionJsonFunc(transactionId)
+}
+
+func (mock *BlockchainReaderMock) GetSmartContractTransactionMetadataJson(transactionId string) ([]byte, error) {
+	if mock.GetSmartContractTransactionMetadataJsonFunc == nil {
+		panic("BlockchainReaderMock.GetSmartContractTransactionMetadataJsonFunc: method is nil but BlockchainReader.GetSmartContractTransactionMetadataJson was just called")
+	}
+	mock.record("GetSmartContractTransactionMetadataJson", transactionId)
+	return mock.GetSmartContractTransactionMetadataJsonFunc(transactionId)
+}
+
+func (mock *BlockchainReaderMock) ListLatestTransactions(count int) ([]byte, error) {
+	if mock.ListLatestTransactionsFunc == nil {
+		panic("BlockchainReaderMock.ListLatestTransactionsFunc: method is nil but BlockchainReader.ListLatestTransactions was just called")
+	}
+	mock.record("ListLatestTransactions", count)
+	return mock.ListLatestTransactionsFunc(count)
+}
+
+// ForgetAdminMock is a mock implementation of sdk.ForgetAdmin. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type ForgetAdminMock struct {
+	RequestForgetFunc    func(txIds []string) (string, error)
+	ApproveForgetFunc    func(forgetRequestTxID string) (string, error)
+	CommitForgetFunc     func(forgetRequestTxID string, forgetApprovalTxID []string) (sdk.ForgetReport, error)
+	ListForgetGroupsFunc func(txIds []string) ([]sdk.ForgetGroup, error)
+
+	calls
+}
+
+func (mock *ForgetAdminMock) RequestForget(txIds []string) (string, error) {
+	if mock.RequestForgetFunc == nil {
+		panic("ForgetAdminMock.RequestForgetFunc: method is nil but ForgetAdmin.RequestForget was just called")
+	}
+	mock.record("RequestForget", txIds)
+	return mock.RequestForgetFunc(txIds)
+}
+
+func (mock *ForgetAdminMock) ApproveForget(forgetRequestTxID string) (string, error) {
+	if mock.ApproveForgetFunc == nil {
+		panic("ForgetAdminMock.ApproveForgetFunc: method is nil but ForgetAdmin.ApproveForget was just called")
+	}
+	mock.record("ApproveForget", forgetRequestTxID)
+	return mock.ApproveForgetFunc(forgetRequestTxID)
+}
+
+func (mock *ForgetAdminMock) CommitForget(forgetRequestTxID string, forgetApprovalTxID []string) (sdk.ForgetReport, error) {
+	if mock.CommitForgetFunc == nil {
+		panic("ForgetAdminMock.CommitForgetFunc: method is nil but ForgetAdmin.CommitForget was just called")
+	}
+	mock.record("CommitForget", forgetRequestTxID, forgetApprovalTxID)
+	return mock.CommitForgetFunc(forgetRequestTxID, forgetApprovalTxID)
+}
+
+func (mock *ForgetAdminMock) ListForgetGroups(txIds []string) ([]sdk.ForgetGroup, error) {
+	if mock.ListForgetGroupsFunc == nil {
+		panic("ForgetAdminMock.ListForgetGroupsFunc: method is nil but ForgetAdmin.ListForgetGroups was just called")
+	}
+	mock.record("ListForgetGroups", txIds)
+	return mock.ListForgetGroupsFunc(txIds)
+}
+
+// EventSubscriberMock is a mock implementation of sdk.EventSubscriber. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type EventSubscriberMock struct {
+	RegisterEventListenerFunc func(scName string, eventFilter string) (*sdk.ListenerController, <-chan *sdk.EventWrapper, error)
+
+	calls
+}
+
+func (mock *EventSubscriberMock) RegisterEventListener(scName string, eventFilter string) (*sdk.ListenerController, <-chan *sdk.EventWrapper, error) {
+	if mock.RegisterEventListenerFunc == nil {
+		panic("EventSubscriberMock.RegisterEventListenerFunc: method is nil but EventSubscriber.RegisterEventListener was just called")
+	}
+	mock.record("RegisterEventListener", scName, eventFilter)
+	return mock.RegisterEventListenerFunc(scName, eventFilter)
+}
+
+// APIMock is a mock implementation of sdk.API. Each method records its call
+// and calls the field of the same name suffixed with Func, which must be set.
+type APIMock struct {
+	InvokeFunc                                  func(smartContractSpec string, args []byte) ([]byte, error)
+	IdentifiedInvokeFunc                        func(smartContractSpec string, args []byte) ([]byte, string, error)
+	ListInvokableSCFunc                         func() ([]byte, error)
+	CreateClientFunc                            func(clientDataJSON []byte) ([]byte, error)
+	CreateUserFunc                              func(userID string, password string, roles []string, domains []string) (string, error)
+	UpdateClientFunc                            func(clientDataJSON []byte) ([]byte, error)
+	UpdateUserFunc                              func(userID string, password string, roles []string, domains []string) (string, error)
+	ListClientFunc                              func(clientID []byte) ([]byte, error)
+	GetUserInfoFunc                             func(clientID string) (sdk.UserFullData, error)
+	ListClientsFunc                             func(query []byte) ([]byte, error)
+	GetUserInfosFunc                            func(allDomains bool, domainName string) ([]sdk.UserFullDataWrapper, error)
+	RemoveClientFunc                            func(clientDomainDataJSON []byte) ([]byte, error)
+	DeleteUserFunc                              func(userID string, userDomainName string) (string, error)
+	CheckApiAccessFunc                          func(apiAccessControllerJSON []byte) ([]byte, error)
+	ManageApiAccessFunc                         func(in []byte) ([]byte, error)
+	CreateDomainFunc                            func(domainName []byte) ([]byte, error)
+	ListDomainFunc                              func(domainName []byte) ([]byte, error)
+	ListManagedDomainsFunc                      func(userID []byte) ([]byte, error)
+	GrantDomainAdminFunc                        func(in []byte) ([]byte, error)
+	RevokeDomainAdminFunc                       func(in []byte) ([]byte, error)
+	RegisterSmartContractFunc                   func(scRegistration []byte) ([]byte, error)
+	ListSmartContractFunc                       func(scName []byte) ([]byte, error)
+	ListSmartContractsFunc                      func(query []byte) ([]byte, error)
+	GrantAccessFunc                             func(clientAccessDataJSON []byte) ([]byte, error)
+	RevokeAccessFunc                            func(clientAccessDataJSON []byte) ([]byte, error)
+	GetBlockchainSummaryJsonFunc                func() ([]byte, error)
+	GetBlockchainSummaryFunc                    func() (sdk.BlockchainSummary, error)
+	GetBlockDetailsJsonFunc                     func(chainID string, blockID string) ([]byte, error)
+	CalculateBlockHashFunc                      func(chainID string, blockID string) ([]byte, error)
+	GetSmartContractTransactionJsonFunc         func(transactionId string) ([]byte, error)
+	GetSmartContractTransactionMetadataJsonFunc func(transactionId string) ([]byte, error)
+	ListLatestTransactionsFunc                  func(count int) ([]byte, error)
+	RequestForgetFunc                           func(txIds []string) (string, error)
+	ApproveForgetFunc                           func(forgetRequestTxID string) (string, error)
+	CommitForgetFunc                            func(forgetRequestTxID string, forgetApprovalTxID []string) (sdk.ForgetReport, error)
+	ListForgetGroupsFunc                        func(txIds []string) ([]sdk.ForgetGroup, error)
+	RegisterEventListenerFunc                   func(scName string, eventFilter string) (*sdk.ListenerController, <-chan *sdk.EventWrapper, error)
+
+	calls
+}
+
+func (mock *APIMock) Invoke(smartContractSpec string, args []byte) ([]byte, error) {
+	if mock.InvokeFunc == nil {
+		panic("APIMock.InvokeFunc: method is nil but API.Invoke was just called")
+	}
+	mock.record("Invoke", smartContractSpec, args)
+	return mock.InvokeFunc(smartContractSpec, args)
+}
+
+func (mock *APIMock) IdentifiedInvoke(smartContractSpec string, args []byte) ([]byte, string, error) {
+	if mock.IdentifiedInvokeFunc == nil {
+		panic("APIMock.IdentifiedInvokeFunc: method is nil but API.IdentifiedInvoke was just called")
+	}
+	mock.record("IdentifiedInvoke", smartContractSpec, args)
+	return mock.IdentifiedInvokeFunc(smartContractSpec, args)
+}
+
+func (mock *APIMock) ListInvokableSC() ([]byte, error) {
+	if mock.ListInvokableSCFunc == nil {
+		panic("APIMock.ListInvokableSCFunc: method is nil but API.ListInvokableSC was just called")
+	}
+	mock.record("ListInvokableSC")
+	return mock.ListInvokableSCFunc()
+}
+
+func (mock *APIMock) CreateClient(clientDataJSON []byte) ([]byte, error) {
+	if mock.CreateClientFunc == nil {
+		panic("APIMock.CreateClientFunc: method is nil but API.CreateClient was just called")
+	}
+	mock.record("CreateClient", clientDataJSON)
+	return mock.CreateClientFunc(clientDataJSON)
+}
+
+func (mock *APIMock) CreateUser(userID string, password string, roles []string, domains []string) (string, error) {
+	if mock.CreateUserFunc == nil {
+		panic("APIMock.CreateUserFunc: method is nil but API.CreateUser was just called")
+	}
+	mock.record("CreateUser", userID, password, roles, domains)
+	return mock.CreateUserFunc(userID, password, roles, domains)
+}
+
+func (mock *APIMock) UpdateClient(clientDataJSON []byte) ([]byte, error) {
+	if mock.UpdateClientFunc == nil {
+		panic("APIMock.UpdateClientFunc: method is nil but API.UpdateClient was just called")
+	}
+	mock.record("UpdateClient", clientDataJSON)
+	return mock.UpdateClientFunc(clientDataJSON)
+}
+
+func (mock *APIMock) UpdateUser(userID string, password string, roles []string, domains []string) (string, error) {
+	if mock.UpdateUserFunc == nil {
+		panic("APIMock.UpdateUserFunc: method is nil but API.UpdateUser was just called")
+	}
+	mock.record("UpdateUser", userID, password, roles, domains)
+	return mock.UpdateUserFunc(userID, password, roles, domains)
+}
+
+func (mock *APIMock) ListClient(clientID []byte) ([]byte, error) {
+	if mock.ListClientFunc == nil {
+		panic("APIMock.ListClientFunc: method is nil but API.ListClient was just called")
+	}
+	mock.record("ListClient", clientID)
+	return mock.ListClientFunc(clientID)
+}
+
+func (mock *APIMock) GetUserInfo(clientID string) (sdk.UserFullData, error) {
+	if mock.GetUserInfoFunc == nil {
+		panic("APIMock.GetUserInfoFunc: method is nil but API.GetUserInfo was just called")
+	}
+	mock.record("GetUserInfo", clientID)
+	return mock.GetUserInfoFunc(clientID)
+}
+
+func (mock *APIMock) ListClients(query []byte) ([]byte, error) {
+	if mock.ListClientsFunc == nil {
+		panic("APIMock.ListClientsFunc: method is nil but API.ListClients was just called")
+	}
+	mock.record("ListClients", query)
+	return mock.ListClientsFunc(query)
+}
+
+func (mock *APIMock) GetUserInfos(allDomains bool, domainName string) ([]sdk.UserFullDataWrapper, error) {
+	if mock.GetUserInfosFunc == nil {
+		panic("APIMock.GetUserInfosFunc: method is nil but API.GetUserInfos was just called")
+	}
+	mock.record("GetUserInfos", allDomains, domainName)
+	return mock.GetUserInfosFunc(allDomains, domainName)
+}
+
+func (mock *APIMock) RemoveClient(clientDomainDataJSON []byte) ([]byte, error) {
+	if mock.RemoveClientFunc == nil {
+		panic("APIMock.RemoveClientFunc: method is nil but API.RemoveClient was just called")
+	}
+	mock.record("RemoveClient", clientDomainDataJSON)
+	return mock.RemoveClientFunc(clientDomainDataJSON)
+}
+
+func (mock *APIMock) DeleteUser(userID string, userDomainName string) (string, error) {
+	if mock.DeleteUserFunc == nil {
+		panic("APIMock.DeleteUserFunc: method is nil but API.DeleteUser was just called")
+	}
+	mock.record("DeleteUser", userID, userDomainName)
+	return mock.DeleteUserFunc(userID, userDomainName)
+}
+
+func (mock *APIMock) CheckApiAccess(apiAccessControllerJSON []byte) ([]byte, error) {
+	if mock.CheckApiAccessFunc == nil {
+		panic("APIMock.CheckApiAccessFunc: method is nil but API.CheckApiAccess was just called")
+	}
+	mock.record("CheckApiAccess", apiAccessControllerJSON)
+	return mock.CheckApiAccessFunc(apiAccessControllerJSON)
+}
+
+func (mock *APIMock) ManageApiAccess(in []byte) ([]byte, error) {
+	if mock.ManageApiAccessFunc == nil {
+		panic("APIMock.ManageApiAccessFunc: method is nil but API.ManageApiAccess was just called")
+	}
+	mock.record("ManageApiAccess", in)
+	return mock.ManageApiAccessFunc(in)
+}
+
+func (mock *APIMock) CreateDomain(domainName []byte) ([]byte, error) {
+	if mock.CreateDomainFunc == nil {
+		panic("APIMock.CreateDomainFunc: method is nil but API.CreateDomain was just called")
+	}
+	mock.record("CreateDomain", domainName)
+	return mock.CreateDomainFunc(domainName)
+}
+
+func (mock *APIMock) ListDomain(domainName []byte) ([]byte, error) {
+	if mock.ListDomainFunc == nil {
+		panic("APIMock.ListDomainFunc: method is nil but API.ListDomain was just called")
+	}
+	mock.record("ListDomain", domainName)
+	return mock.ListDomainFunc(domainName)
+}
+
+func (mock *APIMock) ListManagedDomains(userID []byte) ([]byte, error) {
+	if mock.ListManagedDomainsFunc == nil {
+		panic("APIMock.ListManagedDomainsFunc: method is nil but API.ListManagedDomains was just called")
+	}
+	mock.record("ListManagedDomains", userID)
+	return mock.ListManagedDomainsFunc(userID)
+}
+
+func (mock *APIMock) GrantDomainAdmin(in []byte) ([]byte, error) {
+	if mock.GrantDomainAdminFunc == nil {
+		panic("APIMock.GrantDomainAdminFunc: method is nil but API.GrantDomainAdmin was just called")
+	}
+	mock.record("GrantDomainAdmin", in)
+	return mock.GrantDomainAdminFunc(in)
+}
+
+func (mock *APIMock) RevokeDomainAdmin(in []byte) ([]byte, error) {
+	if mock.RevokeDomainAdminFunc == nil {
+		panic("APIMock.RevokeDomainAdminFunc: method is nil but API.RevokeDomainAdmin was just called")
+	}
+	mock.record("RevokeDomainAdmin", in)
+	return mock.RevokeDomainAdminFunc(in)
+}
+
+func (mock *APIMock) RegisterSmartContract(scRegistration []byte) ([]byte, error) {
+	if mock.RegisterSmartContractFunc == nil {
+		panic("APIMock.RegisterSmartContractFunc: method is nil but API.RegisterSmartContract was just called")
+	}
+	mock.record("RegisterSmartContract", scRegistration)
+	return mock.RegisterSmartContractFunc(scRegistration)
+}
+
+func (mock *APIMock) ListSmartContract(scName []byte) ([]byte, error) {
+	if mock.ListSmartContractFunc == nil {
+		panic("APIMock.ListSmartContractFunc: method is nil but API.ListSmartContract was just called")
+	}
+	mock.record("ListSmartContract", scName)
+	return mock.ListSmartContractFunc(scName)
+}
+
+func (mock *APIMock) ListSmartContracts(query []byte) ([]byte, error) {
+	if mock.ListSmartContractsFunc == nil {
+		panic("APIMock.ListSmartContractsFunc: method is nil but API.ListSmartContracts was just called")
+	}
+	mock.record("ListSmartContracts", query)
+	return mock.ListSmartContractsFunc(query)
+}
+
+func (mock *APIMock) GrantAccess(clientAccessDataJSON []byte) ([]byte, error) {
+	if mock.GrantAccessFunc == nil {
+		panic("APIMock.GrantAccessFunc: method is nil but API.GrantAccess was just called")
+	}
+	mock.record("GrantAccess", clientAccessDataJSON)
+	return mock.GrantAccessFunc(clientAccessDataJSON)
+}
+
+func (mock *APIMock) RevokeAccess(clientAccessDataJSON []byte) ([]byte, error) {
+	if mock.RevokeAccessFunc == nil {
+		panic("APIMock.RevokeAccessFunc: method is nil but API.RevokeAccess was just called")
+	}
+	mock.record("RevokeAccess", clientAccessDataJSON)
+	return mock.RevokeAccessFunc(clientAccessDataJSON)
+}
+
+func (mock *APIMock) GetBlockchainSummaryJson() ([]byte, error) {
+	if mock.GetBlockchainSummaryJsonFunc == nil {
+		panic("APIMock.GetBlockchainSummaryJsonFunc: method is nil but API.GetBlockchainSummaryJson was just called")
+	}
+	mock.record("GetBlockchainSummaryJson")
+	return mock.GetBlockchainSummaryJsonFunc()
+}
+
+func (mock *APIMock) GetBlockchainSummary() (sdk.BlockchainSummary, error) {
+	if mock.GetBlockchainSummaryFunc == nil {
+		panic("APIMock.GetBlockchainSummaryFunc: method is nil but API.GetBlockchainSummary was just called")
+	}
+	mock.record("GetBlockchainSummary")
+	return mock.GetBlockchainSummaryFunc()
+}
+
+func (mock *APIMock) GetBlockDetailsJson(chainID string, blockID string) ([]byte, error) {
+	if mock.GetBlockDetailsJsonFunc == nil {
+		panic("APIMock.GetBlockDetailsJsonFunc: method is nil but API.GetBlockDetailsJson was just called")
+	}
+	mock.record("GetBlockDetailsJson", chainID, blockID)
+	return mock.GetBlockDetailsJsonFunc(chainID, blockID)
+}
+
+func (mock *APIMock) CalculateBlockHash(chainID string, blockID string) ([]byte, error) {
+	if mock.CalculateBlockHashFunc == nil {
+		panic("APIMock.CalculateBlockHashFunc: method is nil but API.CalculateBlockHash was just called")
+	}
+	mock.record("CalculateBlockHash", chainID, blockID)
+	return mock.CalculateBlockHashFunc(chainID, blockID)
+}
+
+func (mock *APIMock) GetSmartContractTransactionJson(transactionId string) ([]byte, error) {
+	if mock.GetSmartContractTransactionJsonFunc == nil {
+		panic("APIMock.GetSmartContractTransactionJsonFunc: method is nil but API.GetSmartContractTransactionJson was just called")
+	}
+	mock.record("GetSmartContractTransactionJson", transactionId)
+	return mock.GetSmartContractTransactionJsonFunc(transactionId)
+}
+
+func (mock *APIMock) GetSmartContractTransactionMetadataJson(transactionId string) ([]byte, error) {
+	if mock.GetSmartContractTransactionMetadataJsonFunc == nil {
+		panic("APIMock.GetSmartContractTransactionMetadataJsonFunc: method is nil but API.GetSmartContractTransactionMetadataJson was just called")
+	}
+	mock.record("GetSmartContractTransactionMetadataJson", transactionId)
+	return mock.GetSmartContractTransactionMetadataJsonFunc(transactionId)
+}
+
+func (mock *APIMock) ListLatestTransactions(count int) ([]byte, error) {
+	if mock.ListLatestTransactionsFunc == nil {
+		panic("APIMock.ListLatestTransactionsFunc: method is nil but API.ListLatestTransactions was just called")
+	}
+	mock.record("ListLatestTransactions", count)
+	return mock.ListLatestTransactionsFunc(count)
+}
+
+func (mock *APIMock) RequestForget(txIds []string) (string, error) {
+	if mock.RequestForgetFunc == nil {
+		panic("APIMock.RequestForgetFunc: method is nil but API.RequestForget was just called")
+	}
+	mock.record("RequestForget", txIds)
+	return mock.RequestForgetFunc(txIds)
+}
+
+func (mock *APIMock) ApproveForget(forgetRequestTxID string) (string, error) {
+	if mock.ApproveForgetFunc == nil {
+		panic("APIMock.ApproveForgetFunc: method is nil but API.ApproveForget was just called")
+	}
+	mock.record("ApproveForget", forgetRequestTxID)
+	return mock.ApproveForgetFunc(forgetRequestTxID)
+}
+
+func (mock *APIMock) CommitForget(forgetRequestTxID string, forgetApprovalTxID []string) (sdk.ForgetReport, error) {
+	if mock.CommitForgetFunc == nil {
+		panic("APIMock.CommitForgetFunc: method is nil but API.CommitForget was just called")
+	}
+	mock.record("CommitForget", forgetRequestTxID, forgetApprovalTxID)
+	return mock.CommitForgetFunc(forgetRequestTxID, forgetApprovalTxID)
+}
+
+func (mock *APIMock) ListForgetGroups(txIds []string) ([]sdk.ForgetGroup, error) {
+	if mock.ListForgetGroupsFunc == nil {
+		panic("APIMock.ListForgetGroupsFunc: method is nil but API.ListForgetGroups was just called")
+	}
+	mock.record("ListForgetGroups", txIds)
+	return mock.ListForgetGroupsFunc(txIds)
+}
+
+func (mock *APIMock) RegisterEventListener(scName string, eventFilter string) (*sdk.ListenerController, <-chan *sdk.EventWrapper, error) {
+	if mock.RegisterEventListenerFunc == nil {
+		panic("APIMock.RegisterEventListenerFunc: method is nil but API.RegisterEventListener was just called")
+	}
+	mock.record("RegisterEventListener", scName, eventFilter)
+	return mock.RegisterEventListenerFunc(scName, eventFilter)
+}
@@ -0,0 +1,134 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package mocks_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/mocks"
+)
+
+func transfer(invoker sdk.Invoker, amount string) (string, error) {
+	_, commitID, err := invoker.IdentifiedInvoke("ledger-v1", []byte(amount))
+	return commitID, err
+}
+
+func TestInvokerMock(t *testing.T) {
+	invoker := &mocks.InvokerMock{
+		IdentifiedInvokeFunc: func(smartContractSpec string, args []byte) ([]byte, string, error) {
+			return nil, "tx1", nil
+		},
+	}
+
+	commitID, err := transfer(invoker, "42")
+	if err != nil || commitID != "tx1" {
+		t.Fatalf("transfer = %q, %v", commitID, err)
+	}
+	calls := invoker.Calls("IdentifiedInvoke")
+	if len(calls) != 1 || calls[0].Args[0] != "ledger-v1" || string(calls[0].Args[1].([]byte)) != "42" {
+		t.Errorf("Calls = %+v", calls)
+	}
+	if len(invoker.Calls("Invoke")) != 0 {
+		t.Error("Invoke was recorded")
+	}
+}
+
+// TestMocksUpToDate regenerates the mocks and compares them with mocks.go, so that the
+// mocks cannot drift from the interfaces they implement.
+func TestMocksUpToDate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mocks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "mocks.go")
+	cmd := exec.Command("go", "run", "./mocks/gen.go", "-source", "api_interfaces.go", "-output", output)
+	cmd.Dir = ".."
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("go run ./mocks/gen.go: %v\n%s", err, out)
+	}
+	generated, err := ioutil.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	committed, err := ioutil.ReadFile("mocks.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(generated, committed) {
+		t.Error("mocks.go is out of date: run go generate from the SDK's root directory")
+	}
+}
+
+// TestEveryMock calls every method of every mock, checking that it calls the matching
+// Func field with its arguments and records the call, and that it panics if the field
+// is not set.
+func TestEveryMock(t *testing.T) {
+	for _, mock := range []interface{}{
+		&mocks.InvokerMock{},
+		&mocks.UserAdminMock{},
+		&mocks.DomainAdminMock{},
+		&mocks.SmartContractRegistryMock{},
+		&mocks.BlockchainReaderMock{},
+		&mocks.ForgetAdminMock{},
+		&mocks.EventSubscriberMock{},
+		&mocks.APIMock{},
+	} {
+		value := reflect.ValueOf(mock)
+		name := value.Elem().Type().Name()
+		for i := 0; i < value.NumMethod(); i++ {
+			method := value.Type().Method(i)
+			if method.Name == "Calls" {
+				continue
+			}
+			field := value.Elem().FieldByName(method.Name + "Func")
+			if !field.IsValid() {
+				t.Errorf("%s.%s has no %sFunc field", name, method.Name, method.Name)
+				continue
+			}
+
+			args := make([]reflect.Value, method.Type.NumIn()-1)
+			for j := range args {
+				args[j] = reflect.Zero(method.Type.In(j + 1))
+			}
+			if !panics(func() { value.Method(i).Call(args) }) {
+				t.Errorf("%s.%s did not panic without %sFunc", name, method.Name, method.Name)
+			}
+
+			called := false
+			field.Set(reflect.MakeFunc(field.Type(), func(in []reflect.Value) []reflect.Value {
+				called = len(in) == len(args)
+				results := make([]reflect.Value, field.Type().NumOut())
+				for j := range results {
+					results[j] = reflect.Zero(field.Type().Out(j))
+				}
+				return results
+			}))
+			value.Method(i).Call(args)
+			if !called {
+				t.Errorf("%s.%s did not call %sFunc", name, method.Name, method.Name)
+			}
+			calls := value.MethodByName("Calls").Call([]reflect.Value{reflect.ValueOf(method.Name)})[0]
+			if calls.Len() != 1 || calls.Index(0).FieldByName("Args").Len() != len(args) {
+				t.Errorf("%s.%s recorded %v", name, method.Name, calls)
+			}
+		}
+	}
+}
+
+func panics(f func()) (panicked bool) {
+	defer func() { panicked = recover() != nil }()
+	f()
+	return false
+}
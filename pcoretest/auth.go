@@ -0,0 +1,115 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package pcoretest
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type tokenClaims struct {
+	Subject  string   `json:"sub"`
+	ClientID string   `json:"clientId"`
+	Roles    []string `json:"roles"`
+	Domains  []string `json:"domains"`
+	IssuedAt int64    `json:"iat"`
+	Expiry   int64    `json:"exp"`
+	ID       string   `json:"jti"`
+}
+
+// TokenSecret returns the HMAC secret s signs its HS256 tokens with, so that tests
+// can verify them (see sdk.WithTokenVerificationKey).
+func (s *Server) TokenSecret() []byte {
+	return append([]byte(nil), s.secret...)
+}
+
+// RevokeToken makes s reject token from now on, as if its session had been terminated.
+func (s *Server) RevokeToken(token string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[token] = true
+}
+
+// issueToken returns a new token for u, in the "<token> <expireTimestamp>" format
+// of Auth and Renew responses. It must be called with s.mu held.
+func (s *Server) issueToken(u *user) string {
+	now := s.now()
+	claims := tokenClaims{
+		Subject:  u.id,
+		ClientID: u.id,
+		Roles:    u.roles,
+		Domains:  sortedKeys(u.domains),
+		IssuedAt: now.Unix(),
+		Expiry:   now.Add(s.tokenTTL).Unix(),
+		ID:       s.nextID("jti"),
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signed))
+	token := signed + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	return fmt.Sprintf("%s %d", token, claims.Expiry)
+}
+
+// caller returns the user whose token is attached to ctx. It must be called with
+// s.mu held.
+func (s *Server) caller(ctx context.Context) (*user, string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var lastError error = status.Error(codes.Unauthenticated, "pcoretest: missing bearer token")
+	for _, value := range md.Get("authorization") {
+		token := strings.TrimPrefix(value, "Bearer ")
+		u, err := s.checkToken(token)
+		if err == nil {
+			return u, token, nil
+		}
+		lastError = err
+	}
+	return nil, "", lastError
+}
+
+func (s *Server) checkToken(token string) (*user, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, status.Error(codes.Unauthenticated, "pcoretest: malformed token")
+	}
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, status.Error(codes.Unauthenticated, "pcoretest: invalid token signature")
+	}
+	if s.revoked[token] {
+		return nil, status.Error(codes.Unauthenticated, "pcoretest: token revoked")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "pcoretest: malformed token")
+	}
+	var claims tokenClaims
+	if err = json.Unmarshal(payload, &claims); err != nil {
+		return nil, status.Error(codes.Unauthenticated, "pcoretest: malformed token")
+	}
+	if !s.now().Before(time.Unix(claims.Expiry, 0)) {
+		return nil, status.Error(codes.Unauthenticated, "pcoretest: token expired")
+	}
+	u := s.users[claims.ClientID]
+	if u == nil {
+		return nil, status.Error(codes.Unauthenticated, "pcoretest: unknown client")
+	}
+	return u, nil
+}
@@ -0,0 +1,257 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package pcoretest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+)
+
+// HandlerFunc implements a smart contract in a Server. What it returns is what
+// Invoke returns; a non-nil error fails the invocation and discards its writes.
+type HandlerFunc func(sc *Context) ([]byte, error)
+
+// Context is the environment of one smart contract invocation. Writes and events
+// are buffered, and only take effect if the handler succeeds. An invocation that
+// writes nothing is read-only, and has no commit ID.
+//
+// Handlers run concurrently. As on a ledger, an invocation that writes is only
+// committed if the keys it read have not been written since: otherwise its handler
+// runs again, with a new Context.
+type Context struct {
+	// Spec is the <name>-v<version> of the invoked smart contract, with the version
+	// resolved (never "*").
+	Spec string
+	// ClientID identifies the invoking user.
+	ClientID string
+	// Args is the raw argument of the invocation.
+	Args []byte
+	// Task is Args decoded as a pb.ScTask (as sent by CallSmartContract). It is
+	// empty if Args is not a JSON-encoded ScTask.
+	Task pb.ScTask
+
+	s      *Server
+	space  string
+	writes map[string][]byte
+	// reads holds the version of the keys read from the store when first read.
+	reads  map[string]uint64
+	events []event
+}
+
+// DecodeArgs returns Args decoded as sent by InvokeArgs and InvokeValues (see
+// sdk.DecodeArgs).
+func (sc *Context) DecodeArgs() ([]string, error) {
+	return sdk.DecodeArgs(sc.Args)
+}
+
+// Get returns the value of key in the smart contract's key-value store, seeing the
+// invocation's own writes.
+func (sc *Context) Get(key string) ([]byte, bool) {
+	if value, written := sc.writes[key]; written {
+		return value, value != nil
+	}
+	sc.s.mu.Lock()
+	defer sc.s.mu.Unlock()
+	if _, read := sc.reads[key]; !read {
+		sc.reads[key] = sc.s.versions[sc.space][key]
+	}
+	value, ok := sc.s.store[sc.space][key]
+	if ok {
+		// The handler may modify the value it reads.
+		value = append([]byte{}, value...)
+	}
+	return value, ok
+}
+
+// Put sets key to value in the smart contract's key-value store.
+func (sc *Context) Put(key string, value []byte) {
+	sc.writes[key] = append([]byte{}, value...)
+}
+
+// Delete removes key from the smart contract's key-value store.
+func (sc *Context) Delete(key string) {
+	sc.writes[key] = nil
+}
+
+// RecordIdempotencyKey records the idempotency key of Task, as smart contracts invoked
+// through sdk.IdempotentCaller do (see sdk.IdempotencyMutationKey). It reports false if
+// Task has no key, or if an earlier invocation recorded it.
+func (sc *Context) RecordIdempotencyKey() bool {
+	if sc.Task.IdempotencyKey == "" {
+		return false
+	}
+	key := sdk.IdempotencyMutationKey(sc.Task.IdempotencyKey)
+	if _, recorded := sc.Get(key); recorded {
+		return false
+	}
+	sc.Put(key, []byte(sc.Task.Action))
+	return true
+}
+
+// Emit sends an event to the listeners of the smart contract once the invocation
+// completes, carrying the invocation's transaction ID.
+func (sc *Context) Emit(eventName string, payload string) {
+	sc.events = append(sc.events, event{ScName: sc.space, EventName: eventName, Payload: payload})
+}
+
+// Handle makes h the implementation of the smart contracts matching spec, which is
+// either <name>-v<version> or <name>-v* for every version. An exact spec takes
+// precedence over a wildcard. The smart contract must still be registered (see
+// AddSmartContract) to be invoked.
+func (s *Server) Handle(spec string, h HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.handlers[spec] = h
+}
+
+// Value returns the value of key in the key-value store of smart contract scName.
+func (s *Server) Value(scName string, key string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	value, ok := s.store[scName][key]
+	return value, ok
+}
+
+// maxConflicts bounds the number of times an invocation is run again because of
+// conflicting ones.
+const maxConflicts = 100
+
+// invoke runs the handler of the smart contract named by an Invoke payload
+// ("<spec> <args>"), and returns its result and commit ID. The handler runs without
+// s.mu held, so that it may use the Server's methods, and again as long as its
+// invocation conflicts with others (see Context).
+func (s *Server) invoke(ctx context.Context, payload []byte) ([]byte, string, error) {
+	s.mu.Lock()
+	caller, _, err := s.caller(ctx)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, "", err
+	}
+	c, args, err := s.resolveInvocation(caller, payload)
+	if err != nil {
+		s.mu.Unlock()
+		return nil, "", err
+	}
+	h := s.handlers[c.spec()]
+	if h == nil {
+		h = s.handlers[c.name+"-v*"]
+	}
+	s.mu.Unlock()
+	if h == nil {
+		return nil, "", fmt.Errorf("Smart contract %s has no handler", c.spec())
+	}
+
+	var sc *Context
+	var result []byte
+	var txID string
+	for conflicts := 0; ; conflicts++ {
+		sc = &Context{Spec: c.spec(), ClientID: caller.id, Args: args, s: s, space: c.name, writes: make(map[string][]byte), reads: make(map[string]uint64)}
+		json.Unmarshal(args, &sc.Task)
+		result, err = h(sc)
+		if err != nil {
+			return nil, "", err
+		}
+
+		s.mu.Lock()
+		if !s.conflicts(sc) {
+			break
+		}
+		s.mu.Unlock()
+		if conflicts == maxConflicts {
+			return nil, "", fmt.Errorf("Smart contract %s: Too many conflicting invocations", c.spec())
+		}
+	}
+	s.serving = nodeOf(ctx)
+	txID = s.commit(sc, caller, args)
+	eventTxID := txID
+	if eventTxID == "" {
+		eventTxID = s.nextID("ev")
+	}
+	s.mu.Unlock()
+
+	for _, each := range sc.events {
+		each.TxId = eventTxID
+		s.emit(each)
+	}
+	return result, txID, nil
+}
+
+// conflicts reports whether sc writes, and read keys that were written since. It must
+// be called with s.mu held.
+func (s *Server) conflicts(sc *Context) bool {
+	if len(sc.writes) == 0 {
+		return false
+	}
+	for key, version := range sc.reads {
+		if s.versions[sc.space][key] != version {
+			return true
+		}
+	}
+	return false
+}
+
+// commit applies the writes of sc as a new transaction, and returns its ID, or ""
+// for a read-only invocation. It must be called with s.mu held.
+func (s *Server) commit(sc *Context, caller *user, args []byte) string {
+	if len(sc.writes) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(sc.writes))
+	for key := range sc.writes {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	if s.store[sc.space] == nil {
+		s.store[sc.space] = make(map[string][]byte)
+		s.versions[sc.space] = make(map[string]uint64)
+	}
+	mutations := make([]mutation, 0, len(keys))
+	for _, key := range keys {
+		value := sc.writes[key]
+		if value == nil {
+			delete(s.store[sc.space], key)
+		} else {
+			s.store[sc.space][key] = value
+		}
+		s.versions[sc.space][key]++
+		mutations = append(mutations, mutation{Key: []byte(key), Value: value})
+	}
+
+	tx := &transaction{id: s.nextID("tx"), scName: sc.space, clientID: caller.id, payload: args, mutations: mutations}
+	s.chain.append(tx, s.now())
+	s.hideFromLagging(tx.id)
+	return tx.id
+}
+
+func (s *Server) resolveInvocation(caller *user, payload []byte) (*contract, []byte, error) {
+	spec, args := string(payload), []byte{}
+	if i := strings.IndexByte(spec, ' '); i >= 0 {
+		spec, args = spec[:i], payload[i+1:]
+	}
+	name, version := splitSpec(spec)
+	family := s.contracts[name]
+	if family == nil {
+		return nil, nil, fmt.Errorf("Smart contract %s does not exist", spec)
+	}
+	c := family.latest()
+	if version != "" && version != "*" {
+		c = family.versions[version]
+	}
+	if c == nil {
+		return nil, nil, fmt.Errorf("Smart contract %s does not exist", spec)
+	}
+	if !caller.isSuperAdmin() && !family.accessList[caller.id] {
+		return nil, nil, fmt.Errorf("Client %s has no access to smart contract %s", caller.id, name)
+	}
+	return c, args, nil
+}
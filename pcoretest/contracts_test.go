@@ -0,0 +1,194 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package pcoretest_test
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func counter(sc *pcoretest.Context) ([]byte, error) {
+	switch sc.Task.Action {
+	case "get":
+		value, _ := sc.Get(sc.Task.Data)
+		return value, nil
+	case "set":
+		sc.Put(sc.Task.Data, []byte("1"))
+		sc.Emit("set", sc.Task.Data)
+		return []byte("ok"), nil
+	}
+	return nil, errors.New("unknown action")
+}
+
+func TestHandlers(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	server.AddSmartContract("counter", "1", sdk.DOMAIN_DEFAULT)
+	server.AddSmartContract("counter", "2", sdk.DOMAIN_DEFAULT)
+	server.Handle("counter-v*", counter)
+	server.Handle("counter-v1", func(*pcoretest.Context) ([]byte, error) { return []byte("v1"), nil })
+
+	if out, err := root.Invoke("counter-v1", nil); err != nil || string(out) != "v1" {
+		t.Errorf("Invoke(counter-v1) = %q, %v", out, err)
+	}
+
+	task, _ := json.Marshal(map[string]string{"action": "set", "data": "alice"})
+	out, commitID, err := root.IdentifiedInvoke("counter-v2", task)
+	if err != nil || string(out) != "ok" || commitID == "" {
+		t.Fatalf("IdentifiedInvoke = %q, %q, %v", out, commitID, err)
+	}
+	if value, ok := server.Value("counter", "alice"); !ok || string(value) != "1" {
+		t.Errorf("Value = %q, %v", value, ok)
+	}
+
+	text, err := sdk.CallSmartContractText(root, "counter", "get", "alice")
+	if err != nil || text != "1" {
+		t.Errorf("CallSmartContractText = %q, %v", text, err)
+	}
+	_, readOnlyID, err := root.IdentifiedInvoke("counter-v2", []byte(`{"action":"get","data":"alice"}`))
+	if err != nil || readOnlyID != "" {
+		t.Errorf("read-only IdentifiedInvoke = %q, %v", readOnlyID, err)
+	}
+	if _, err = sdk.CallSmartContract(root, "counter", "bogus", nil); err == nil {
+		t.Error("handler error was not reported")
+	}
+
+	var details struct {
+		Mutations []struct{ Key, Value []byte }
+	}
+	raw, err := root.GetSmartContractTransactionJson(commitID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = json.Unmarshal(raw, &details); err != nil || len(details.Mutations) != 1 || string(details.Mutations[0].Key) != "alice" {
+		t.Errorf("GetSmartContractTransactionJson = %s, %v", raw, err)
+	}
+}
+
+func TestHandlerEvents(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+	server.AddSmartContract("counter", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("counter-v*", counter)
+
+	listening, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, events, err := listening.RegisterEventListener("counter", ".*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controller.Close()
+
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	_, commitID, err := client.IdentifiedInvoke("counter-v1", []byte(`{"action":"set","data":"bob"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case e := <-events:
+		if e.Error != nil || e.ScEvent.TxId != commitID || e.ScEvent.Payload != "bob" {
+			t.Errorf("event = %+v, %v", e.ScEvent, e.Error)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+}
+
+func TestInvokeArgs(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	server.AddSmartContract("echo", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("echo-v1", func(sc *pcoretest.Context) ([]byte, error) {
+		args, err := sc.DecodeArgs()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(args)
+	})
+
+	out, err := root.InvokeValues("echo-v1", "two words", "line\nbreak", 42, map[string]string{"k": "v"})
+	if err != nil || string(out) != `["two words","line\nbreak","42","{\"k\":\"v\"}"]` {
+		t.Errorf("InvokeValues = %s, %v", out, err)
+	}
+	if out, err = root.InvokeArgs("echo-v1", "alice", ""); err != nil || string(out) != `["alice",""]` {
+		t.Errorf("InvokeArgs = %s, %v", out, err)
+	}
+}
+
+func TestCodecs(t *testing.T) {
+	server, err := pcoretest.NewServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+	server.AddSmartContract("blobs", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("blobs-v1", func(sc *pcoretest.Context) ([]byte, error) {
+		var blob []byte
+		if _, err := sdk.DecodeTask(sc.Args, &blob); err != nil {
+			return nil, err
+		}
+		// Results are raw bytes, encoded with the same codec.
+		return sdk.Base64Codec.Marshal(append(blob, 0xff))
+	})
+
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword, sdk.WithCodec("blobs", sdk.Base64Codec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var result []byte
+	if _, err = sdk.CallSmartContractJSON(client, "blobs", "store", []byte{0x00, 0xfe}, &result); err != nil || string(result) != "\x00\xfe\xff" {
+		t.Errorf("CallSmartContractJSON = %v, %v", result, err)
+	}
+	plain, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer plain.Close()
+	if _, err = sdk.CallSmartContract(plain, "blobs", "store", []byte{0x00, 0xfe}); err == nil {
+		t.Error("binary data was sent without a codec")
+	}
+}
+
+func TestConflictingHandlers(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	server.AddSmartContract("tally", "1", sdk.DOMAIN_DEFAULT)
+	server.Handle("tally-v*", func(sc *pcoretest.Context) ([]byte, error) {
+		count, _ := sc.Get("count")
+		// Leave time for other invocations to read the same count.
+		time.Sleep(time.Millisecond)
+		count = append(count, '+')
+		sc.Put("count", count)
+		return count, nil
+	})
+
+	const invocations = 20
+	var wg sync.WaitGroup
+	for i := 0; i < invocations; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := root.Invoke("tally-v1", nil); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if count, _ := server.Value("tally", "count"); len(count) != invocations {
+		t.Errorf("count = %q after %d concurrent increments", count, invocations)
+	}
+}
@@ -0,0 +1,238 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package pcoretest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"google.golang.org/grpc/status"
+)
+
+// handler implements pb.RequestHandlerServer on top of a Server.
+type handler struct {
+	s *Server
+}
+
+// operation is the server side of one ParallelCore API call, run with s.mu held.
+type operation func(caller *user, payload []byte) ([]byte, error)
+
+// serve authenticates the caller and runs op. Errors returned by op are reported in
+// the Response, the way ParallelCore reports failed requests.
+func (s *Server) serve(ctx context.Context, payload []byte, op operation) (*pb.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	caller, _, err := s.caller(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.serving = nodeOf(ctx)
+	out, err := op(caller, payload)
+	if err != nil {
+		return &pb.Response{Error: []byte(err.Error())}, nil
+	}
+	return &pb.Response{Payload: out}, nil
+}
+
+func (h *handler) Auth(ctx context.Context, in *pb.AuthRequest) (*pb.Response, error) {
+	s := h.s
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := s.users[string(in.ClientId)]
+	if u == nil || u.password != string(in.Credential) {
+		return &pb.Response{Error: []byte("Authentication failed: invalid client ID or credential")}, nil
+	}
+	return &pb.Response{Payload: []byte(s.issueToken(u))}, nil
+}
+
+func (h *handler) Renew(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, func(caller *user, _ []byte) ([]byte, error) {
+		return []byte(h.s.issueToken(caller)), nil
+	})
+}
+
+func (h *handler) Ping(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, func(*user, []byte) ([]byte, error) {
+		return []byte("pong"), nil
+	})
+}
+
+func (h *handler) Invoke(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	payload, _, err := h.s.invoke(ctx, in.Payload)
+	if err != nil {
+		if _, isStatus := status.FromError(err); isStatus {
+			return nil, err
+		}
+		return &pb.Response{Error: []byte(err.Error())}, nil
+	}
+	return &pb.Response{Payload: payload}, nil
+}
+
+func (h *handler) IdentifiedInvoke(ctx context.Context, in *pb.Request) (*pb.IdentifiedResponse, error) {
+	payload, commitID, err := h.s.invoke(ctx, in.Payload)
+	if err != nil {
+		if _, isStatus := status.FromError(err); isStatus {
+			return nil, err
+		}
+		return &pb.IdentifiedResponse{Error: []byte(err.Error())}, nil
+	}
+	return &pb.IdentifiedResponse{Payload: payload, CommittedId: []byte(commitID)}, nil
+}
+
+func (h *handler) UserMan(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.userMan)
+}
+
+func (h *handler) SysMan(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.sysMan)
+}
+
+func (h *handler) ManageApiAccess(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.manageApiAccess)
+}
+
+func (h *handler) CheckApiAccess(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.checkApiAccess)
+}
+
+func (h *handler) RegisterSmartContract(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.registerSmartContract)
+}
+
+func (h *handler) ListSmartContract(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.listSmartContract)
+}
+
+func (h *handler) ListSmartContracts(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.listSmartContracts)
+}
+
+func (h *handler) GrantAccess(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.grantAccess)
+}
+
+func (h *handler) RevokeAccess(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.revokeAccess)
+}
+
+func (h *handler) CreateDomain(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.createDomain)
+}
+
+func (h *handler) ListDomain(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.listDomain)
+}
+
+func (h *handler) ListManagedDomains(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.listManagedDomains)
+}
+
+func (h *handler) GrantDomainAdmin(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.grantDomainAdmin)
+}
+
+func (h *handler) RevokeDomainAdmin(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.revokeDomainAdmin)
+}
+
+func (h *handler) CreateClient(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.createClient)
+}
+
+func (h *handler) UpdateClient(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.updateClient)
+}
+
+func (h *handler) ListClient(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.listClient)
+}
+
+func (h *handler) ListClients(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.listClients)
+}
+
+func (h *handler) RemoveClient(ctx context.Context, in *pb.Request) (*pb.Response, error) {
+	return h.s.serve(ctx, in.Payload, h.s.removeClient)
+}
+
+func (h *handler) RegisterEventListener(stream pb.RequestHandler_RegisterEventListenerServer) error {
+	s := h.s
+	ctx := stream.Context()
+
+	s.mu.Lock()
+	_, _, err := s.caller(ctx)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	in, err := stream.Recv()
+	if err != nil {
+		return err
+	}
+	var request sdk.RegisterEventListenerRequest
+	if err = json.Unmarshal(in.Payload, &request); err != nil {
+		return stream.Send(&pb.Response{Error: []byte(fmt.Sprintf("Invalid event listener request: %v", err))})
+	}
+	filter, err := regexp.Compile(request.EventFilter)
+	if err != nil {
+		return stream.Send(&pb.Response{Error: []byte(fmt.Sprintf("Invalid event filter: %v", err))})
+	}
+
+	l := &listener{scName: request.ScName, filter: filter, events: make(chan event, 64), done: make(chan struct{})}
+	s.mu.Lock()
+	s.listeners[l] = true
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.listeners, l)
+		s.mu.Unlock()
+		l.stop()
+	}()
+
+	if err = stream.Send(&pb.Response{Payload: []byte("Successfully registered event listener.")}); err != nil {
+		return err
+	}
+	for {
+		select {
+		case e := <-l.events:
+			payload, _ := json.Marshal(e)
+			if err = stream.Send(&pb.Response{Payload: payload}); err != nil {
+				return err
+			}
+		case <-l.done:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// decode unmarshals a JSON payload, reporting errors the way ParallelCore does.
+func decode(payload []byte, v interface{}) error {
+	if err := json.Unmarshal(payload, v); err != nil {
+		return fmt.Errorf("Invalid request payload: %v", err)
+	}
+	return nil
+}
+
+func splitList(list string) []string {
+	items := make([]string, 0)
+	for _, each := range strings.Split(list, ",") {
+		if each = strings.TrimSpace(each); each != "" {
+			items = append(items, each)
+		}
+	}
+	return items
+}
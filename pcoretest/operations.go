@@ -0,0 +1,945 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package pcoretest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+)
+
+const sysManContract = "sysman"
+
+// administers reports whether u may administer domainName: super-admins administer
+// every domain, other users only the domains they are domain-admin of.
+func (s *Server) administers(u *user, domainName string) bool {
+	return u.isSuperAdmin() || s.manages(u, domainName)
+}
+
+// manages reports whether u is a domain-admin of domainName.
+func (s *Server) manages(u *user, domainName string) bool {
+	d := s.domains[domainName]
+	return d != nil && d.admins[u.id]
+}
+
+func (s *Server) managedDomains(u *user) []string {
+	names := make([]string, 0)
+	for name, d := range s.domains {
+		if d.admins[u.id] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func errPermission(api string) error {
+	return fmt.Errorf("%s: Permission denied", api)
+}
+
+func domainOrDefault(name string) string {
+	if name == "" {
+		return sdk.DOMAIN_DEFAULT
+	}
+	return name
+}
+
+func marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+//
+// Users
+//
+
+func (s *Server) createClient(caller *user, payload []byte) ([]byte, error) {
+	var data sdk.UserData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	if data.ID == "" {
+		return nil, fmt.Errorf("%s: Client ID cannot be empty", sdk.API_CREATE_CLIENT)
+	}
+	if s.users[data.ID] != nil {
+		return nil, fmt.Errorf("%s: Client %s already exists", sdk.API_CREATE_CLIENT, data.ID)
+	}
+	roles := splitList(data.Roles)
+	if len(roles) == 0 {
+		return nil, fmt.Errorf("%s: Client roles cannot be empty", sdk.API_CREATE_CLIENT)
+	}
+	domains := splitList(domainOrDefault(data.DomainName))
+	for _, name := range domains {
+		if s.domains[name] == nil {
+			return nil, fmt.Errorf("%s: Domain %s does not exist", sdk.API_CREATE_CLIENT, name)
+		}
+		if !s.administers(caller, name) {
+			return nil, errPermission(sdk.API_CREATE_CLIENT)
+		}
+	}
+	s.addUser(data.ID, data.Credential, roles, domains)
+	return []byte("true"), nil
+}
+
+func (s *Server) updateClient(caller *user, payload []byte) ([]byte, error) {
+	var data sdk.UserData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	target := s.users[data.ID]
+	if target == nil {
+		return nil, fmt.Errorf("%s: Client %s does not exist", sdk.API_UPDATE_CLIENT, data.ID)
+	}
+	allowed := false
+	for name := range target.domains {
+		allowed = allowed || s.manages(caller, name)
+	}
+	if !allowed {
+		return nil, errPermission(sdk.API_UPDATE_CLIENT)
+	}
+
+	domains := splitList(data.DomainName)
+	for _, name := range domains {
+		if s.domains[name] == nil {
+			return nil, fmt.Errorf("%s: Domain %s does not exist", sdk.API_UPDATE_CLIENT, name)
+		}
+		if !s.manages(caller, name) {
+			return nil, errPermission(sdk.API_UPDATE_CLIENT)
+		}
+	}
+	if data.Credential != "" {
+		target.password = data.Credential
+	}
+	if roles := splitList(data.Roles); len(roles) != 0 {
+		target.roles = roles
+	}
+	if len(domains) != 0 {
+		for name := range target.domains {
+			delete(s.domains[name].clients, target.id)
+			delete(s.domains[name].admins, target.id)
+		}
+		target.domains = make(map[string]bool)
+		for _, name := range domains {
+			s.domains[name].clients[target.id] = true
+			target.domains[name] = true
+		}
+	}
+	return []byte("true"), nil
+}
+
+func (s *Server) fullData(u *user) sdk.UserFullData {
+	accessList := make([]string, 0)
+	for name, family := range s.contracts {
+		if family.accessList[u.id] {
+			accessList = append(accessList, name)
+		}
+	}
+	sort.Strings(accessList)
+	return sdk.UserFullData{
+		ID:             u.id,
+		Roles:          append([]string{}, u.roles...),
+		AccessList:     accessList,
+		Domains:        sortedKeys(u.domains),
+		ManagedDomains: s.managedDomains(u),
+	}
+}
+
+func (s *Server) listClient(caller *user, payload []byte) ([]byte, error) {
+	clientID := string(payload)
+	if clientID == "" {
+		clientID = caller.id
+	}
+	target := s.users[clientID]
+	if target == nil {
+		return nil, fmt.Errorf("%s: Client %s does not exist", sdk.API_LIST_CLIENT, clientID)
+	}
+	allowed := target == caller || caller.isSuperAdmin()
+	for name := range target.domains {
+		allowed = allowed || s.manages(caller, name)
+	}
+	if !allowed {
+		return nil, errPermission(sdk.API_LIST_CLIENT)
+	}
+	return marshal(s.fullData(target))
+}
+
+func (s *Server) listClients(caller *user, payload []byte) ([]byte, error) {
+	var query sdk.InfoListData
+	if err := decode(payload, &query); err != nil {
+		return nil, err
+	}
+	var domains []string
+	switch {
+	case query.AllDomains && caller.isSuperAdmin():
+		domains = sortedKeys(s.domainSet())
+	case query.AllDomains:
+		domains = s.managedDomains(caller)
+		if len(domains) == 0 {
+			return nil, errPermission(sdk.API_LIST_CLIENTS)
+		}
+	default:
+		if s.domains[query.DomainName] == nil {
+			return nil, fmt.Errorf("%s: Domain %s does not exist", sdk.API_LIST_CLIENTS, query.DomainName)
+		}
+		if !s.administers(caller, query.DomainName) {
+			return nil, errPermission(sdk.API_LIST_CLIENTS)
+		}
+		domains = []string{query.DomainName}
+	}
+
+	ids := make(map[string]bool)
+	for _, name := range domains {
+		for id := range s.domains[name].clients {
+			ids[id] = true
+		}
+	}
+	result := make([]sdk.UserFullDataWrapper, 0, len(ids))
+	for _, id := range sortedKeys(ids) {
+		result = append(result, sdk.UserFullDataWrapper{ID: id, Data: s.fullData(s.users[id])})
+	}
+	return marshal(result)
+}
+
+func (s *Server) removeClient(caller *user, payload []byte) ([]byte, error) {
+	var data sdk.UserDomainData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	domainName := domainOrDefault(data.DomainName)
+	target := s.users[data.ID]
+	if target == nil || !target.domains[domainName] {
+		return nil, fmt.Errorf("%s: Client %s does not exist in domain %s", sdk.API_REMOVE_CLIENT, data.ID, domainName)
+	}
+	if target == caller {
+		return nil, fmt.Errorf("%s: Clients cannot remove themselves", sdk.API_REMOVE_CLIENT)
+	}
+	if !s.administers(caller, domainName) {
+		return nil, errPermission(sdk.API_REMOVE_CLIENT)
+	}
+
+	for _, d := range s.domains {
+		delete(d.clients, target.id)
+		delete(d.admins, target.id)
+	}
+	for _, family := range s.contracts {
+		delete(family.accessList, target.id)
+	}
+	for _, clients := range s.apiAccess {
+		delete(clients, target.id)
+	}
+	delete(s.users, target.id)
+	return []byte("true"), nil
+}
+
+//
+// Domains
+//
+
+func (s *Server) domainSet() map[string]bool {
+	set := make(map[string]bool, len(s.domains))
+	for name := range s.domains {
+		set[name] = true
+	}
+	return set
+}
+
+type domainData struct {
+	Clients        []string `json:"clients"`
+	Admins         []string `json:"admins"`
+	SmartContracts []string `json:"smartContracts"`
+}
+
+type domainDataWrapper struct {
+	DomainName string     `json:"domainName"`
+	Data       domainData `json:"data"`
+}
+
+func (d *domain) data() domainData {
+	return domainData{Clients: sortedKeys(d.clients), Admins: sortedKeys(d.admins), SmartContracts: sortedKeys(d.smartContracts)}
+}
+
+func (s *Server) createDomain(caller *user, payload []byte) ([]byte, error) {
+	name := string(payload)
+	if !caller.isSuperAdmin() {
+		return nil, errPermission(sdk.API_CREATE_DOMAIN)
+	}
+	if name == "" {
+		return nil, fmt.Errorf("%s: Domain name cannot be empty", sdk.API_CREATE_DOMAIN)
+	}
+	if s.domains[name] != nil {
+		return nil, fmt.Errorf("%s: Domain %s already exists", sdk.API_CREATE_DOMAIN, name)
+	}
+	s.domains[name] = newDomain()
+	return []byte("true"), nil
+}
+
+func (s *Server) listDomain(caller *user, payload []byte) ([]byte, error) {
+	name := string(payload)
+	if name != "" {
+		d := s.domains[name]
+		if d == nil {
+			return nil, fmt.Errorf("%s: Domain %s does not exist", sdk.API_LIST_DOMAIN, name)
+		}
+		if !s.administers(caller, name) {
+			return nil, errPermission(sdk.API_LIST_DOMAIN)
+		}
+		return marshal(d.data())
+	}
+
+	names := s.managedDomains(caller)
+	if caller.isSuperAdmin() {
+		names = sortedKeys(s.domainSet())
+	} else if len(names) == 0 {
+		return nil, errPermission(sdk.API_LIST_DOMAIN)
+	}
+	result := make([]domainDataWrapper, 0, len(names))
+	for _, each := range names {
+		result = append(result, domainDataWrapper{DomainName: each, Data: s.domains[each].data()})
+	}
+	return marshal(result)
+}
+
+func (s *Server) listManagedDomains(caller *user, payload []byte) ([]byte, error) {
+	clientID := string(payload)
+	if clientID == "" {
+		clientID = caller.id
+	}
+	if clientID != caller.id && !caller.isSuperAdmin() {
+		return nil, errPermission(sdk.API_LIST_MANAGED_DOMAINS)
+	}
+	target := s.users[clientID]
+	if target == nil {
+		return nil, fmt.Errorf("%s: Client %s does not exist", sdk.API_LIST_MANAGED_DOMAINS, clientID)
+	}
+	return marshal(s.managedDomains(target))
+}
+
+func (s *Server) grantDomainAdmin(caller *user, payload []byte) ([]byte, error) {
+	return s.setDomainAdmin(caller, payload, sdk.API_GRANT_DOMAIN_ADMIN, true)
+}
+
+func (s *Server) revokeDomainAdmin(caller *user, payload []byte) ([]byte, error) {
+	return s.setDomainAdmin(caller, payload, sdk.API_REVOKE_DOMAIN_ADMIN, false)
+}
+
+func (s *Server) setDomainAdmin(caller *user, payload []byte, api string, admin bool) ([]byte, error) {
+	var data sdk.UserDomainData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	domainName := domainOrDefault(data.DomainName)
+	d := s.domains[domainName]
+	if d == nil {
+		return nil, fmt.Errorf("%s: Domain %s does not exist", api, domainName)
+	}
+	if !s.administers(caller, domainName) {
+		return nil, errPermission(api)
+	}
+	if !d.clients[data.ID] {
+		return nil, fmt.Errorf("%s: Client %s does not exist in domain %s", api, data.ID, domainName)
+	}
+	if admin {
+		d.admins[data.ID] = true
+	} else {
+		delete(d.admins, data.ID)
+	}
+	return []byte("true"), nil
+}
+
+//
+// Access control
+//
+
+func (s *Server) grantAccess(caller *user, payload []byte) ([]byte, error) {
+	return s.setAccess(caller, payload, sdk.API_GRANT_ACCESS, true)
+}
+
+func (s *Server) revokeAccess(caller *user, payload []byte) ([]byte, error) {
+	return s.setAccess(caller, payload, sdk.API_REVOKE_ACCESS, false)
+}
+
+func (s *Server) setAccess(caller *user, payload []byte, api string, access bool) ([]byte, error) {
+	var data sdk.UserAccessData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	name, _ := splitSpec(data.SmartContractName)
+	domainName := domainOrDefault(data.DomainName)
+	family := s.contracts[name]
+	if family == nil || s.domains[domainName] == nil || !s.domains[domainName].smartContracts[name] {
+		return nil, fmt.Errorf("%s: Smart contract %s does not exist in domain %s", api, name, domainName)
+	}
+	if s.users[data.ID] == nil {
+		return nil, fmt.Errorf("%s: Client %s does not exist", api, data.ID)
+	}
+	if !s.administers(caller, domainName) {
+		return nil, errPermission(api)
+	}
+	if access {
+		family.accessList[data.ID] = true
+	} else {
+		delete(family.accessList, data.ID)
+	}
+	return []byte("true"), nil
+}
+
+func (s *Server) checkApiAccess(caller *user, payload []byte) ([]byte, error) {
+	var data sdk.ApiAccessControlData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	clients, restricted := s.apiAccess[data.ApiName]
+	allowed := !restricted || caller.isSuperAdmin() || clients[caller.id]
+	return []byte(strconv.FormatBool(allowed)), nil
+}
+
+// manageApiAccess grants or revokes access to an API. Once access to an API has been
+// granted to anyone, only super-admins and the clients granted access may use it.
+// Options carry the client ID, either raw or as GetSmartContractTransactionOptions.
+func (s *Server) manageApiAccess(caller *user, payload []byte) ([]byte, error) {
+	var data sdk.ApiAccessControlData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	if !caller.isSuperAdmin() {
+		return nil, errPermission(sdk.API_MANAGE_API_ACCESS)
+	}
+	var options sdk.GetSmartContractTransactionOptions
+	clientID := string(data.Options)
+	if json.Unmarshal(data.Options, &options) == nil && options.ClientId != "" {
+		clientID = options.ClientId
+	}
+	if s.users[clientID] == nil {
+		return nil, fmt.Errorf("%s: Client %s does not exist", sdk.API_MANAGE_API_ACCESS, clientID)
+	}
+
+	switch data.Operation {
+	case "grant":
+		if s.apiAccess[data.ApiName] == nil {
+			s.apiAccess[data.ApiName] = make(map[string]bool)
+		}
+		s.apiAccess[data.ApiName][clientID] = true
+	case "revoke":
+		delete(s.apiAccess[data.ApiName], clientID)
+	default:
+		return nil, fmt.Errorf("%s: Unknown operation %q", sdk.API_MANAGE_API_ACCESS, data.Operation)
+	}
+	return []byte("true"), nil
+}
+
+//
+// Smart contracts
+//
+
+type smartContractInfo struct {
+	Name       string `json:"scName"`
+	Version    string `json:"scVersion"`
+	Space      string `json:"space"`
+	Checksum   string `json:"checksum"`
+	Mode       string `json:"mode"`
+	AccessList string `json:"accessList"`
+	Domains    string `json:"domains"`
+}
+
+func (s *Server) contractInfo(c *contract) smartContractInfo {
+	return smartContractInfo{
+		Name:       c.name,
+		Version:    c.version,
+		Space:      c.name,
+		Checksum:   c.checksum,
+		Mode:       "package",
+		AccessList: strings.Join(sortedKeys(s.contracts[c.name].accessList), ","),
+		Domains:    c.domain,
+	}
+}
+
+func (s *Server) registerSmartContract(caller *user, payload []byte) ([]byte, error) {
+	var data sdk.SmartContractData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	name, version := splitSpec(data.Name)
+	if name == "" || version == "" || version == "*" {
+		return nil, fmt.Errorf("%s: Smart contract name must be of the form <name>-v<version>", sdk.API_REGISTER_SMARTCONTRACT)
+	}
+	domainName := domainOrDefault(data.DomainName)
+	if s.domains[domainName] == nil {
+		return nil, fmt.Errorf("%s: Domain %s does not exist", sdk.API_REGISTER_SMARTCONTRACT, domainName)
+	}
+	if !s.administers(caller, domainName) {
+		return nil, errPermission(sdk.API_REGISTER_SMARTCONTRACT)
+	}
+	if family := s.contracts[name]; family != nil {
+		if family.versions[version] != nil {
+			return nil, fmt.Errorf("%s: Smart contract %s is already registered", sdk.API_REGISTER_SMARTCONTRACT, data.Name)
+		}
+		if latest := family.latest(); latest != nil && latest.domain != domainName {
+			return nil, fmt.Errorf("%s: Smart contract %s is registered in domain %s", sdk.API_REGISTER_SMARTCONTRACT, name, latest.domain)
+		}
+	}
+	s.registerContract(name, version, domainName, data.FileContent, data.InitArgs)
+	return []byte("true"), nil
+}
+
+func (s *Server) listSmartContract(caller *user, payload []byte) ([]byte, error) {
+	name, version := splitSpec(string(payload))
+	family := s.contracts[name]
+	if family == nil {
+		return nil, fmt.Errorf("%s: Smart contract %s does not exist", sdk.API_LIST_SMARTCONTRACT, payload)
+	}
+	c := family.latest()
+	if version != "" && version != "*" {
+		c = family.versions[version]
+	}
+	if c == nil {
+		return nil, fmt.Errorf("%s: Smart contract %s does not exist", sdk.API_LIST_SMARTCONTRACT, payload)
+	}
+	if !s.administers(caller, c.domain) {
+		return nil, errPermission(sdk.API_LIST_SMARTCONTRACT)
+	}
+	return marshal(s.contractInfo(c))
+}
+
+func (s *Server) listSmartContracts(caller *user, payload []byte) ([]byte, error) {
+	var query sdk.InfoListData
+	if err := decode(payload, &query); err != nil {
+		return nil, err
+	}
+	return s.listDomainsSmartContracts(caller, query, sdk.API_LIST_SMARTCONTRACTS)
+}
+
+func (s *Server) listDomainsSmartContracts(caller *user, query sdk.InfoListData, api string) ([]byte, error) {
+	domains := make(map[string]bool)
+	switch {
+	case query.AllDomains && caller.isSuperAdmin():
+		domains = s.domainSet()
+	case query.AllDomains:
+		for _, name := range s.managedDomains(caller) {
+			domains[name] = true
+		}
+		if len(domains) == 0 {
+			return nil, errPermission(api)
+		}
+	default:
+		if s.domains[query.DomainName] == nil {
+			return nil, fmt.Errorf("%s: Domain %s does not exist", api, query.DomainName)
+		}
+		if !s.administers(caller, query.DomainName) {
+			return nil, errPermission(api)
+		}
+		domains[query.DomainName] = true
+	}
+
+	result := make([]smartContractInfo, 0)
+	for _, name := range sortedKeys(s.contractSet()) {
+		family := s.contracts[name]
+		versions := make([]string, 0, len(family.versions))
+		for version := range family.versions {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return sdk.CompareVersions(versions[i], versions[j]) < 0 })
+		for _, version := range versions {
+			if c := family.versions[version]; domains[c.domain] {
+				result = append(result, s.contractInfo(c))
+			}
+		}
+	}
+	return marshal(result)
+}
+
+func (s *Server) contractSet() map[string]bool {
+	set := make(map[string]bool, len(s.contracts))
+	for name := range s.contracts {
+		set[name] = true
+	}
+	return set
+}
+
+//
+// UserMan
+//
+
+func (s *Server) userMan(caller *user, payload []byte) ([]byte, error) {
+	var task sdk.UserManData
+	if err := decode(payload, &task); err != nil {
+		return nil, err
+	}
+	switch task.Action {
+	case sdk.API_UPDATE_SELF_CREDENTIAL:
+		return s.updateSelfCredential(caller, task.Data)
+	case sdk.API_LIST_INVOKABLE_SC:
+		return s.listInvokableSC(caller)
+	case sdk.API_GET_BLOCK_CHAIN_SUMMARY_JSON:
+		return s.blockchainSummary()
+	case sdk.API_GET_BLOCK_DETAILS_JSON:
+		return s.blockDetails(task.Data)
+	case sdk.API_CALCULATE_BLOCK_HASH:
+		b, err := s.findBlock(task.Data)
+		if err != nil {
+			return nil, err
+		}
+		return []byte(b.hash), nil
+	case sdk.API_GET_SMARTCONTRACT_TRANSACTION_JSON:
+		return s.transactionDetails(string(task.Data))
+	case sdk.API_GET_SMARTCONTRACT_TRANSACTION_META_JSON:
+		return s.transactionMetadata(string(task.Data))
+	case sdk.API_LIST_LATEST_TRANSACTION:
+		return s.latestTransactions(task.Data)
+	}
+	return nil, fmt.Errorf("%s: Unknown action %q", sdk.API_USER_MAN, task.Action)
+}
+
+func (s *Server) updateSelfCredential(caller *user, payload []byte) ([]byte, error) {
+	var data sdk.UserData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	if data.ID != caller.id {
+		return nil, errPermission(sdk.API_UPDATE_SELF_CREDENTIAL)
+	}
+	if data.Credential == "" {
+		return nil, fmt.Errorf("%s: Credential cannot be empty", sdk.API_UPDATE_SELF_CREDENTIAL)
+	}
+	caller.password = data.Credential
+	return []byte("true"), nil
+}
+
+type invokableSC struct {
+	Name    string `json:"name"`
+	Version string `json:"ver"`
+}
+
+func (s *Server) listInvokableSC(caller *user) ([]byte, error) {
+	result := make([]invokableSC, 0)
+	for _, name := range sortedKeys(s.contractSet()) {
+		family := s.contracts[name]
+		if !caller.isSuperAdmin() && !family.accessList[caller.id] {
+			continue
+		}
+		for _, c := range family.versions {
+			result = append(result, invokableSC{Name: c.name, Version: c.version})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return sdk.CompareVersions(result[i].Version, result[j].Version) < 0
+	})
+	return marshal(result)
+}
+
+func (s *Server) lastBlock() sdk.BlockSummary {
+	if len(s.chain.blocks) == 0 {
+		return sdk.BlockSummary{}
+	}
+	b := s.chain.blocks[len(s.chain.blocks)-1]
+	return sdk.BlockSummary{
+		BlockNumber:       b.number,
+		ChunksetCount:     1,
+		CreationTimestamp: b.timestamp,
+		Hash:              b.hash,
+		PrevHash:          b.prevHash,
+		Status:            b.status(),
+	}
+}
+
+func (b *block) status() int {
+	if b.sealed {
+		return sdk.BLOCK_STATUS_SEALED
+	}
+	return sdk.BLOCK_STATUS_OPEN
+}
+
+// blockchainSummary reports the chain once per node, as each node holds a replica.
+func (s *Server) blockchainSummary() ([]byte, error) {
+	summary := sdk.BlockchainSummary{Chains: make([]sdk.ChainSummary, 0, len(s.nodes))}
+	for i, node := range s.nodes {
+		summary.Chains = append(summary.Chains, sdk.ChainSummary{
+			SealedBlockCount: s.chain.sealedCount(),
+			ChainId:          s.chain.id,
+			MachineId:        fmt.Sprintf("pcoretest-machine-%d", i),
+			NetworkAddress:   node,
+			PcoreId:          fmt.Sprintf("pcoretest-pcore-%d", i),
+			Tags:             sdk.ChainTags{Name: strings.SplitN(node, ".", 2)[0]},
+			LastBlock:        s.lastBlock(),
+		})
+	}
+	return marshal(summary)
+}
+
+func (s *Server) findBlock(payload []byte) (*block, error) {
+	var data sdk.BlockData
+	if err := decode(payload, &data); err != nil {
+		return nil, err
+	}
+	if data.ChainId != s.chain.id {
+		return nil, fmt.Errorf("Chain %s does not exist", data.ChainId)
+	}
+	number, err := strconv.ParseInt(data.BlockId, 10, 64)
+	if err != nil || number < 0 || number >= int64(len(s.chain.blocks)) {
+		return nil, fmt.Errorf("Block %s does not exist in chain %s", data.BlockId, data.ChainId)
+	}
+	return s.chain.blocks[number], nil
+}
+
+type blockDetails struct {
+	Block             string `json:"block"`
+	BlockNumber       int64  `json:"block_number"`
+	ChainId           string `json:"chain_id"`
+	ChunksetCount     int64  `json:"chunkset_count"`
+	CreationTimestamp int64  `json:"creation_timestamp"`
+	Hash              string `json:"hash"`
+	MachineId         string `json:"machine_id"`
+	NetworkAddress    string `json:"network_address"`
+	PcoreId           string `json:"pcore_id"`
+	PrevHash          string `json:"prev_hash"`
+	Status            int    `json:"status"`
+}
+
+func (s *Server) blockDetails(payload []byte) ([]byte, error) {
+	b, err := s.findBlock(payload)
+	if err != nil {
+		return nil, err
+	}
+	node := ""
+	if len(s.nodes) != 0 {
+		node = s.nodes[0]
+	}
+	return marshal(blockDetails{
+		Block:             strings.Join(b.txIDs, ","),
+		BlockNumber:       b.number,
+		ChainId:           s.chain.id,
+		ChunksetCount:     1,
+		CreationTimestamp: b.timestamp,
+		Hash:              b.hash,
+		MachineId:         "pcoretest-machine-0",
+		NetworkAddress:    node,
+		PcoreId:           "pcoretest-pcore-0",
+		PrevHash:          b.prevHash,
+		Status:            b.status(),
+	})
+}
+
+type transactionDetails struct {
+	TxId      string     `json:"tx_id"`
+	ScName    string     `json:"sc_name"`
+	ClientId  string     `json:"client_id"`
+	Mutations []mutation `json:"mutations"`
+}
+
+type transactionMetadata struct {
+	TxId        string `json:"tx_id"`
+	ChainId     string `json:"chain_id"`
+	BlockNumber int64  `json:"block_number"`
+	Timestamp   int64  `json:"timestamp"`
+}
+
+func (s *Server) transaction(txID string) (*transaction, error) {
+	tx := s.chain.txs[txID]
+	if tx == nil || s.lagging[s.serving][txID] {
+		return nil, fmt.Errorf(sdk.FMT_TRANSACTION_X_DOES_NOT_EXIST, txID)
+	}
+	return tx, nil
+}
+
+func (s *Server) transactionDetails(txID string) ([]byte, error) {
+	tx, err := s.transaction(txID)
+	if err != nil {
+		return nil, err
+	}
+	mutations := append([]mutation{}, tx.mutations...)
+	return marshal(transactionDetails{TxId: tx.id, ScName: tx.scName, ClientId: tx.clientID, Mutations: mutations})
+}
+
+func (s *Server) transactionMetadata(txID string) ([]byte, error) {
+	tx, err := s.transaction(txID)
+	if err != nil {
+		return nil, err
+	}
+	return marshal(transactionMetadata{TxId: tx.id, ChainId: s.chain.id, BlockNumber: tx.blockNumber, Timestamp: tx.timestamp})
+}
+
+func (s *Server) latestTransactions(payload []byte) ([]byte, error) {
+	var count int
+	if err := decode(payload, &count); err != nil {
+		return nil, err
+	}
+	txIDs := make([]string, 0, count)
+	for i := len(s.chain.order) - 1; i >= 0 && len(txIDs) < count; i-- {
+		if txID := s.chain.order[i]; !s.lagging[s.serving][txID] {
+			txIDs = append(txIDs, txID)
+		}
+	}
+	return marshal(map[string][]string{"tx_ids": txIDs})
+}
+
+//
+// SysMan
+//
+
+func (s *Server) sysMan(caller *user, payload []byte) ([]byte, error) {
+	var task sdk.SysManData
+	if err := decode(payload, &task); err != nil {
+		return nil, err
+	}
+	switch task.Action {
+	case sdk.API_REQUEST_FORGET:
+		return s.requestForget(caller, task.Data)
+	case sdk.API_APPROVE_FORGET:
+		return s.approveForget(caller, task.Data)
+	case sdk.API_COMMIT_FORGET:
+		return s.commitForget(caller, task.Data)
+	case sdk.API_LIST_FORGET_GROUPS:
+		return s.listForgetGroups(caller, task.Data)
+	case sdk.API_LIST_DOMAIN_SMARTCONTRACT:
+		return s.listDomainsSmartContracts(caller, sdk.InfoListData{DomainName: string(task.Data)}, sdk.API_LIST_DOMAIN_SMARTCONTRACT)
+	}
+	if op := s.systemCalls()[task.Action]; op != nil {
+		return op(caller, task.Data)
+	}
+	return nil, fmt.Errorf("%s: Unknown action %q", sdk.API_SYS_MAN, task.Action)
+}
+
+// systemCalls maps the SysMan action names of the dedicated RPCs to their operations.
+func (s *Server) systemCalls() map[string]operation {
+	return map[string]operation{
+		sdk.API_CREATE_DOMAIN:          s.createDomain,
+		sdk.API_LIST_DOMAIN:            s.listDomain,
+		sdk.API_LIST_MANAGED_DOMAINS:   s.listManagedDomains,
+		sdk.API_GRANT_DOMAIN_ADMIN:     s.grantDomainAdmin,
+		sdk.API_REVOKE_DOMAIN_ADMIN:    s.revokeDomainAdmin,
+		sdk.API_CREATE_CLIENT:          s.createClient,
+		sdk.API_UPDATE_CLIENT:          s.updateClient,
+		sdk.API_REMOVE_CLIENT:          s.removeClient,
+		sdk.API_LIST_CLIENT:            s.listClient,
+		sdk.API_LIST_CLIENTS:           s.listClients,
+		sdk.API_GRANT_ACCESS:           s.grantAccess,
+		sdk.API_REVOKE_ACCESS:          s.revokeAccess,
+		sdk.API_REGISTER_SMARTCONTRACT: s.registerSmartContract,
+		sdk.API_LIST_SMARTCONTRACT:     s.listSmartContract,
+		sdk.API_LIST_SMARTCONTRACTS:    s.listSmartContracts,
+	}
+}
+
+// recordSystemTransaction appends a transaction made by the system smart contract.
+func (s *Server) recordSystemTransaction(caller *user, payload []byte) *transaction {
+	tx := &transaction{id: s.nextID("tx"), scName: sysManContract, clientID: caller.id, payload: payload}
+	s.chain.append(tx, s.now())
+	s.hideFromLagging(tx.id)
+	return tx
+}
+
+func (s *Server) requestForget(caller *user, payload []byte) ([]byte, error) {
+	if !caller.isSuperAdmin() {
+		return nil, errPermission(sdk.API_REQUEST_FORGET)
+	}
+	var params sdk.RequestForgetParams
+	if err := decode(payload, &params); err != nil {
+		return nil, err
+	}
+	if len(params.TxIds) == 0 {
+		return nil, fmt.Errorf("%s: tx_ids cannot be empty", sdk.API_REQUEST_FORGET)
+	}
+	tx := s.recordSystemTransaction(caller, payload)
+	s.forgetRequests[tx.id] = &forgetRequest{txIDs: append([]string(nil), params.TxIds...), approvals: make(map[string]bool)}
+	return []byte(tx.id), nil
+}
+
+func (s *Server) approveForget(caller *user, payload []byte) ([]byte, error) {
+	if !caller.isSuperAdmin() {
+		return nil, errPermission(sdk.API_APPROVE_FORGET)
+	}
+	var params sdk.ApproveForgetParams
+	if err := decode(payload, &params); err != nil {
+		return nil, err
+	}
+	request := s.forgetRequests[params.RequestTxId]
+	if request == nil {
+		return nil, fmt.Errorf("%s: Forget request %s does not exist", sdk.API_APPROVE_FORGET, params.RequestTxId)
+	}
+	if request.committed {
+		return nil, fmt.Errorf("%s: Forget request %s is already committed", sdk.API_APPROVE_FORGET, params.RequestTxId)
+	}
+	tx := s.recordSystemTransaction(caller, payload)
+	request.approvals[tx.id] = true
+	return []byte(tx.id), nil
+}
+
+func (s *Server) commitForget(caller *user, payload []byte) ([]byte, error) {
+	if !caller.isSuperAdmin() {
+		return nil, errPermission(sdk.API_COMMIT_FORGET)
+	}
+	var params sdk.CommitForgetParams
+	if err := decode(payload, &params); err != nil {
+		return nil, err
+	}
+	request := s.forgetRequests[params.RequestTxId]
+	if request == nil {
+		return nil, fmt.Errorf("%s: Forget request %s does not exist", sdk.API_COMMIT_FORGET, params.RequestTxId)
+	}
+	if request.committed {
+		return nil, fmt.Errorf("%s: Forget request %s is already committed", sdk.API_COMMIT_FORGET, params.RequestTxId)
+	}
+	if len(params.ApprovalTxIds) == 0 {
+		return nil, fmt.Errorf("%s: Forget request %s is not approved", sdk.API_COMMIT_FORGET, params.RequestTxId)
+	}
+	for _, approval := range params.ApprovalTxIds {
+		if !request.approvals[approval] {
+			return nil, fmt.Errorf("%s: %s is not an approval of forget request %s", sdk.API_COMMIT_FORGET, approval, params.RequestTxId)
+		}
+	}
+
+	report := sdk.ForgetReport{Deleted: []string{}, AlreadyDeleted: []string{}, NotFound: []string{}}
+	for _, txID := range request.txIDs {
+		switch {
+		case s.chain.forgotten[txID]:
+			report.AlreadyDeleted = append(report.AlreadyDeleted, txID)
+		case s.chain.txs[txID] != nil:
+			s.chain.forget(txID)
+			report.Deleted = append(report.Deleted, txID)
+		default:
+			report.NotFound = append(report.NotFound, txID)
+		}
+	}
+	request.committed = true
+	report.CommitTxId = s.recordSystemTransaction(caller, payload).id
+	return marshal(report)
+}
+
+func (s *Server) listForgetGroups(caller *user, payload []byte) ([]byte, error) {
+	if !caller.isSuperAdmin() {
+		return nil, errPermission(sdk.API_LIST_FORGET_GROUPS)
+	}
+	var txIDs []string
+	if err := decode(payload, &txIDs); err != nil {
+		return nil, err
+	}
+	wanted := make(map[string]bool, len(txIDs))
+	for _, txID := range txIDs {
+		wanted[txID] = true
+	}
+
+	requestIDs := make([]string, 0, len(s.forgetRequests))
+	for id := range s.forgetRequests {
+		requestIDs = append(requestIDs, id)
+	}
+	sort.Strings(requestIDs)
+	groups := make([]sdk.ForgetGroup, 0)
+	for _, id := range requestIDs {
+		request := s.forgetRequests[id]
+		for _, txID := range request.txIDs {
+			if wanted[txID] {
+				groups = append(groups, sdk.ForgetGroup{TxIds: append([]string(nil), request.txIDs...)})
+				break
+			}
+		}
+	}
+	return marshal(groups)
+}
@@ -0,0 +1,362 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+// Package pcoretest provides an in-memory fake ParallelCore engine, so that code
+// built on the ParallelCore Go Client SDK can be unit-tested without a live network.
+//
+// The fake implements engine_client_proto.RequestHandlerServer over an in-process
+// gRPC connection (bufconn). It models authentication and token renewal, users,
+// roles, domains, domain admins, smart contract registration and access lists,
+// UserMan and SysMan actions, the right-to-forget workflow, and event streams.
+//
+// Smart contracts are scripted with Go handlers (see Server.Handle), which read and
+// write a per-contract key-value store and emit events. Invocations that write
+// produce a transaction, recorded in its own block, whose ID is the commit ID.
+//
+// Typical usage:
+//
+//	func TestSomething(t *testing.T) {
+//		client, server := pcoretest.NewClient(t)
+//		server.AddUser("alice", "secret", []string{"app"}, []string{"default"})
+//		server.AddSmartContract("ledger", "1", "default", "alice")
+//		server.Handle("ledger-v*", func(sc *pcoretest.Context) ([]byte, error) {
+//			sc.Put(sc.Task.Action, []byte(sc.Task.Data))
+//			return []byte("ok"), nil
+//		})
+//		...
+//	}
+package pcoretest
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+	pb "github.com/digital-transaction/parallelcore-client-sdk-go/engine_client_proto"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+const (
+	// RootID and RootPassword identify the super-admin every Server starts with.
+	RootID       = "root"
+	RootPassword = "parallelcore"
+
+	// RoleSuperAdmin is the role that makes a user a super-admin.
+	RoleSuperAdmin = "admin"
+
+	// DefaultEndpoint is the endpoint of the single node a Server starts with.
+	DefaultEndpoint = "node0.pcoretest.local:5000"
+
+	// DefaultTokenTTL is how long tokens issued by a Server stay valid.
+	DefaultTokenTTL = time.Hour
+
+	bufferSize = 1024 * 1024
+)
+
+// Server is an in-memory fake ParallelCore engine. All of its methods are safe for
+// concurrent use.
+type Server struct {
+	grpcServer *grpc.Server
+	certDir    string
+	certPath   string
+
+	mu       sync.Mutex
+	now      func() time.Time
+	tokenTTL time.Duration
+	secret   []byte
+	revoked  map[string]bool
+	nodes    []string
+	// nodeListeners holds the listener of every node dialed so far.
+	nodeListeners map[string]*bufconn.Listener
+	// serving is the node serving the request in progress (see nodeOf).
+	serving string
+	// lagging maps lagging nodes to the transactions they do not see yet.
+	lagging map[string]map[string]bool
+
+	users     map[string]*user
+	domains   map[string]*domain
+	contracts map[string]*contractFamily
+	apiAccess map[string]map[string]bool
+	handlers  map[string]HandlerFunc
+	store     map[string]map[string][]byte
+	// versions counts the commits that wrote each key of store, to detect conflicting
+	// invocations.
+	versions map[string]map[string]uint64
+
+	chain          *chain
+	forgetRequests map[string]*forgetRequest
+	listeners      map[*listener]bool
+	seq            uint64
+}
+
+// NewServer starts a Server with one node (DefaultEndpoint), the 'default' domain,
+// and the super-admin RootID, who is also domain-admin of 'default'.
+func NewServer() (*Server, error) {
+	s := &Server{
+		now:            time.Now,
+		tokenTTL:       DefaultTokenTTL,
+		secret:         make([]byte, 32),
+		revoked:        make(map[string]bool),
+		nodes:          []string{DefaultEndpoint},
+		nodeListeners:  make(map[string]*bufconn.Listener),
+		lagging:        make(map[string]map[string]bool),
+		users:          make(map[string]*user),
+		domains:        make(map[string]*domain),
+		contracts:      make(map[string]*contractFamily),
+		apiAccess:      make(map[string]map[string]bool),
+		handlers:       make(map[string]HandlerFunc),
+		store:          make(map[string]map[string][]byte),
+		versions:       make(map[string]map[string]uint64),
+		chain:          newChain(),
+		forgetRequests: make(map[string]*forgetRequest),
+		listeners:      make(map[*listener]bool),
+	}
+	if _, err := rand.Read(s.secret); err != nil {
+		return nil, err
+	}
+	s.domains[sdk.DOMAIN_DEFAULT] = newDomain()
+	s.addUser(RootID, RootPassword, []string{RoleSuperAdmin}, []string{sdk.DOMAIN_DEFAULT})
+	s.domains[sdk.DOMAIN_DEFAULT].admins[RootID] = true
+
+	cert, err := s.writeCertificate()
+	if err != nil {
+		s.removeCertificate()
+		return nil, err
+	}
+	s.grpcServer = grpc.NewServer(grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	pb.RegisterRequestHandlerServer(s.grpcServer, &handler{s})
+	return s, nil
+}
+
+// NewClient starts a Server for the duration of test t, and returns a Client
+// connected to it as RootID. Both are closed when t finishes.
+func NewClient(t testing.TB) (*sdk.Client, *Server) {
+	t.Helper()
+
+	s, err := NewServer()
+	if err != nil {
+		t.Fatalf("pcoretest: %v", err)
+	}
+	t.Cleanup(s.Close)
+
+	client, err := s.Open(RootID, RootPassword)
+	if err != nil {
+		t.Fatalf("pcoretest: %v", err)
+	}
+	t.Cleanup(client.Close)
+	return client, s
+}
+
+// Open returns a Client connected to s as clientID (see sdk.OpenAny).
+func (s *Server) Open(clientID string, credential string, opts ...sdk.Option) (*sdk.Client, error) {
+	return sdk.OpenAny(s.EndpointSpecs(), clientID, credential, s.certPath, append(s.Options(), opts...)...)
+}
+
+// Options returns the sdk.Options needed to connect to s, for use with any of the
+// sdk.Open* functions along with CertPath and EndpointSpecs.
+func (s *Server) Options() []sdk.Option {
+	return []sdk.Option{sdk.WithContextDialer(s.dial)}
+}
+
+// CertPath returns the path of the self-signed certificate s serves TLS with.
+func (s *Server) CertPath() string {
+	return s.certPath
+}
+
+// EndpointSpecs returns the space-delimited endpoints of s's nodes.
+func (s *Server) EndpointSpecs() string {
+	return strings.Join(s.Endpoints(), " ")
+}
+
+// Endpoints returns the endpoints of s's nodes.
+func (s *Server) Endpoints() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.nodes...)
+}
+
+// SetEndpoints replaces s's nodes. Every endpoint must be of the form
+// <name>.pcoretest.local:<port>. Endpoints that are not nodes of s refuse connections,
+// and every node is reported with its own chain in the blockchain summary.
+func (s *Server) SetEndpoints(endpoints ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes = append([]string(nil), endpoints...)
+}
+
+// SetClock replaces the clock s uses to issue and check tokens and to timestamp
+// blocks. now must be safe for concurrent use.
+func (s *Server) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+// SetTokenTTL sets how long tokens issued from now on stay valid.
+func (s *Server) SetTokenTTL(ttl time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokenTTL = ttl
+}
+
+// Close stops s, disconnecting all Clients.
+func (s *Server) Close() {
+	s.mu.Lock()
+	for each := range s.listeners {
+		each.stop()
+	}
+	s.mu.Unlock()
+
+	s.grpcServer.Stop()
+	s.removeCertificate()
+}
+
+func (s *Server) dial(ctx context.Context, endpoint string) (net.Conn, error) {
+	s.mu.Lock()
+	known := false
+	for _, node := range s.nodes {
+		known = known || node == endpoint
+	}
+	l := s.nodeListeners[endpoint]
+	if known && l == nil {
+		l = bufconn.Listen(bufferSize)
+		s.nodeListeners[endpoint] = l
+		go s.grpcServer.Serve(nodeListener{l, nodeAddr(endpoint)})
+	}
+	s.mu.Unlock()
+	if !known {
+		return nil, &net.OpError{Op: "dial", Net: "tcp", Err: fmt.Errorf("pcoretest: connection refused by %q", endpoint)}
+	}
+	return l.Dial()
+}
+
+// nodeAddr is the address of a node, as seen by the handlers of the requests it serves.
+type nodeAddr string
+
+func (a nodeAddr) Network() string { return "pcoretest" }
+func (a nodeAddr) String() string  { return string(a) }
+
+// nodeListener accepts the connections to one node. They report the node as their
+// remote address, which gRPC hands to handlers as their peer (see nodeOf).
+type nodeListener struct {
+	*bufconn.Listener
+	node nodeAddr
+}
+
+func (l nodeListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	return nodeConn{conn, l.node}, nil
+}
+
+type nodeConn struct {
+	net.Conn
+	node nodeAddr
+}
+
+func (c nodeConn) RemoteAddr() net.Addr { return c.node }
+
+// nodeOf returns the node serving the request of ctx.
+func nodeOf(ctx context.Context) string {
+	if p, ok := peer.FromContext(ctx); ok {
+		if node, ok := p.Addr.(nodeAddr); ok {
+			return string(node)
+		}
+	}
+	return ""
+}
+
+// SetLagging makes the node endpoint lag behind the others, or catch up with them.
+// While it lags, the node does not see the transactions committed through other nodes
+// (its blockchain queries report them missing), as a replica that has not yet caught
+// up would. The state of smart contracts is not affected.
+func (s *Server) SetLagging(endpoint string, lagging bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !lagging {
+		delete(s.lagging, endpoint)
+	} else if s.lagging[endpoint] == nil {
+		s.lagging[endpoint] = make(map[string]bool)
+	}
+}
+
+// hideFromLagging hides transaction txID from the lagging nodes other than the one
+// serving the request in progress. It must be called with s.mu held.
+func (s *Server) hideFromLagging(txID string) {
+	for node, hidden := range s.lagging {
+		if node != s.serving {
+			hidden[txID] = true
+		}
+	}
+}
+
+// writeCertificate generates a self-signed certificate for *.pcoretest.local and
+// writes it where sdk.Open* functions can read it as their certPath.
+func (s *Server) writeCertificate() (tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pcoretest.local"},
+		DNSNames:              []string{"pcoretest.local", "*.pcoretest.local"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+
+	s.certDir, err = ioutil.TempDir("", "pcoretest")
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	s.certPath = filepath.Join(s.certDir, "cert.pem")
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err = ioutil.WriteFile(s.certPath, certPEM, 0600); err != nil {
+		return tls.Certificate{}, err
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}, nil
+}
+
+func (s *Server) removeCertificate() {
+	if s.certDir != "" {
+		os.RemoveAll(s.certDir)
+	}
+}
+
+// nextID returns a new unique, transaction-like identifier.
+func (s *Server) nextID(prefix string) string {
+	s.seq++
+	return fmt.Sprintf("%s%016x", prefix, s.seq)
+}
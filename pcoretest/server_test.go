@@ -0,0 +1,220 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package pcoretest_test
+
+import (
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/digital-transaction/parallelcore-client-sdk-go/pcoretest"
+)
+
+func TestAuthAndRenew(t *testing.T) {
+	client, server := pcoretest.NewClient(t)
+
+	if _, err := server.Open(pcoretest.RootID, "wrong"); err == nil {
+		t.Fatal("Open succeeded with a wrong credential")
+	}
+
+	token := client.GetToken()
+	if err := client.Renew(); err != nil {
+		t.Fatal(err)
+	}
+	if client.GetToken() == token {
+		t.Error("Renew did not issue a new token")
+	}
+
+	server.RevokeToken(client.GetToken())
+	if _, err := client.GetUserInfo(""); err == nil {
+		t.Error("revoked token was accepted")
+	}
+}
+
+func TestTokenExpiry(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+	var mu sync.Mutex
+	now := time.Now()
+	server.SetClock(func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	})
+
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	mu.Lock()
+	now = now.Add(pcoretest.DefaultTokenTTL)
+	mu.Unlock()
+	if _, err = client.GetUserInfo(""); err == nil {
+		t.Error("expired token was accepted")
+	}
+}
+
+func TestUsersAndDomains(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+
+	if _, err := root.CreateDomain([]byte("sales")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := root.CreateUser("alice", "secret", []string{"app"}, []string{"sales"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := root.CreateUser("alice", "secret", []string{"app"}, []string{"sales"}); err == nil {
+		t.Error("duplicate CreateUser succeeded")
+	}
+	if _, err := root.GrantDomainAdmin([]byte(`{"clientId":"alice","clientDomainName":"sales"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	alice, err := server.Open("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+
+	info, err := alice.GetUserInfo("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.ID != "alice" || strings.Join(info.ManagedDomains, ",") != "sales" {
+		t.Errorf("GetUserInfo = %+v", info)
+	}
+
+	if _, err = alice.CreateUser("bob", "pw", []string{"app"}, []string{"sales"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = alice.CreateUser("carol", "pw", []string{"app"}, []string{"default"}); err == nil {
+		t.Error("domain-admin created a user outside their domain")
+	}
+	users, err := alice.GetUserInfos(false, "sales")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].ID != "alice" || users[1].ID != "bob" {
+		t.Errorf("GetUserInfos = %+v", users)
+	}
+
+	if _, err = alice.DeleteUser("alice", "sales"); err == nil {
+		t.Error("user removed themselves")
+	}
+	if _, err = alice.DeleteUser("bob", "sales"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = root.GetUserInfo("bob"); err == nil {
+		t.Error("removed user still exists")
+	}
+}
+
+func TestSmartContractAccess(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	server.AddUser("alice", "secret", []string{"app"}, nil)
+
+	registration := `{"scName":"ledger-v2","domainName":"default"}`
+	if _, err := root.RegisterSmartContract([]byte(registration)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := root.GrantAccess([]byte(`{"clientId":"alice","scName":"ledger","domainName":"default"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	alice, err := server.Open("alice", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer alice.Close()
+
+	invokable, err := alice.ListInvokableSC()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(invokable) != `[{"name":"ledger","ver":"2"}]` {
+		t.Errorf("ListInvokableSC = %s", invokable)
+	}
+	if _, err = alice.ListSmartContract([]byte("ledger")); err == nil {
+		t.Error("non-admin listed a smart contract")
+	}
+	if _, err = alice.Invoke("other-v1", nil); err == nil {
+		t.Error("invoked an unregistered smart contract")
+	}
+}
+
+func TestForget(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+
+	requestID, err := root.RequestForget([]string{"missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	approvalID, err := root.ApproveForget(requestID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := root.CommitForget(requestID, []string{approvalID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.NotFound) != 1 || report.CommitTxId == "" {
+		t.Errorf("CommitForget = %+v", report)
+	}
+
+	groups, err := root.ListForgetGroups([]string{"missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Errorf("ListForgetGroups = %+v", groups)
+	}
+	if got := len(server.Transactions()); got != 3 {
+		t.Errorf("recorded %d transactions, want 3", got)
+	}
+}
+
+func TestBlockchainSummary(t *testing.T) {
+	root, server := pcoretest.NewClient(t)
+	server.SetEndpoints(pcoretest.DefaultEndpoint, "node1.pcoretest.local:5000")
+
+	summary, err := root.GetBlockchainSummary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(summary.Chains) != 2 || summary.Chains[1].NetworkAddress != "node1.pcoretest.local:5000" {
+		t.Errorf("GetBlockchainSummary = %+v", summary)
+	}
+}
+
+func TestEventListener(t *testing.T) {
+	_, server := pcoretest.NewClient(t)
+
+	client, err := server.Open(pcoretest.RootID, pcoretest.RootPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controller, events, err := client.RegisterEventListener("ledger", "^transfer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer controller.Close()
+
+	server.EmitEvent("ledger", "audit", "ignored")
+	txID := server.EmitEvent("ledger", "transferred", "42")
+	select {
+	case e := <-events:
+		if e.Error != nil {
+			t.Fatal(e.Error)
+		}
+		if e.ScEvent.TxId != txID || e.ScEvent.Payload != "42" {
+			t.Errorf("event = %+v", e.ScEvent)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+	}
+}
@@ -0,0 +1,323 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package pcoretest
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	sdk "github.com/digital-transaction/parallelcore-client-sdk-go"
+)
+
+type user struct {
+	id       string
+	password string
+	roles    []string
+	domains  map[string]bool
+}
+
+func (u *user) isSuperAdmin() bool {
+	for _, role := range u.roles {
+		if role == RoleSuperAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type domain struct {
+	clients        map[string]bool
+	admins         map[string]bool
+	smartContracts map[string]bool
+}
+
+func newDomain() *domain {
+	return &domain{
+		clients:        make(map[string]bool),
+		admins:         make(map[string]bool),
+		smartContracts: make(map[string]bool),
+	}
+}
+
+// contractFamily holds every registered version of a smart contract, along with its
+// access list, which ParallelCore keeps per smart contract name.
+type contractFamily struct {
+	name       string
+	versions   map[string]*contract
+	accessList map[string]bool
+}
+
+type contract struct {
+	name     string
+	version  string
+	domain   string
+	checksum string
+	initArgs string
+}
+
+func (c *contract) spec() string {
+	return c.name + "-v" + c.version
+}
+
+// latest returns the highest registered version, comparing dot-separated numbers.
+func (f *contractFamily) latest() *contract {
+	var best *contract
+	for _, each := range f.versions {
+		if best == nil || sdk.CompareVersions(each.version, best.version) > 0 {
+			best = each
+		}
+	}
+	return best
+}
+
+// splitSpec splits a smart contract spec of the form <name>-v<version> (the version
+// may be "*"). A spec without version returns an empty version.
+func splitSpec(spec string) (name string, version string) {
+	i := strings.LastIndex(spec, "-v")
+	if i <= 0 {
+		return spec, ""
+	}
+	return spec[:i], spec[i+2:]
+}
+
+type mutation struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type transaction struct {
+	id          string
+	scName      string
+	clientID    string
+	payload     []byte
+	mutations   []mutation
+	blockNumber int64
+	timestamp   int64
+}
+
+type block struct {
+	number    int64
+	hash      string
+	prevHash  string
+	timestamp int64
+	txIDs     []string
+	sealed    bool
+}
+
+// chain is the single chain on which the fake records transactions, one block each.
+type chain struct {
+	id        string
+	blocks    []*block
+	txs       map[string]*transaction
+	order     []string
+	forgotten map[string]bool
+	// holdSeal leaves new blocks open until Seal is called.
+	holdSeal bool
+}
+
+func newChain() *chain {
+	return &chain{id: "pcoretest-chain-0", txs: make(map[string]*transaction), forgotten: make(map[string]bool)}
+}
+
+func (c *chain) append(tx *transaction, now time.Time) {
+	prevHash := ""
+	if len(c.blocks) != 0 {
+		prevHash = c.blocks[len(c.blocks)-1].hash
+	}
+	b := &block{
+		number:    int64(len(c.blocks)),
+		prevHash:  prevHash,
+		timestamp: now.Unix(),
+		txIDs:     []string{tx.id},
+		sealed:    !c.holdSeal,
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%d|%s", prevHash, b.number, tx.id)))
+	b.hash = hex.EncodeToString(sum[:])
+	c.blocks = append(c.blocks, b)
+
+	tx.blockNumber, tx.timestamp = b.number, b.timestamp
+	c.txs[tx.id] = tx
+	c.order = append(c.order, tx.id)
+}
+
+// forget deletes transaction txID, leaving its block in place.
+func (c *chain) forget(txID string) {
+	delete(c.txs, txID)
+	c.forgotten[txID] = true
+	for i, each := range c.order {
+		if each == txID {
+			c.order = append(c.order[:i:i], c.order[i+1:]...)
+			break
+		}
+	}
+}
+
+func (c *chain) sealedCount() int64 {
+	var count int64
+	for _, each := range c.blocks {
+		if each.sealed {
+			count++
+		}
+	}
+	return count
+}
+
+type forgetRequest struct {
+	txIDs     []string
+	approvals map[string]bool
+	committed bool
+}
+
+// listener is one RegisterEventListener stream.
+type listener struct {
+	scName string
+	filter *regexp.Regexp
+	events chan event
+	done   chan struct{}
+	once   sync.Once
+}
+
+type event struct {
+	TxId      string `json:"txId"`
+	ScName    string `json:"scName"`
+	EventName string `json:"eventName"`
+	Payload   string `json:"payload"`
+}
+
+func (l *listener) stop() {
+	l.once.Do(func() { close(l.done) })
+}
+
+func sortedKeys(set map[string]bool) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// AddUser creates (or replaces) a user, creating the domains it belongs to if needed.
+func (s *Server) AddUser(clientID string, password string, roles []string, domains []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.addUser(clientID, password, roles, domains)
+}
+
+func (s *Server) addUser(clientID string, password string, roles []string, domains []string) {
+	if len(domains) == 0 {
+		domains = []string{"default"}
+	}
+	u := &user{id: clientID, password: password, roles: append([]string(nil), roles...), domains: make(map[string]bool)}
+	for _, name := range domains {
+		if s.domains[name] == nil {
+			s.domains[name] = newDomain()
+		}
+		s.domains[name].clients[clientID] = true
+		u.domains[name] = true
+	}
+	s.users[clientID] = u
+}
+
+// AddDomain creates a domain, making admins its domain-admins.
+func (s *Server) AddDomain(name string, admins ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.domains[name] == nil {
+		s.domains[name] = newDomain()
+	}
+	for _, admin := range admins {
+		s.domains[name].admins[admin] = true
+	}
+}
+
+// AddSmartContract registers version of smart contract name in domainName, and grants
+// the clients in accessList access to it.
+func (s *Server) AddSmartContract(name string, version string, domainName string, accessList ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.registerContract(name, version, domainName, nil, "")
+	for _, clientID := range accessList {
+		s.contracts[name].accessList[clientID] = true
+	}
+}
+
+func (s *Server) registerContract(name string, version string, domainName string, content []byte, initArgs string) *contract {
+	family := s.contracts[name]
+	if family == nil {
+		family = &contractFamily{name: name, versions: make(map[string]*contract), accessList: make(map[string]bool)}
+		s.contracts[name] = family
+	}
+	sum := sha256.Sum256(content)
+	c := &contract{name: name, version: version, domain: domainName, checksum: hex.EncodeToString(sum[:]), initArgs: initArgs}
+	family.versions[version] = c
+	if s.domains[domainName] == nil {
+		s.domains[domainName] = newDomain()
+	}
+	s.domains[domainName].smartContracts[name] = true
+	return c
+}
+
+// Transactions returns the IDs of all transactions recorded so far, oldest first.
+func (s *Server) Transactions() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.chain.order...)
+}
+
+// SetAutoSeal sets whether new blocks are sealed as soon as their transaction is
+// recorded (the default). Otherwise, they stay open until Seal is called.
+func (s *Server) SetAutoSeal(on bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.chain.holdSeal = !on
+}
+
+// Seal seals every open block.
+func (s *Server) Seal() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, each := range s.chain.blocks {
+		each.sealed = true
+	}
+}
+
+// EmitEvent sends an event from smart contract scName to every matching event
+// listener, as if a transaction had emitted it, and returns the event's transaction ID.
+func (s *Server) EmitEvent(scName string, eventName string, payload string) string {
+	s.mu.Lock()
+	txID := s.nextID("ev")
+	s.mu.Unlock()
+
+	s.emit(event{TxId: txID, ScName: scName, EventName: eventName, Payload: payload})
+	return txID
+}
+
+func (s *Server) emit(e event) {
+	s.mu.Lock()
+	targets := make([]*listener, 0)
+	for each := range s.listeners {
+		if each.scName == e.ScName && each.filter.MatchString(e.EventName) {
+			targets = append(targets, each)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, each := range targets {
+		select {
+		case each.events <- e:
+		case <-each.done:
+		}
+	}
+}
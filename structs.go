@@ -98,3 +98,37 @@ type GetSmartContractTransactionOptions struct {
 	ClientId      string `json:"client-id"`
 	SmartContract string `json:"smart-contract"`
 }
+
+// BlockchainSummary is the summary of the blockchain(s) of a ParallelChain network, as
+// returned by GetBlockchainSummary.
+type BlockchainSummary struct {
+	Chains []ChainSummary `json:"chains"`
+}
+
+// ChainSummary describes one chain of a BlockchainSummary, and the node holding it.
+// NetworkAddress is the endpoint of that node, used by SummaryResolver to discover the
+// network.
+type ChainSummary struct {
+	SealedBlockCount int64        `json:"sealed_block_count"`
+	ChainId          string       `json:"chain_id"`
+	MachineId        string       `json:"machine_id"`
+	NetworkAddress   string       `json:"network_address"`
+	PcoreId          string       `json:"pcore_id"`
+	Tags             ChainTags    `json:"tags"`
+	LastBlock        BlockSummary `json:"last_block"`
+}
+
+// ChainTags are the tags of a chain.
+type ChainTags struct {
+	Name string `json:"name"`
+}
+
+// BlockSummary describes the last block of a chain.
+type BlockSummary struct {
+	BlockNumber       int64  `json:"block_number"`
+	ChunksetCount     int64  `json:"chunkset_count"`
+	CreationTimestamp int64  `json:"creation_timestamp"`
+	Hash              string `json:"hash"`
+	PrevHash          string `json:"prev_hash"`
+	Status            int    `json:"status"`
+}
@@ -0,0 +1,160 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	"golang.org/x/net/http/httpproxy"
+	"golang.org/x/net/proxy"
+)
+
+// dialContext connects to endpoint, going through the proxy selected by proxyFor.
+// It is installed into every gRPC connection opened by openOne.
+func (cfg *openConfig) dialContext(ctx context.Context, endpoint string) (net.Conn, error) {
+	conn, err := cfg.dial(ctx, endpoint)
+	if err != nil {
+		return nil, err
+	}
+	for _, hook := range cfg.connHooks {
+		conn = hook(endpoint, conn)
+	}
+	return conn, nil
+}
+
+func (cfg *openConfig) dial(ctx context.Context, endpoint string) (net.Conn, error) {
+	if cfg.dialer != nil {
+		return cfg.dialer(ctx, endpoint)
+	}
+	proxyURL, err := cfg.proxyFor(endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	var dialer net.Dialer
+	if proxyURL == nil {
+		return dialer.DialContext(ctx, "tcp", endpoint)
+	}
+
+	switch proxyURL.Scheme {
+	case "http", "https":
+		return dialHTTPConnect(ctx, proxyURL, endpoint)
+	case "socks5", "socks5h":
+		var auth *proxy.Auth
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			auth = &proxy.Auth{User: proxyURL.User.Username(), Password: password}
+		}
+		socksDialer, err := proxy.SOCKS5("tcp", proxyURL.Host, auth, &dialer)
+		if err != nil {
+			return nil, fmt.Errorf("CLIENT: proxy %q: %w", proxyURL.Redacted(), err)
+		}
+		return socksDialer.(proxy.ContextDialer).DialContext(ctx, "tcp", endpoint)
+	}
+	return nil, fmt.Errorf("CLIENT: proxy %q: Unsupported proxy scheme %q", proxyURL.Redacted(), proxyURL.Scheme)
+}
+
+// proxyFor returns the URL of the proxy to use to reach endpoint, or nil if endpoint
+// should be dialed directly.
+func (cfg *openConfig) proxyFor(endpoint string) (*url.URL, error) {
+	if cfg.proxyURL == PROXY_DIRECT {
+		return nil, nil
+	}
+	if cfg.proxyURL != "" {
+		proxyURL, err := url.Parse(cfg.proxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("CLIENT: Invalid proxy URL. %w", err)
+		}
+		return proxyURL, nil
+	}
+	// Endpoints are always reached over TLS, so only HTTPS_PROXY (and NO_PROXY) apply.
+	proxyURL, err := httpproxy.FromEnvironment().ProxyFunc()(&url.URL{Scheme: "https", Host: endpoint})
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: Invalid proxy environment. %w", err)
+	}
+	return proxyURL, nil
+}
+
+func dialHTTPConnect(ctx context.Context, proxyURL *url.URL, endpoint string) (net.Conn, error) {
+	proxyAddr := proxyURL.Host
+	if proxyURL.Port() == "" {
+		if proxyURL.Scheme == "https" {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "443")
+		} else {
+			proxyAddr = net.JoinHostPort(proxyURL.Hostname(), "80")
+		}
+	}
+
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", proxyAddr)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: proxy %q: %w", proxyURL.Redacted(), err)
+	}
+	// Do not let a silent proxy block past the dial deadline.
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+		defer conn.SetDeadline(time.Time{})
+	}
+	if proxyURL.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		if err = tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("CLIENT: proxy %q: %w", proxyURL.Redacted(), err)
+		}
+		conn = tlsConn
+	}
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: endpoint},
+		Host:   endpoint,
+		Header: make(http.Header),
+	}
+	if proxyURL.User != nil {
+		password, _ := proxyURL.User.Password()
+		credential := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+		req.Header.Set("Proxy-Authorization", "Basic "+credential)
+	}
+	if err = req.Write(conn); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("CLIENT: proxy %q: %w", proxyURL.Redacted(), err)
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("CLIENT: proxy %q: %w", proxyURL.Redacted(), err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("CLIENT: proxy %q: CONNECT %s: %s", proxyURL.Redacted(), endpoint, resp.Status)
+	}
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn hands out bytes the proxy sent right after its CONNECT reply before
+// reading from the tunnel itself.
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(b []byte) (int, error) {
+	return c.reader.Read(b)
+}
@@ -0,0 +1,216 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+const proxiedEndpoint = "pcore.example:443"
+
+// serveProxy accepts one connection on a local listener, hands it to handle, and
+// returns the listener's address.
+func serveProxy(t *testing.T, handle func(conn net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}()
+	return listener.Addr().String()
+}
+
+// connectProxy answers CONNECT requests for proxiedEndpoint with authorization, if
+// set, then sends "hello" through the tunnel, along with its reply.
+func connectProxy(t *testing.T, authorization string) string {
+	return serveProxy(t, func(conn net.Conn) {
+		req, err := http.ReadRequest(bufio.NewReader(conn))
+		if err != nil {
+			return
+		}
+		switch {
+		case req.Method != http.MethodConnect || req.Host != proxiedEndpoint:
+			io.WriteString(conn, "HTTP/1.1 400 Bad Request\r\n\r\n")
+		case req.Header.Get("Proxy-Authorization") != authorization:
+			io.WriteString(conn, "HTTP/1.1 407 Proxy Authentication Required\r\n\r\n")
+		default:
+			io.WriteString(conn, "HTTP/1.1 200 OK\r\n\r\nhello")
+		}
+	})
+}
+
+// socks5Proxy answers SOCKS5 requests for proxiedEndpoint, requiring user and
+// password if user is set, then sends "hello" through the tunnel.
+func socks5Proxy(t *testing.T, user string, password string) string {
+	return serveProxy(t, func(conn net.Conn) {
+		reader := bufio.NewReader(conn)
+		read := func(n int) []byte {
+			b := make([]byte, n)
+			if _, err := io.ReadFull(reader, b); err != nil {
+				return make([]byte, n)
+			}
+			return b
+		}
+
+		greeting := read(2)
+		methods := read(int(greeting[1]))
+		method := byte(0)
+		if user != "" {
+			method = 2
+		}
+		if greeting[0] != 5 || !strings.ContainsRune(string(methods), rune(method)) {
+			conn.Write([]byte{5, 0xff})
+			return
+		}
+		conn.Write([]byte{5, method})
+		if method == 2 {
+			version := read(2)
+			gotUser := string(read(int(version[1])))
+			gotPassword := string(read(int(read(1)[0])))
+			if gotUser != user || gotPassword != password {
+				conn.Write([]byte{1, 1})
+				return
+			}
+			conn.Write([]byte{1, 0})
+		}
+
+		request := read(4)
+		var host string
+		switch request[3] {
+		case 1:
+			host = net.IP(read(4)).String()
+		case 3:
+			host = string(read(int(read(1)[0])))
+		case 4:
+			host = net.IP(read(16)).String()
+		}
+		port := binary.BigEndian.Uint16(read(2))
+		if request[1] != 1 || net.JoinHostPort(host, strconv.Itoa(int(port))) != proxiedEndpoint {
+			conn.Write([]byte{5, 4, 0, 1, 0, 0, 0, 0, 0, 0})
+			return
+		}
+		conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+		io.WriteString(conn, "hello")
+	})
+}
+
+func dialThrough(proxyURL string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := newOpenConfig([]Option{WithProxy(proxyURL)}).dialContext(ctx, proxiedEndpoint)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	greeting, err := ioutil.ReadAll(io.LimitReader(conn, 5))
+	return string(greeting), err
+}
+
+func TestHTTPConnectProxy(t *testing.T) {
+	if greeting, err := dialThrough("http://" + connectProxy(t, "")); err != nil || greeting != "hello" {
+		t.Errorf("CONNECT = %q, %v", greeting, err)
+	}
+
+	authorization := "Basic " + base64.StdEncoding.EncodeToString([]byte("alice:s3cret"))
+	if greeting, err := dialThrough("http://alice:s3cret@" + connectProxy(t, authorization)); err != nil || greeting != "hello" {
+		t.Errorf("CONNECT with credentials = %q, %v", greeting, err)
+	}
+	_, err := dialThrough("http://alice:wrong@" + connectProxy(t, authorization))
+	if err == nil || !strings.Contains(err.Error(), "407") || strings.Contains(err.Error(), "wrong") {
+		t.Errorf("CONNECT with wrong credentials = %v, want a redacted 407", err)
+	}
+}
+
+func TestSOCKS5Proxy(t *testing.T) {
+	if greeting, err := dialThrough("socks5://" + socks5Proxy(t, "", "")); err != nil || greeting != "hello" {
+		t.Errorf("SOCKS5 = %q, %v", greeting, err)
+	}
+	if greeting, err := dialThrough("socks5://alice:s3cret@" + socks5Proxy(t, "alice", "s3cret")); err != nil || greeting != "hello" {
+		t.Errorf("SOCKS5 with credentials = %q, %v", greeting, err)
+	}
+	if _, err := dialThrough("socks5://alice:wrong@" + socks5Proxy(t, "alice", "s3cret")); err == nil {
+		t.Error("SOCKS5 with wrong credentials succeeded")
+	}
+	if _, err := dialThrough("ftp://127.0.0.1:21"); err == nil {
+		t.Error("unsupported proxy scheme accepted")
+	}
+}
+
+// setenv sets the environment variable key to value until the test ends.
+func setenv(t *testing.T, key string, value string) {
+	previous, ok := os.LookupEnv(key)
+	os.Setenv(key, value)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv(key, previous)
+		} else {
+			os.Unsetenv(key)
+		}
+	})
+}
+
+func TestProxyFromEnvironment(t *testing.T) {
+	for _, key := range []string{"HTTP_PROXY", "http_proxy", "HTTPS_PROXY", "https_proxy", "NO_PROXY", "no_proxy", "REQUEST_METHOD"} {
+		setenv(t, key, "")
+	}
+	proxyFor := func(endpoint string, opts ...Option) string {
+		t.Helper()
+		proxyURL, err := newOpenConfig(opts).proxyFor(endpoint)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proxyURL == nil {
+			return ""
+		}
+		return proxyURL.String()
+	}
+
+	// Endpoints are reached over TLS: HTTP_PROXY does not apply.
+	setenv(t, "HTTP_PROXY", "http://plain.example:3128")
+	if got := proxyFor(proxiedEndpoint); got != "" {
+		t.Errorf("with HTTP_PROXY only, proxy = %q", got)
+	}
+
+	setenv(t, "HTTPS_PROXY", "http://secure.example:3128")
+	setenv(t, "NO_PROXY", "internal.example,.corp.example")
+	for endpoint, want := range map[string]string{
+		proxiedEndpoint:          "http://secure.example:3128",
+		"internal.example:443":   "",
+		"node1.corp.example:443": "",
+	} {
+		if got := proxyFor(endpoint); got != want {
+			t.Errorf("proxy for %s = %q, want %q", endpoint, got, want)
+		}
+	}
+
+	if got := proxyFor("internal.example:443", WithProxy("socks5://socks.example:1080")); got != "socks5://socks.example:1080" {
+		t.Errorf("WithProxy did not override the environment: %q", got)
+	}
+	if got := proxyFor(proxiedEndpoint, WithProxy(PROXY_DIRECT)); got != "" {
+		t.Errorf("PROXY_DIRECT did not disable the environment: %q", got)
+	}
+}
@@ -6,7 +6,21 @@
 package parallelcore_client_sdk_go
 
 import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	_ "crypto/sha256"
+	_ "crypto/sha512"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
@@ -30,3 +44,248 @@ func parseTokenAndExpireTimestamp(s string) (token string, expireTimestamp int64
 func formatTokenTimestamp(token string, expireTimestamp int64) string {
 	return fmt.Sprintf("Token{len:%d, expire{%d, %q}}", len(token), expireTimestamp, time.Unix(expireTimestamp, 0).String())
 }
+
+// TokenClaims holds the claims of a ParallelCore JWT, as decoded by ParseTokenClaims.
+//
+// ClientID, Roles and Domains are taken from whichever of the common claim names
+// the issuing engine used (for example "clientId", "client_id" or "sub" for ClientID),
+// and are left empty if the token does not carry them. All claims, including the ones
+// not mapped to a field, are available in Raw.
+type TokenClaims struct {
+	Subject   string
+	ClientID  string
+	Issuer    string
+	Roles     []string
+	Domains   []string
+	IssuedAt  int64
+	NotBefore int64
+	ExpiresAt int64
+	Raw       map[string]interface{}
+}
+
+var (
+	ErrTokenMalformed        = errors.New("CLIENT: Malformed token")
+	ErrTokenNoExpiry         = errors.New("CLIENT: Token has no expiry")
+	ErrTokenExpired          = errors.New("CLIENT: Token expired")
+	ErrTokenNotYetValid      = errors.New("CLIENT: Token not yet valid")
+	ErrTokenSignatureInvalid = errors.New("CLIENT: Token signature invalid")
+)
+
+// ParseTokenClaims decodes the claims of token, verifies its signature with key, and
+// checks its validity period against the local clock, tolerating DEFAULT_CLOCK_SKEW
+// (see TokenClaims.Valid). key must be an *rsa.PublicKey, *ecdsa.PublicKey,
+// ed25519.PublicKey or, for HMAC-signed tokens, a []byte secret.
+//
+// If key is nil, the signature is not verified: only pass nil for tokens obtained
+// from ParallelCore itself, never for tokens forwarded by another party.
+//
+// If the token is well-formed and correctly signed but not currently valid,
+// ParseTokenClaims returns both the claims and the error of TokenClaims.Valid.
+func ParseTokenClaims(token string, key crypto.PublicKey) (*TokenClaims, error) {
+	claims, err := decodeTokenClaims(token, key)
+	if err != nil {
+		return nil, err
+	}
+	return claims, claims.Valid(time.Now(), DEFAULT_CLOCK_SKEW)
+}
+
+// decodeTokenClaims is similar to ParseTokenClaims, without checking the validity
+// period.
+func decodeTokenClaims(token string, key crypto.PublicKey) (*TokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: expected 3 segments, got %d", ErrTokenMalformed, len(parts))
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeTokenSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	raw := make(map[string]interface{})
+	if err := decodeTokenSegment(parts[1], &raw); err != nil {
+		return nil, err
+	}
+	if key != nil {
+		signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+		}
+		if err = verifyTokenSignature(header.Alg, []byte(parts[0]+"."+parts[1]), signature, key); err != nil {
+			return nil, err
+		}
+	}
+
+	claims := &TokenClaims{Raw: raw}
+	claims.Subject = claimString(raw, "sub")
+	claims.ClientID = claimString(raw, "clientId", "client_id", "clientID", "sub")
+	claims.Issuer = claimString(raw, "iss")
+	claims.Roles = claimStrings(raw, "roles", "clientRoles", "role")
+	claims.Domains = claimStrings(raw, "domains", "clientDomainName", "domain")
+	claims.IssuedAt = claimInt64(raw, "iat")
+	claims.NotBefore = claimInt64(raw, "nbf")
+	claims.ExpiresAt = claimInt64(raw, "exp")
+	return claims, nil
+}
+
+// Valid checks the validity period of the claims at time now, tolerating up to skew of
+// difference between the local clock and the clock of the issuing engine.
+//
+// Claims without an expiry ("exp") are rejected with ErrTokenNoExpiry, since
+// ParallelCore always sets one; callers willing to accept such tokens must check for
+// that error explicitly.
+func (claims *TokenClaims) Valid(now time.Time, skew time.Duration) error {
+	if claims.ExpiresAt == 0 {
+		return ErrTokenNoExpiry
+	}
+	if now.Add(-skew).After(time.Unix(claims.ExpiresAt, 0)) {
+		return fmt.Errorf("%w at %s", ErrTokenExpired, time.Unix(claims.ExpiresAt, 0))
+	}
+	if claims.NotBefore != 0 && now.Add(skew).Before(time.Unix(claims.NotBefore, 0)) {
+		return fmt.Errorf("%w before %s", ErrTokenNotYetValid, time.Unix(claims.NotBefore, 0))
+	}
+	if claims.IssuedAt != 0 && now.Add(skew).Before(time.Unix(claims.IssuedAt, 0)) {
+		return fmt.Errorf("%w: issued in the future at %s", ErrTokenNotYetValid, time.Unix(claims.IssuedAt, 0))
+	}
+	return nil
+}
+
+// HasRole reports whether role is one of the claimed roles.
+func (claims *TokenClaims) HasRole(role string) bool {
+	return containsString(claims.Roles, role)
+}
+
+// ParsePublicKeyPEM parses a PEM-encoded PKIX public key or X.509 certificate, as
+// accepted by WithTokenVerificationKey and ParseTokenClaims.
+func ParsePublicKeyPEM(data []byte) (crypto.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("CLIENT: No PEM block found")
+	}
+	if block.Type == "CERTIFICATE" {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("CLIENT: %w", err)
+		}
+		return cert.PublicKey, nil
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("CLIENT: %w", err)
+	}
+	return key, nil
+}
+
+func decodeTokenSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(segment, "="))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err = decoder.Decode(v); err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenMalformed, err)
+	}
+	return nil
+}
+
+func verifyTokenSignature(alg string, signed []byte, signature []byte, key crypto.PublicKey) error {
+	if alg == "EdDSA" {
+		if edKey, ok := key.(ed25519.PublicKey); ok && ed25519.Verify(edKey, signed, signature) {
+			return nil
+		}
+		return ErrTokenSignatureInvalid
+	}
+
+	var hash crypto.Hash
+	if len(alg) == 5 {
+		switch alg[2:] {
+		case "256":
+			hash = crypto.SHA256
+		case "384":
+			hash = crypto.SHA384
+		case "512":
+			hash = crypto.SHA512
+		}
+	}
+	if hash == 0 {
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrTokenSignatureInvalid, alg)
+	}
+
+	hasher := hash.New()
+	hasher.Write(signed)
+	digest := hasher.Sum(nil)
+
+	var ok bool
+	switch alg[:2] {
+	case "RS":
+		rsaKey, isRSA := key.(*rsa.PublicKey)
+		ok = isRSA && rsa.VerifyPKCS1v15(rsaKey, hash, digest, signature) == nil
+	case "PS":
+		rsaKey, isRSA := key.(*rsa.PublicKey)
+		ok = isRSA && rsa.VerifyPSS(rsaKey, hash, digest, signature, nil) == nil
+	case "ES":
+		ecKey, isEC := key.(*ecdsa.PublicKey)
+		if isEC && len(signature) == 2*((ecKey.Curve.Params().BitSize+7)/8) {
+			half := len(signature) / 2
+			r := new(big.Int).SetBytes(signature[:half])
+			s := new(big.Int).SetBytes(signature[half:])
+			ok = ecdsa.Verify(ecKey, digest, r, s)
+		}
+	case "HS":
+		secret, isSecret := key.([]byte)
+		if isSecret {
+			mac := hmac.New(hash.New, secret)
+			mac.Write(signed)
+			ok = hmac.Equal(mac.Sum(nil), signature)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrTokenSignatureInvalid, alg)
+	}
+	if !ok {
+		return ErrTokenSignatureInvalid
+	}
+	return nil
+}
+
+func claimString(raw map[string]interface{}, names ...string) string {
+	for _, name := range names {
+		if value, ok := raw[name].(string); ok && value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// claimStrings accepts both JSON arrays and the comma-delimited strings ParallelCore
+// uses elsewhere for roles and domains (see UserData).
+func claimStrings(raw map[string]interface{}, names ...string) []string {
+	for _, name := range names {
+		switch value := raw[name].(type) {
+		case string:
+			if value != "" {
+				return strings.Split(value, ",")
+			}
+		case []interface{}:
+			list := make([]string, 0, len(value))
+			for _, each := range value {
+				list = append(list, fmt.Sprint(each))
+			}
+			return list
+		}
+	}
+	return nil
+}
+
+func claimInt64(raw map[string]interface{}, name string) int64 {
+	if number, ok := raw[name].(json.Number); ok {
+		if value, err := number.Int64(); err == nil {
+			return value
+		}
+		if value, err := number.Float64(); err == nil {
+			return int64(value)
+		}
+	}
+	return 0
+}
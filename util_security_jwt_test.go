@@ -0,0 +1,253 @@
+//
+// Copyright 2021 Digital Transaction Limited.
+// All Rights Reserved.
+//
+
+package parallelcore_client_sdk_go
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+)
+
+type tokenKeys struct {
+	rsa     *rsa.PrivateKey
+	p256    *ecdsa.PrivateKey
+	p384    *ecdsa.PrivateKey
+	p521    *ecdsa.PrivateKey
+	ed25519 ed25519.PrivateKey
+	secret  []byte
+}
+
+func newTokenKeys(t *testing.T) *tokenKeys {
+	t.Helper()
+	keys := &tokenKeys{secret: []byte("0123456789abcdef0123456789abcdef")}
+	var err error
+	if keys.rsa, err = rsa.GenerateKey(rand.Reader, 2048); err != nil {
+		t.Fatal(err)
+	}
+	for _, each := range []struct {
+		curve elliptic.Curve
+		key   **ecdsa.PrivateKey
+	}{{elliptic.P256(), &keys.p256}, {elliptic.P384(), &keys.p384}, {elliptic.P521(), &keys.p521}} {
+		if *each.key, err = ecdsa.GenerateKey(each.curve, rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, keys.ed25519, err = ed25519.GenerateKey(rand.Reader); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// sign returns a token for claims, signed with alg and the matching key of keys.
+func (keys *tokenKeys) sign(t *testing.T, alg string, claims map[string]interface{}) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+
+	hashes := map[string]crypto.Hash{"256": crypto.SHA256, "384": crypto.SHA384, "512": crypto.SHA512}
+	var signature []byte
+	var err error
+	if alg == "EdDSA" {
+		signature = ed25519.Sign(keys.ed25519, []byte(signed))
+	} else {
+		hash := hashes[alg[2:]]
+		hasher := hash.New()
+		hasher.Write([]byte(signed))
+		digest := hasher.Sum(nil)
+		switch alg[:2] {
+		case "RS":
+			signature, err = rsa.SignPKCS1v15(rand.Reader, keys.rsa, hash, digest)
+		case "PS":
+			signature, err = rsa.SignPSS(rand.Reader, keys.rsa, hash, digest, nil)
+		case "ES":
+			key := map[string]*ecdsa.PrivateKey{"256": keys.p256, "384": keys.p384, "512": keys.p521}[alg[2:]]
+			var r, s *big.Int
+			r, s, err = ecdsa.Sign(rand.Reader, key, digest)
+			size := (key.Curve.Params().BitSize + 7) / 8
+			signature = make([]byte, 2*size)
+			r.FillBytes(signature[:size])
+			s.FillBytes(signature[size:])
+		case "HS":
+			mac := hmac.New(hash.New, keys.secret)
+			mac.Write([]byte(signed))
+			signature = mac.Sum(nil)
+		}
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// public returns the key verifying the tokens keys sign with alg.
+func (keys *tokenKeys) public(alg string) crypto.PublicKey {
+	switch {
+	case alg == "EdDSA":
+		return keys.ed25519.Public()
+	case alg == "ES256":
+		return &keys.p256.PublicKey
+	case alg == "ES384":
+		return &keys.p384.PublicKey
+	case alg == "ES512":
+		return &keys.p521.PublicKey
+	case strings.HasPrefix(alg, "HS"):
+		return keys.secret
+	}
+	return &keys.rsa.PublicKey
+}
+
+func validClaims() map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"sub":              "alice",
+		"clientRoles":      "app,auditor",
+		"clientDomainName": "sales",
+		"iat":              now.Unix(),
+		"nbf":              now.Unix(),
+		"exp":              now.Add(time.Hour).Unix(),
+	}
+}
+
+func TestParseTokenClaimsAlgorithms(t *testing.T) {
+	keys := newTokenKeys(t)
+	for _, alg := range []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "HS256", "HS384", "HS512", "EdDSA"} {
+		token := keys.sign(t, alg, validClaims())
+		claims, err := ParseTokenClaims(token, keys.public(alg))
+		if err != nil {
+			t.Errorf("%s: %v", alg, err)
+			continue
+		}
+		if claims.ClientID != "alice" || !claims.HasRole("auditor") || len(claims.Domains) != 1 || claims.Domains[0] != "sales" {
+			t.Errorf("%s: claims = %+v", alg, claims)
+		}
+
+		// A tampered payload or signature fails verification.
+		parts := strings.Split(token, ".")
+		tampered := validClaims()
+		tampered["sub"] = "mallory"
+		payload, _ := json.Marshal(tampered)
+		forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString(payload) + "." + parts[2]
+		if _, err = ParseTokenClaims(forged, keys.public(alg)); !errors.Is(err, ErrTokenSignatureInvalid) {
+			t.Errorf("%s: tampered payload = %v", alg, err)
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		signature[len(signature)/2] ^= 1
+		forged = parts[0] + "." + parts[1] + "." + base64.RawURLEncoding.EncodeToString(signature)
+		if _, err = ParseTokenClaims(forged, keys.public(alg)); !errors.Is(err, ErrTokenSignatureInvalid) {
+			t.Errorf("%s: tampered signature = %v", alg, err)
+		}
+		if _, err = ParseTokenClaims(parts[0]+"."+parts[1]+".", keys.public(alg)); !errors.Is(err, ErrTokenSignatureInvalid) {
+			t.Errorf("%s: empty signature = %v", alg, err)
+		}
+	}
+}
+
+func TestParseTokenClaimsKeys(t *testing.T) {
+	keys := newTokenKeys(t)
+	other := newTokenKeys(t)
+	for _, test := range []struct {
+		alg string
+		key crypto.PublicKey
+	}{
+		{"RS256", &other.rsa.PublicKey},
+		{"RS256", &keys.p256.PublicKey},
+		{"RS256", keys.secret},
+		{"PS256", keys.ed25519.Public()},
+		{"ES256", &other.p256.PublicKey},
+		{"ES256", &keys.p384.PublicKey},
+		{"ES256", &keys.rsa.PublicKey},
+		{"HS256", []byte("another secret")},
+		// An RSA public key must not be usable as an HMAC secret.
+		{"HS256", &keys.rsa.PublicKey},
+		{"EdDSA", other.ed25519.Public()},
+		{"EdDSA", keys.secret},
+	} {
+		if _, err := ParseTokenClaims(keys.sign(t, test.alg, validClaims()), test.key); !errors.Is(err, ErrTokenSignatureInvalid) {
+			t.Errorf("%s with %T = %v, want ErrTokenSignatureInvalid", test.alg, test.key, err)
+		}
+	}
+
+	// Unsigned tokens and unknown algorithms are refused whenever a key is given.
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`))
+	payload, _ := json.Marshal(validClaims())
+	unsigned := header + "." + base64.RawURLEncoding.EncodeToString(payload) + "."
+	if _, err := ParseTokenClaims(unsigned, keys.secret); !errors.Is(err, ErrTokenSignatureInvalid) {
+		t.Errorf("alg none = %v", err)
+	}
+	// Without a key, signatures are not verified.
+	if _, err := ParseTokenClaims(unsigned, nil); err != nil {
+		t.Errorf("alg none without key = %v", err)
+	}
+}
+
+func TestParseTokenClaimsMalformed(t *testing.T) {
+	keys := newTokenKeys(t)
+	token := keys.sign(t, "HS256", validClaims())
+	parts := strings.Split(token, ".")
+	for name, malformed := range map[string]string{
+		"empty":              "",
+		"two segments":       parts[0] + "." + parts[1],
+		"four segments":      token + ".x",
+		"bad header base64":  "!!." + parts[1] + "." + parts[2],
+		"bad payload base64": parts[0] + ".!!." + parts[2],
+		"bad payload JSON":   parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte("{")) + "." + parts[2],
+		"payload not object": parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte("[1]")) + "." + parts[2],
+		"bad signature":      parts[0] + "." + parts[1] + ".!!",
+	} {
+		if _, err := ParseTokenClaims(malformed, keys.secret); !errors.Is(err, ErrTokenMalformed) {
+			t.Errorf("%s: %v, want ErrTokenMalformed", name, err)
+		}
+	}
+}
+
+func TestTokenClaimsValid(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	skew := 30 * time.Second
+	for _, test := range []struct {
+		name   string
+		claims TokenClaims
+		want   error
+	}{
+		{"valid", TokenClaims{IssuedAt: now.Unix(), NotBefore: now.Unix(), ExpiresAt: now.Add(time.Hour).Unix()}, nil},
+		{"no expiry", TokenClaims{IssuedAt: now.Unix()}, ErrTokenNoExpiry},
+		{"expired", TokenClaims{ExpiresAt: now.Add(-time.Minute).Unix()}, ErrTokenExpired},
+		{"expired within skew", TokenClaims{ExpiresAt: now.Add(-10 * time.Second).Unix()}, nil},
+		{"not yet valid", TokenClaims{NotBefore: now.Add(time.Minute).Unix(), ExpiresAt: now.Add(time.Hour).Unix()}, ErrTokenNotYetValid},
+		{"not yet valid within skew", TokenClaims{NotBefore: now.Add(10 * time.Second).Unix(), ExpiresAt: now.Add(time.Hour).Unix()}, nil},
+		{"issued in the future", TokenClaims{IssuedAt: now.Add(time.Minute).Unix(), ExpiresAt: now.Add(time.Hour).Unix()}, ErrTokenNotYetValid},
+		{"issued in the future within skew", TokenClaims{IssuedAt: now.Add(10 * time.Second).Unix(), ExpiresAt: now.Add(time.Hour).Unix()}, nil},
+	} {
+		if err := test.claims.Valid(now, skew); !errors.Is(err, test.want) || (test.want == nil && err != nil) {
+			t.Errorf("%s: Valid = %v, want %v", test.name, err, test.want)
+		}
+	}
+
+	// ParseTokenClaims checks validity, and still returns the claims.
+	keys := newTokenKeys(t)
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	claims, err := ParseTokenClaims(keys.sign(t, "HS256", expired), keys.secret)
+	if !errors.Is(err, ErrTokenExpired) || claims == nil || claims.Subject != "alice" {
+		t.Errorf("ParseTokenClaims of an expired token = %+v, %v", claims, err)
+	}
+	noExpiry := validClaims()
+	delete(noExpiry, "exp")
+	if _, err = ParseTokenClaims(keys.sign(t, "HS256", noExpiry), keys.secret); !errors.Is(err, ErrTokenNoExpiry) {
+		t.Errorf("ParseTokenClaims of a token without expiry = %v", err)
+	}
+}